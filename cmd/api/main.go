@@ -13,6 +13,7 @@ import (
 	"interface-api/internal/server"
 	"interface-api/pkg/cleanup"
 	"interface-api/pkg/config"
+	"interface-api/pkg/devicesync"
 	"interface-api/pkg/logger"
 	"interface-api/pkg/webhookworker"
 	"interface-api/pkg/worker"
@@ -34,7 +35,7 @@ import (
 //	@name						shortmesh_admin_token
 //	@description				Admin session cookie authentication
 
-func gracefulShutdown(apiServer *http.Server, w *worker.Worker, cw *cleanup.CleanupWorker, ww *webhookworker.WebhookWorker, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, w *worker.Worker, cw *cleanup.CleanupWorker, ww *webhookworker.WebhookWorker, dw *devicesync.DeviceSyncWorker, done chan bool) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -55,6 +56,10 @@ func gracefulShutdown(apiServer *http.Server, w *worker.Worker, cw *cleanup.Clea
 		ww.Stop()
 	}
 
+	if dw != nil {
+		dw.Stop()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := apiServer.Shutdown(ctx); err != nil {
@@ -69,6 +74,11 @@ func gracefulShutdown(apiServer *http.Server, w *worker.Worker, cw *cleanup.Clea
 func main() {
 	db := database.New()
 
+	if err := database.LoadCredentialRoles(); err != nil {
+		logger.Error(fmt.Sprintf("Credential role loading failed: %v", err))
+		os.Exit(1)
+	}
+
 	if err := database.InitializeSuperAdminCredentials(db.DB()); err != nil {
 		logger.Error(fmt.Sprintf("Credential initialization failed: %v", err))
 		os.Exit(1)
@@ -98,11 +108,19 @@ func main() {
 		logger.Info("Webhook worker disabled via WEBHOOK_WORKER_ENABLED=false")
 	}
 
+	var dw *devicesync.DeviceSyncWorker
+	if devicesync.IsEnabled() {
+		dw = devicesync.New(db)
+		dw.Start()
+	} else {
+		logger.Info("Device sync worker disabled via DEVICE_SYNC_ENABLED=false")
+	}
+
 	srv := server.NewServer()
 
 	done := make(chan bool, 1)
 
-	go gracefulShutdown(srv, w, cw, ww, done)
+	go gracefulShutdown(srv, w, cw, ww, dw, done)
 
 	var err error
 	if config.RequiresHTTPS() {
@@ -0,0 +1,94 @@
+package adminsession
+
+import (
+	"fmt"
+	"os"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/throttler"
+
+	"gorm.io/gorm"
+)
+
+// newLoginLockoutStores picks the stores named by ADMIN_LOGIN_LOCKOUT_STORE,
+// "database" (the default) so lockouts are shared between replicas and
+// survive restarts, or "memory" for a single instance.
+func newLoginLockoutStores(db *gorm.DB) (username, ip throttler.LockoutStore) {
+	switch store := os.Getenv("ADMIN_LOGIN_LOCKOUT_STORE"); store {
+	case "memory":
+		return throttler.NewMemoryLockoutStore(), throttler.NewMemoryLockoutStore()
+	case "", "database":
+	default:
+		logger.Error(fmt.Sprintf("Unknown ADMIN_LOGIN_LOCKOUT_STORE '%s', using database", store))
+	}
+	return &dbLockoutStore{db: db, kind: lockoutTypeUsername}, &dbLockoutStore{db: db, kind: lockoutTypeIP}
+}
+
+// dbLockoutStore keeps the lockouts of one kind in the login_lockouts table.
+// Expired rows are removed by the cleanup worker.
+type dbLockoutStore struct {
+	db   *gorm.DB
+	kind string
+}
+
+func (s *dbLockoutStore) Get(key string) (*throttler.Lockout, error) {
+	record, err := models.FindLoginLockout(s.db, s.kind, key)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	lockout := toLockout(*record)
+	return &lockout, nil
+}
+
+func (s *dbLockoutStore) Update(key string, fn func(*throttler.Lockout)) error {
+	return models.UpdateLoginLockout(s.db, s.kind, key, func(record *models.LoginLockout) {
+		lockout := toLockout(*record)
+		fn(&lockout)
+
+		record.Failures = lockout.Failures
+		record.Lockouts = lockout.Lockouts
+		record.Tokens = lockout.Tokens
+		record.LastRefill = lockout.LastRefill.UTC()
+		record.LockedUntil = lockout.LockedUntil.UTC()
+		record.LastFailure = lockout.LastFailure.UTC()
+		record.ExpiresAt = lockout.ExpiresAt.UTC()
+	})
+}
+
+func (s *dbLockoutStore) Delete(key string) (bool, error) {
+	return models.DeleteLoginLockout(s.db, s.kind, key)
+}
+
+func (s *dbLockoutStore) DeleteAll() (int, error) {
+	count, err := models.DeleteLoginLockouts(s.db, s.kind)
+	return int(count), err
+}
+
+func (s *dbLockoutStore) List() ([]throttler.Lockout, error) {
+	records, err := models.FindLoginLockouts(s.db, s.kind)
+	if err != nil {
+		return nil, err
+	}
+	lockouts := make([]throttler.Lockout, 0, len(records))
+	for _, record := range records {
+		lockouts = append(lockouts, toLockout(record))
+	}
+	return lockouts, nil
+}
+
+func toLockout(record models.LoginLockout) throttler.Lockout {
+	return throttler.Lockout{
+		Key:         record.Value,
+		Failures:    record.Failures,
+		Lockouts:    record.Lockouts,
+		Tokens:      record.Tokens,
+		LastRefill:  record.LastRefill,
+		LockedUntil: record.LockedUntil,
+		LastFailure: record.LastFailure,
+		ExpiresAt:   record.ExpiresAt,
+	}
+}
@@ -0,0 +1,201 @@
+package adminsession
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"interface-api/pkg/logger"
+	"interface-api/pkg/throttler"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	lockoutTypeUsername = "username"
+	lockoutTypeIP       = "ip"
+)
+
+// loginLockoutConfigs reads the admin login throttling settings. A username
+// gets fewer attempts than an IP address, which may be shared by several
+// admins behind one NAT.
+func loginLockoutConfigs() (username, ip throttler.LockoutConfig) {
+	window := time.Duration(envPositiveInt("ADMIN_LOGIN_ATTEMPT_WINDOW_MINUTES", 15)) * time.Minute
+	base := time.Duration(envPositiveInt("ADMIN_LOGIN_LOCKOUT_SECONDS", 60)) * time.Second
+	maxLockout := time.Duration(envPositiveInt("ADMIN_LOGIN_MAX_LOCKOUT_MINUTES", 60)) * time.Minute
+
+	username = throttler.LockoutConfig{
+		Attempts:    envPositiveInt("ADMIN_LOGIN_MAX_ATTEMPTS", 5),
+		Interval:    window,
+		BaseLockout: base,
+		MaxLockout:  maxLockout,
+	}
+	ip = username
+	ip.Attempts = envPositiveInt("ADMIN_LOGIN_MAX_ATTEMPTS_PER_IP", 20)
+	return username, ip
+}
+
+func envPositiveInt(name string, fallback int) int {
+	if value := os.Getenv(name); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// loginLockedOut returns how long login stays blocked for the username or the
+// client IP, whichever is longer
+func (h *AdminSessionHandler) loginLockedOut(username, ip string) (time.Duration, error) {
+	usernameWait, err := h.usernameLockouts.Check(username)
+	if err != nil {
+		return 0, err
+	}
+	ipWait, err := h.ipLockouts.Check(ip)
+	if err != nil {
+		return 0, err
+	}
+	return max(usernameWait, ipWait), nil
+}
+
+// recordLoginFailure counts a failed login against both the username and the
+// client IP
+func (h *AdminSessionHandler) recordLoginFailure(username, ip string) {
+	if lockout, err := h.usernameLockouts.Fail(username); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record admin login failure for '%s': %v", username, err))
+	} else if lockout > 0 {
+		logger.Warn(fmt.Sprintf("Admin login for '%s' locked out for %s after repeated failures", username, lockout))
+	}
+	if lockout, err := h.ipLockouts.Fail(ip); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record admin login failure from %s: %v", ip, err))
+	} else if lockout > 0 {
+		logger.Warn(fmt.Sprintf("Admin login from %s locked out for %s after repeated failures", ip, lockout))
+	}
+}
+
+func tooManyLoginAttempts(c echo.Context, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Error: fmt.Sprintf("Too many failed login attempts. Try again in %d seconds", seconds),
+	})
+}
+
+// ListLoginLockouts godoc
+//
+//	@Summary		List admin login lockouts
+//	@Description	Get the usernames and IP addresses with recent failed admin logins, and whether they are locked out.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Security		CookieAuth
+//	@Success		200	{object}	ListLoginLockoutsResponse
+//	@Failure		403	{object}	ErrorResponse	"Insufficient permissions"
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/login-lockouts [get]
+//	@Router			/api/v1/admin/login-lockouts [get]
+func (h *AdminSessionHandler) ListLoginLockouts(c echo.Context) error {
+	now := time.Now()
+	response := ListLoginLockoutsResponse{Lockouts: []LoginLockout{}}
+
+	for _, tracked := range []struct {
+		kind    string
+		tracker *throttler.LockoutTracker
+	}{
+		{lockoutTypeUsername, h.usernameLockouts},
+		{lockoutTypeIP, h.ipLockouts},
+	} {
+		lockouts, err := tracked.tracker.List()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to list admin login lockouts: %v", err))
+			return echo.ErrInternalServerError
+		}
+		for _, lockout := range lockouts {
+			entry := LoginLockout{
+				Type:        tracked.kind,
+				Value:       lockout.Key,
+				Failures:    lockout.Failures,
+				Lockouts:    lockout.Lockouts,
+				Locked:      lockout.Locked(now),
+				LastFailure: lockout.LastFailure,
+			}
+			if entry.Locked {
+				lockedUntil := lockout.LockedUntil
+				entry.LockedUntil = &lockedUntil
+			}
+			response.Lockouts = append(response.Lockouts, entry)
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ClearLoginLockouts godoc
+//
+//	@Summary		Clear admin login lockouts
+//	@Description	Forget the failed admin logins recorded for a username or IP address, lifting any lockout. Pass all=true to clear every lockout.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Security		CookieAuth
+//	@Param			type	query		string	false	"username or ip"
+//	@Param			value	query		string	false	"Username or IP address to clear"
+//	@Param			all		query		bool	false	"Clear every lockout"
+//	@Success		200		{object}	ClearLoginLockoutsResponse
+//	@Failure		400		{object}	ErrorResponse	"Invalid request"
+//	@Failure		403		{object}	ErrorResponse	"Insufficient permissions"
+//	@Failure		404		{object}	ErrorResponse	"No failed logins recorded"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/login-lockouts [delete]
+//	@Router			/api/v1/admin/login-lockouts [delete]
+func (h *AdminSessionHandler) ClearLoginLockouts(c echo.Context) error {
+	if c.QueryParam("all") == "true" {
+		cleared := 0
+		for _, tracker := range []*throttler.LockoutTracker{h.usernameLockouts, h.ipLockouts} {
+			count, err := tracker.ClearAll()
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to clear admin login lockouts: %v", err))
+				return echo.ErrInternalServerError
+			}
+			cleared += count
+		}
+		logger.Info(fmt.Sprintf("Cleared all %d admin login lockout(s)", cleared))
+		return c.JSON(http.StatusOK, ClearLoginLockoutsResponse{
+			Message: fmt.Sprintf("Cleared %d login lockout(s)", cleared),
+			Cleared: cleared,
+		})
+	}
+
+	var tracker *throttler.LockoutTracker
+	switch kind := c.QueryParam("type"); kind {
+	case lockoutTypeUsername:
+		tracker = h.usernameLockouts
+	case lockoutTypeIP:
+		tracker = h.ipLockouts
+	default:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "type must be 'username' or 'ip', or pass all=true"})
+	}
+
+	value := c.QueryParam("value")
+	if value == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "value is required"})
+	}
+
+	cleared, err := tracker.Clear(value)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to clear admin login lockout: %v", err))
+		return echo.ErrInternalServerError
+	}
+	if !cleared {
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "No failed logins recorded"})
+	}
+
+	logger.Info(fmt.Sprintf("Cleared admin login lockout for %s '%s'", c.QueryParam("type"), value))
+	return c.JSON(http.StatusOK, ClearLoginLockoutsResponse{
+		Message: "Cleared 1 login lockout(s)",
+		Cleared: 1,
+	})
+}
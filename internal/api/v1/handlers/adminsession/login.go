@@ -1,7 +1,6 @@
 package adminsession
 
 import (
-	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,7 +17,7 @@ import (
 // Login godoc
 //
 //	@Summary		Admin login
-//	@Description	Authenticate admin user and create session
+//	@Description	Authenticate admin user and create session. Repeated failures lock out the username and the client IP address for an exponentially growing period.
 //	@Tags			admin
 //	@Accept			x-www-form-urlencoded
 //	@Produce		json
@@ -27,6 +26,7 @@ import (
 //	@Success		200			{object}	LoginResponse
 //	@Failure		400			{object}	ErrorResponse
 //	@Failure		401			{object}	ErrorResponse
+//	@Failure		429			{object}	ErrorResponse	"Too many failed login attempts"
 //	@Failure		500			{object}	ErrorResponse
 //	@Router			/api/v1/admin/login [post]
 func (h *AdminSessionHandler) Login(c echo.Context) error {
@@ -37,50 +37,78 @@ func (h *AdminSessionHandler) Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Username and password required"})
 	}
 
+	ip := c.RealIP()
+	wait, err := h.loginLockedOut(username, ip)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to check admin login lockout: %v", err))
+		return echo.ErrInternalServerError
+	}
+	if wait > 0 {
+		logger.Warn(fmt.Sprintf("Rejected locked out admin login for '%s' from %s", username, ip))
+		return tooManyLoginAttempts(c, wait)
+	}
+
 	credential, err := models.FindCredentialByClientID(h.db.DB(), username)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			logger.Error("Invalid login credentials")
+			h.recordLoginFailure(username, ip)
 			return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid credentials"})
 		}
 		logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	secretHash, err := crypto.Hash(password)
+	match, needsRehash, err := crypto.VerifySecret(password, credential.ClientSecret)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to hash password: %v", err))
+		logger.Error(fmt.Sprintf("Failed to verify password: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	if subtle.ConstantTimeCompare(secretHash, credential.ClientSecret) != 1 {
+	if !match {
 		logger.Error("Invalid login credentials")
+		h.recordLoginFailure(username, ip)
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid credentials"})
 	}
 
-	if !credential.Active {
-		logger.Error("Attempt to log in with inactive credentials")
+	if needsRehash {
+		if err := credential.UpgradeSecretHash(h.db.DB(), password); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to upgrade client secret hash: %v", err))
+		}
+	}
+
+	if err := credential.CheckUsable(); err != nil {
+		logger.Error(fmt.Sprintf("Attempt to log in with unusable credentials: %v", err))
+		h.recordLoginFailure(username, ip)
 		return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid credentials"})
 	}
 
+	if !credential.AllowedIPs.Allows(ip) {
+		logger.Warn(fmt.Sprintf("Rejected login for '%s' from disallowed IP %s", credential.ClientID, ip))
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: "Login is not allowed from this IP address"})
+	}
+
+	if _, err := h.usernameLockouts.Clear(username); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to clear admin login lockout: %v", err))
+	}
+
+	if err := credential.UpdateLastUsed(h.db.DB()); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to update credential last used time: %v", err))
+	}
+
 	sessionToken, err := middleware.GenerateSessionToken()
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to generate session token: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	expiration := time.Now().Add(2 * time.Hour)
-	middleware.StoreSession(sessionToken, expiration, &credential.ID)
-
-	cookie := &http.Cookie{
-		Name:     "shortmesh_admin_token",
-		Value:    sessionToken,
-		Path:     "/",
-		Expires:  expiration,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
+	expiration := time.Now().Add(middleware.SessionIdleTimeout())
+	if err := middleware.StoreSession(sessionToken, expiration, &credential.ID); err != nil {
+		logger.Error(fmt.Sprintf("Failed to store admin session: %v", err))
+		return echo.ErrInternalServerError
 	}
-	c.SetCookie(cookie)
+
+	middleware.SetSessionCookie(c, sessionToken, expiration)
 
 	logger.Info("Logged in successfully")
 
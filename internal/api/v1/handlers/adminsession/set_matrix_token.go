@@ -1,6 +1,7 @@
 package adminsession
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -50,6 +51,10 @@ func (h *AdminSessionHandler) SetMatrixToken(c echo.Context) error {
 	}
 
 	if err := middleware.SetMatrixToken(cookie.Value, req.Token); err != nil {
+		if errors.Is(err, middleware.ErrSessionNotFound) {
+			logger.Info("Set matrix token failed: session not found")
+			return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Session not found"})
+		}
 		logger.Error(fmt.Sprintf("Failed to set matrix token: %v", err))
 		return echo.ErrInternalServerError
 	}
@@ -1,15 +1,26 @@
 package adminsession
 
 import (
+	"time"
+
 	"interface-api/internal/database"
+	"interface-api/pkg/throttler"
 )
 
 type AdminSessionHandler struct {
-	db database.Service
+	db               database.Service
+	usernameLockouts *throttler.LockoutTracker
+	ipLockouts       *throttler.LockoutTracker
 }
 
 func NewAdminSessionHandler(db database.Service) *AdminSessionHandler {
-	return &AdminSessionHandler{db: db}
+	usernameConfig, ipConfig := loginLockoutConfigs()
+	usernameStore, ipStore := newLoginLockoutStores(db.DB())
+	return &AdminSessionHandler{
+		db:               db,
+		usernameLockouts: throttler.NewLockoutTracker(usernameConfig, usernameStore),
+		ipLockouts:       throttler.NewLockoutTracker(ipConfig, ipStore),
+	}
 }
 
 type LoginRequest struct {
@@ -42,3 +53,23 @@ type MatrixTokenStatusResponse struct {
 type ErrorResponse struct {
 	Error string `json:"error" example:"Invalid credentials"`
 }
+
+type LoginLockout struct {
+	// username or ip
+	Type        string     `json:"type" example:"username"`
+	Value       string     `json:"value" example:"admin"`
+	Failures    int        `json:"failures" example:"5"`
+	Lockouts    int        `json:"lockouts" example:"1"`
+	Locked      bool       `json:"locked" example:"true"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastFailure time.Time  `json:"last_failure"`
+}
+
+type ListLoginLockoutsResponse struct {
+	Lockouts []LoginLockout `json:"lockouts"`
+}
+
+type ClearLoginLockoutsResponse struct {
+	Message string `json:"message" example:"Cleared 1 login lockout(s)"`
+	Cleared int    `json:"cleared" example:"1"`
+}
@@ -3,6 +3,7 @@ package credentials
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"interface-api/internal/database/models"
 	"interface-api/pkg/crypto"
@@ -15,7 +16,7 @@ import (
 // Create godoc
 //
 //	@Summary		Create a credential
-//	@Description	Create a new API credential with client_id and auto-generated client_secret
+//	@Description	Create a new API credential with client_id and auto-generated client_secret. The role defaults to user; built-in roles are user, auditor and token_issuer, and operators may define more in CREDENTIAL_ROLES_FILE. Scopes default to the role's and, when given, must fit the role and be held by the caller. An optional expires_at stops the credential from authenticating after that time, enable_signing returns a signing_secret for HMAC-signed requests, and allowed_ips limits the addresses it may be used from.
 //	@Tags			credentials,admin
 //	@Accept			json
 //	@Produce		json
@@ -45,7 +46,39 @@ func (h *CredentialHandler) Create(c echo.Context) error {
 		})
 	}
 
-	_, err := models.FindCredentialByClientID(h.db.DB(), req.ClientID)
+	role := models.RoleUser
+	if req.Role != "" {
+		role = req.Role
+	}
+
+	scopes, status, errMsg := resolveScopes(c, role, req.Scopes)
+	if errMsg != "" {
+		logger.Info(fmt.Sprintf("Credential creation failed: %s", errMsg))
+		return c.JSON(status, ErrorResponse{
+			Error: errMsg,
+		})
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		expiresAt, errMsg = parseExpiresAt(*req.ExpiresAt)
+		if errMsg != "" {
+			logger.Info(fmt.Sprintf("Credential creation failed: %s", errMsg))
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: errMsg,
+			})
+		}
+	}
+
+	allowedIPs, err := models.ParseIPAllowlist(req.AllowedIPs)
+	if err != nil {
+		logger.Info(fmt.Sprintf("Credential creation failed: %v", err))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	_, err = models.FindCredentialByClientID(h.db.DB(), req.ClientID)
 	if err == nil {
 		logger.Info(fmt.Sprintf("Credential creation failed: client_id '%s' already exists", req.ClientID))
 		return c.JSON(http.StatusConflict, ErrorResponse{
@@ -64,19 +97,58 @@ func (h *CredentialHandler) Create(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	secretHash, err := crypto.Hash(clientSecret)
+	secretHash, err := crypto.HashSecret(clientSecret)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to hash client secret: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	credential, err := models.UpsertCredential(
-		h.db.DB(),
-		req.ClientID,
-		secretHash,
-		models.RoleUser,
-		req.Description,
-	)
+	var signingSecret string
+	var encryptedSigningSecret []byte
+	if req.EnableSigning {
+		signingSecret, encryptedSigningSecret, err = models.NewSigningSecret()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to generate signing secret: %v", err))
+			return echo.ErrInternalServerError
+		}
+	}
+
+	// The expiry, allowlist and signing secret are stored in the same
+	// transaction so a failure cannot leave a half-configured credential
+	// behind that blocks retries
+	var credential *models.Credential
+	err = h.db.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		credential, err = models.UpsertCredential(
+			tx,
+			req.ClientID,
+			secretHash,
+			role,
+			scopes,
+			req.Description,
+		)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]any{}
+		if expiresAt != nil {
+			credential.ExpiresAt = expiresAt
+			updates["expires_at"] = expiresAt
+		}
+		if len(allowedIPs) > 0 {
+			credential.AllowedIPs = allowedIPs
+			updates["allowed_ips"] = allowedIPs
+		}
+		if encryptedSigningSecret != nil {
+			credential.SigningSecret = encryptedSigningSecret
+			updates["signing_secret"] = encryptedSigningSecret
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Credential{}).Where("id = ?", credential.ID).Updates(updates).Error
+	})
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to create credential: %v", err))
 		return echo.ErrInternalServerError
@@ -85,16 +157,9 @@ func (h *CredentialHandler) Create(c echo.Context) error {
 	logger.Info("Credential created successfully")
 
 	return c.JSON(http.StatusCreated, CreateResponse{
-		Message: "Credential created successfully",
-		Credential: &CredentialResponse{
-			ClientID:    credential.ClientID,
-			Role:        credential.Role,
-			Scopes:      credential.Scopes,
-			Description: credential.Description,
-			Active:      credential.Active,
-			CreatedAt:   credential.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt:   credential.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		},
-		ClientSecret: clientSecret,
+		Message:       "Credential created successfully",
+		Credential:    newCredentialResponse(credential),
+		ClientSecret:  clientSecret,
+		SigningSecret: signingSecret,
 	})
 }
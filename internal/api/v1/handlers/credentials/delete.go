@@ -3,9 +3,11 @@ package credentials
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
+	"interface-api/pkg/tokenrevoke"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -14,12 +16,14 @@ import (
 // Delete godoc
 //
 //	@Summary		Delete a credential
-//	@Description	Permanently delete a credential by client_id
+//	@Description	Permanently delete a credential by client_id. Tokens it created keep working and keep its client_id in created_by_client_id, unless cascade=true, which also deletes them with their webhooks and releases their Matrix credentials, MAS sessions and MAS users once no other token uses them. dry_run=true lists what would be deleted and released without changing anything.
 //	@Tags			credentials,admin
 //	@Produce		json
 //	@Security		BasicAuth
 //	@Security		CookieAuth
 //	@Param			client_id	path		string			true	"Client ID"
+//	@Param			cascade		query		bool			false	"Also revoke the tokens the credential created"
+//	@Param			dry_run		query		bool			false	"Preview the deletion without applying it"
 //	@Success		200			{object}	DeleteResponse	"Credential deleted successfully"
 //	@Failure		400			{object}	ErrorResponse	"Invalid request"
 //	@Failure		404			{object}	ErrorResponse	"Credential not found"
@@ -55,14 +59,93 @@ func (h *CredentialHandler) Delete(c echo.Context) error {
 		})
 	}
 
-	if err := h.db.DB().Delete(credential).Error; err != nil {
+	cascade := c.QueryParam("cascade") == "true"
+	dryRun := c.QueryParam("dry_run") == "true"
+
+	var identities []models.MatrixIdentity
+	var releases []tokenrevoke.Resources
+	response := DeleteResponse{DryRun: dryRun}
+
+	identities, err = models.FindMatrixIdentitiesByCredential(h.db.DB(), credential.ID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find credential tokens: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	if cascade {
+		releases, err = tokenrevoke.Plan(h.db.DB(), identities)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to plan token revocation: %v", err))
+			return echo.ErrInternalServerError
+		}
+
+		for _, identity := range identities {
+			webhooks, err := models.CountWebhooksByIdentity(h.db.DB(), identity.ID)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to count token webhooks: %v", err))
+				return echo.ErrInternalServerError
+			}
+			response.Tokens = append(response.Tokens, AffectedToken{
+				ID:             identity.ID,
+				Name:           identity.Name,
+				MatrixUsername: identity.MatrixUsername,
+				MatrixDeviceID: identity.MatrixDeviceID,
+				Webhooks:       webhooks,
+				ExpiresAt:      formatOptionalTime(identity.ExpiresAt),
+			})
+		}
+
+		for _, release := range releases {
+			response.Resources = append(response.Resources, ReleasedResource{
+				MatrixUsername:          release.MatrixUsername,
+				DeleteMatrixCredentials: release.DeleteCredentials,
+				RevokeMASSession:        release.MASSessionID != "",
+				DeactivateMASUser:       release.DeactivateUser,
+			})
+		}
+	}
+
+	identityIDs := make([]uint, 0, len(identities))
+	for _, identity := range identities {
+		identityIDs = append(identityIDs, identity.ID)
+	}
+
+	if dryRun {
+		if cascade {
+			response.Message = fmt.Sprintf("Dry run: credential and %d token(s) would be deleted", len(identities))
+		} else {
+			response.Message = fmt.Sprintf("Dry run: credential would be deleted and %d token(s) it created would be kept", len(identities))
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+
+	// Kept tokens lose their credential_id but still name the credential
+	// in created_by_client_id
+	if !cascade {
+		identityIDs = nil
+	}
+
+	if err := models.DeleteCredentialWithTokens(h.db.DB(), credential, identityIDs); err != nil {
 		logger.Error(fmt.Sprintf("Failed to delete credential: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	logger.Info("Credential deleted successfully")
+	// The records are gone, so teardown failures are reported and queued for
+	// the cleanup worker to retry rather than undone.
+	for _, release := range releases {
+		if err := tokenrevoke.ReleaseOrQueue(h.db.DB(), release); err != nil {
+			logger.Warn(fmt.Sprintf("Teardown of Matrix user %s was incomplete: %v", release.MatrixUsername, err))
+			response.Errors = append(response.Errors, fmt.Sprintf("%s: %s", release.MatrixUsername, strings.ReplaceAll(err.Error(), "\n", "; ")))
+		}
+	}
+
+	if cascade {
+		logger.Info(fmt.Sprintf("Credential '%s' deleted with %d token(s)", clientID, len(identities)))
+		response.Message = fmt.Sprintf("Credential and %d token(s) deleted successfully", len(identities))
+	} else {
+		logger.Info(fmt.Sprintf("Credential '%s' deleted; %d token(s) it created were kept", clientID, len(identities)))
+		response.Message = fmt.Sprintf("Credential deleted successfully; %d token(s) it created were kept", len(identities))
+	}
 
-	return c.JSON(http.StatusOK, DeleteResponse{
-		Message: "Credential deleted successfully",
-	})
+	return c.JSON(http.StatusOK, response)
 }
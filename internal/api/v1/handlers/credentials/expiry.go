@@ -0,0 +1,19 @@
+package credentials
+
+import (
+	"time"
+)
+
+// parseExpiresAt parses an RFC3339 credential expiry, which must lie in the
+// future, and returns a client facing message when it is invalid.
+func parseExpiresAt(value string) (*time.Time, string) {
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, "Invalid expires_at format. Must be RFC3339 (e.g., 2026-12-31T23:59:59Z)"
+	}
+	if !expiresAt.After(time.Now().UTC()) {
+		return nil, "expires_at must be in the future"
+	}
+	expiresAt = expiresAt.UTC()
+	return &expiresAt, ""
+}
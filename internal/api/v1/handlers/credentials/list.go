@@ -31,16 +31,8 @@ func (h *CredentialHandler) List(c echo.Context) error {
 	}
 
 	response := make([]CredentialResponse, len(credentials))
-	for i, cred := range credentials {
-		response[i] = CredentialResponse{
-			ClientID:    cred.ClientID,
-			Role:        cred.Role,
-			Scopes:      cred.Scopes,
-			Description: cred.Description,
-			Active:      cred.Active,
-			CreatedAt:   cred.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt:   cred.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		}
+	for i := range credentials {
+		response[i] = *newCredentialResponse(&credentials[i])
 	}
 
 	return c.JSON(http.StatusOK, response)
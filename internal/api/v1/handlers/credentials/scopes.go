@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"fmt"
+	"net/http"
+
+	"interface-api/internal/database/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// resolveScopes returns the scopes for a credential with the given role: the
+// requested ones if any, otherwise the role's defaults. Requested scopes must
+// fit the role, and a caller can only grant scopes it holds itself.
+func resolveScopes(c echo.Context, role models.CredentialRole, requested []string) (models.Scopes, int, string) {
+	if role == models.RoleSuperAdmin {
+		return nil, http.StatusForbidden, "super_admin credentials can only be configured through the environment"
+	}
+	if !models.IsKnownRole(role) {
+		return nil, http.StatusBadRequest, fmt.Sprintf("Unknown role '%s'", role)
+	}
+
+	scopes := models.GetDefaultScopesForRole(role)
+	if requested != nil {
+		if len(requested) == 0 {
+			return nil, http.StatusBadRequest, "scopes must not be empty"
+		}
+		scopes = models.Scopes(requested)
+		if err := models.ValidateScopesForRole(role, scopes); err != nil {
+			return nil, http.StatusBadRequest, fmt.Sprintf("Invalid scopes: %v", err)
+		}
+	}
+
+	if caller, ok := c.Get("credential").(*models.Credential); ok {
+		for _, scope := range scopes {
+			if !caller.HasScope(scope) {
+				return nil, http.StatusForbidden, fmt.Sprintf("Cannot grant scope '%s' you do not hold", scope)
+			}
+		}
+	}
+
+	return scopes, 0, ""
+}
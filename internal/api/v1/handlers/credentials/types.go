@@ -1,6 +1,8 @@
 package credentials
 
 import (
+	"time"
+
 	"interface-api/internal/database"
 	"interface-api/internal/database/models"
 )
@@ -14,14 +16,22 @@ func NewCredentialHandler(db database.Service) *CredentialHandler {
 }
 
 type CreateRequest struct {
-	ClientID    string `json:"client_id" validate:"required"`
-	Description string `json:"description"`
+	ClientID    string                `json:"client_id" validate:"required"`
+	Description string                `json:"description"`
+	Role        models.CredentialRole `json:"role,omitempty" example:"token_issuer"`
+	Scopes      []string              `json:"scopes,omitempty" example:"tokens:write:create,tokens:read:list"`
+	ExpiresAt   *string               `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+	// Issue a secret for signing requests instead of sending Basic auth
+	EnableSigning bool `json:"enable_signing,omitempty"`
+	// IPs or CIDR ranges the credential may be used from; empty allows any
+	AllowedIPs []string `json:"allowed_ips,omitempty" example:"10.0.0.0/8,203.0.113.7"`
 }
 
 type CreateResponse struct {
-	Message      string              `json:"message"`
-	Credential   *CredentialResponse `json:"credential"`
-	ClientSecret string              `json:"client_secret"`
+	Message       string              `json:"message"`
+	Credential    *CredentialResponse `json:"credential"`
+	ClientSecret  string              `json:"client_secret"`
+	SigningSecret string              `json:"signing_secret,omitempty"`
 }
 
 type ListResponse struct {
@@ -29,29 +39,90 @@ type ListResponse struct {
 }
 
 type CredentialResponse struct {
-	ClientID    string                `json:"client_id"`
-	Role        models.CredentialRole `json:"role"`
-	Scopes      models.Scopes         `json:"scopes"`
-	Description string                `json:"description"`
-	Active      bool                  `json:"active"`
-	CreatedAt   string                `json:"created_at"`
-	UpdatedAt   string                `json:"updated_at"`
+	ClientID       string                `json:"client_id"`
+	Role           models.CredentialRole `json:"role"`
+	Scopes         models.Scopes         `json:"scopes"`
+	AllowedIPs     models.IPAllowlist    `json:"allowed_ips"`
+	Description    string                `json:"description"`
+	Active         bool                  `json:"active"`
+	ExpiresAt      *string               `json:"expires_at"`
+	LastUsedAt     *string               `json:"last_used_at"`
+	SigningEnabled bool                  `json:"signing_enabled"`
+	CreatedAt      string                `json:"created_at"`
+	UpdatedAt      string                `json:"updated_at"`
+}
+
+func newCredentialResponse(credential *models.Credential) *CredentialResponse {
+	return &CredentialResponse{
+		ClientID:       credential.ClientID,
+		Role:           credential.Role,
+		Scopes:         credential.Scopes,
+		AllowedIPs:     credential.AllowedIPs,
+		Description:    credential.Description,
+		Active:         credential.Active,
+		ExpiresAt:      formatOptionalTime(credential.ExpiresAt),
+		LastUsedAt:     formatOptionalTime(credential.LastUsedAt),
+		SigningEnabled: len(credential.SigningSecret) > 0,
+		CreatedAt:      credential.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:      credential.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.UTC().Format("2006-01-02T15:04:05Z")
+	return &formatted
 }
 
 type UpdateRequest struct {
-	RegenerateSecret *bool   `json:"regenerate_secret,omitempty"`
-	Active           *bool   `json:"active,omitempty"`
-	Description      *string `json:"description,omitempty"`
+	RegenerateSecret *bool                  `json:"regenerate_secret,omitempty"`
+	Active           *bool                  `json:"active,omitempty"`
+	Description      *string                `json:"description,omitempty"`
+	Role             *models.CredentialRole `json:"role,omitempty" example:"auditor"`
+	Scopes           []string               `json:"scopes,omitempty" example:"credentials:read:list"`
+	// RFC3339 expiry; an empty string removes it
+	ExpiresAt *string `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+	// true issues a new signing secret, false disables request signing
+	SigningEnabled *bool `json:"signing_enabled,omitempty"`
+	// Replaces the allowed IPs or CIDR ranges; an empty list allows any
+	AllowedIPs *[]string `json:"allowed_ips,omitempty" example:"10.0.0.0/8"`
 }
 
 type UpdateResponse struct {
-	Message      string              `json:"message"`
-	Credential   *CredentialResponse `json:"credential"`
-	ClientSecret *string             `json:"client_secret,omitempty"`
+	Message       string              `json:"message"`
+	Credential    *CredentialResponse `json:"credential"`
+	ClientSecret  *string             `json:"client_secret,omitempty"`
+	SigningSecret *string             `json:"signing_secret,omitempty"`
 }
 
 type DeleteResponse struct {
 	Message string `json:"message"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+	// Tokens created by the credential, revoked with cascade=true
+	Tokens []AffectedToken `json:"tokens,omitempty"`
+	// Matrix and MAS resources released with the tokens
+	Resources []ReleasedResource `json:"resources,omitempty"`
+	// Teardown steps that failed; the database records are deleted regardless
+	// and the steps are retried by the cleanup worker
+	Errors []string `json:"errors,omitempty"`
+}
+
+type AffectedToken struct {
+	ID             uint    `json:"id" example:"1"`
+	Name           string  `json:"name" example:"billing-service"`
+	MatrixUsername string  `json:"matrix_username" example:"a1b2c3d4e5f6a7b8"`
+	MatrixDeviceID string  `json:"matrix_device_id" example:"a1b2c3d4e5f6a7b8"`
+	Webhooks       int64   `json:"webhooks" example:"1"`
+	ExpiresAt      *string `json:"expires_at" example:"2026-12-31T23:59:59Z"`
+}
+
+type ReleasedResource struct {
+	MatrixUsername          string `json:"matrix_username" example:"a1b2c3d4e5f6a7b8"`
+	DeleteMatrixCredentials bool   `json:"delete_matrix_credentials" example:"true"`
+	RevokeMASSession        bool   `json:"revoke_mas_session" example:"true"`
+	DeactivateMASUser       bool   `json:"deactivate_mas_user" example:"true"`
 }
 
 type ErrorResponse struct {
@@ -15,7 +15,7 @@ import (
 // Update godoc
 //
 //	@Summary		Update a credential
-//	@Description	Update credential properties (regenerate secret, activate/deactivate, or update description)
+//	@Description	Update credential properties (regenerate secret, activate/deactivate, update description, change role and scopes, set or clear (empty string) expires_at, issue (signing_enabled true) or remove the request signing secret, or replace allowed_ips, where an empty list allows any address). A new role without scopes gets the role's default scopes.
 //	@Tags			credentials,admin
 //	@Accept			json
 //	@Produce		json
@@ -85,7 +85,7 @@ func (h *CredentialHandler) Update(c echo.Context) error {
 			return echo.ErrInternalServerError
 		}
 
-		secretHash, err := crypto.Hash(generatedSecret)
+		secretHash, err := crypto.HashSecret(generatedSecret)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to hash secret: %v", err))
 			return echo.ErrInternalServerError
@@ -95,10 +95,97 @@ func (h *CredentialHandler) Update(c echo.Context) error {
 		newSecret = &generatedSecret
 	}
 
+	var newSigningSecret *string
+	if req.SigningEnabled != nil {
+		if *req.SigningEnabled {
+			signingSecret, encrypted, err := models.NewSigningSecret()
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to generate signing secret: %v", err))
+				return echo.ErrInternalServerError
+			}
+			credential.SigningSecret = encrypted
+			newSigningSecret = &signingSecret
+		} else {
+			credential.SigningSecret = nil
+		}
+	}
+
 	if req.Description != nil {
 		credential.Description = *req.Description
 	}
 
+	if req.ExpiresAt != nil {
+		if credential.Role == models.RoleSuperAdmin {
+			logger.Info(fmt.Sprintf("Credential update failed: cannot set super admin expiry '%s'", clientID))
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Error: "Cannot set an expiry on super admin credentials",
+			})
+		}
+
+		if *req.ExpiresAt == "" {
+			credential.ExpiresAt = nil
+		} else {
+			expiresAt, errMsg := parseExpiresAt(*req.ExpiresAt)
+			if errMsg != "" {
+				logger.Info(fmt.Sprintf("Credential update failed: %s", errMsg))
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error: errMsg,
+				})
+			}
+			credential.ExpiresAt = expiresAt
+		}
+	}
+
+	if req.AllowedIPs != nil {
+		if credential.Role == models.RoleSuperAdmin {
+			logger.Info(fmt.Sprintf("Credential update failed: cannot restrict super admin IPs '%s'", clientID))
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Error: "Cannot set allowed IPs on super admin credentials",
+			})
+		}
+
+		allowedIPs, err := models.ParseIPAllowlist(*req.AllowedIPs)
+		if err != nil {
+			logger.Info(fmt.Sprintf("Credential update failed: %v", err))
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		credential.AllowedIPs = allowedIPs
+	}
+
+	if req.Role != nil || req.Scopes != nil {
+		if credential.Role == models.RoleSuperAdmin {
+			logger.Info(fmt.Sprintf("Credential update failed: cannot change super admin scopes '%s'", clientID))
+			return c.JSON(http.StatusForbidden, ErrorResponse{
+				Error: "Cannot change role or scopes of super admin credentials",
+			})
+		}
+
+		role := credential.Role
+		if req.Role != nil {
+			role = *req.Role
+		}
+
+		// Keep the current scopes when only they are re-validated against an
+		// unchanged role; a new role without explicit scopes gets its defaults.
+		requested := req.Scopes
+		if requested == nil && role == credential.Role && len(credential.Scopes) > 0 {
+			requested = credential.Scopes
+		}
+
+		scopes, status, errMsg := resolveScopes(c, role, requested)
+		if errMsg != "" {
+			logger.Info(fmt.Sprintf("Credential update failed: %s", errMsg))
+			return c.JSON(status, ErrorResponse{
+				Error: errMsg,
+			})
+		}
+
+		credential.Role = role
+		credential.Scopes = scopes
+	}
+
 	if err := h.db.DB().Save(credential).Error; err != nil {
 		logger.Error(fmt.Sprintf("Failed to update credential: %v", err))
 		return echo.ErrInternalServerError
@@ -107,16 +194,9 @@ func (h *CredentialHandler) Update(c echo.Context) error {
 	logger.Info("Credential updated successfully")
 
 	return c.JSON(http.StatusOK, UpdateResponse{
-		Message: "Credential updated successfully",
-		Credential: &CredentialResponse{
-			ClientID:    credential.ClientID,
-			Role:        credential.Role,
-			Scopes:      credential.Scopes,
-			Description: credential.Description,
-			Active:      credential.Active,
-			CreatedAt:   credential.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt:   credential.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		},
-		ClientSecret: newSecret,
+		Message:       "Credential updated successfully",
+		Credential:    newCredentialResponse(credential),
+		ClientSecret:  newSecret,
+		SigningSecret: newSigningSecret,
 	})
 }
@@ -9,6 +9,7 @@ import (
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
 	"interface-api/pkg/matrixclient"
+	"interface-api/pkg/phoneutil"
 	"interface-api/pkg/rabbitmq"
 
 	"github.com/labstack/echo/v4"
@@ -17,7 +18,7 @@ import (
 // Create godoc
 //
 //	@Summary		Create a new device
-//	@Description	Create a new device for the Matrix identity
+//	@Description	Create a new device for the Matrix identity. The default "qr" method returns a WebSocket URL streaming QR codes to scan. The "pairing_code" method requests a code for the given phone number (E.164) and returns it together with a WebSocket URL streaming refreshed codes.
 //	@Tags			devices
 //	@Accept			json
 //	@Produce		json
@@ -52,16 +53,47 @@ func (h *DeviceHandler) Create(c echo.Context) error {
 		})
 	}
 
+	method := strings.TrimSpace(req.Method)
+	if method == "" {
+		method = LinkMethodQR
+	}
+
+	switch method {
+	case LinkMethodQR:
+	case LinkMethodPairingCode:
+		if strings.TrimSpace(req.PhoneNumber) == "" {
+			logger.Info("Device creation failed: missing phone_number for pairing code")
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "Missing required field: phone_number",
+			})
+		}
+		if err := phoneutil.ValidateE164(req.PhoneNumber); err != nil {
+			logger.Info("Device creation failed: invalid phone_number")
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: "phone_number must be in E.164 format (e.g., +1234567890)",
+			})
+		}
+	default:
+		logger.Info(fmt.Sprintf("Device creation failed: unsupported method %q", method))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "method must be one of: qr, pairing_code",
+		})
+	}
+
 	matrixUsername := matrixIdentity.MatrixUsername
 
-	authHeader := c.Request().Header.Get("Authorization")
-	token := strings.TrimPrefix(authHeader, "Bearer ")
+	ticket, _, err := models.CreateWSTicket(h.db.DB(), matrixIdentity.ID, models.WSTicketTTL())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create WebSocket ticket: %v", err))
+		return echo.ErrInternalServerError
+	}
 
 	scheme := "ws"
 	if c.Request().TLS != nil || c.Request().Header.Get("X-Forwarded-Proto") == "https" {
 		scheme = "wss"
 	}
-	qrCodeURL := fmt.Sprintf("%s://%s/api/v1/devices/qr-code?token=%s", scheme, c.Request().Host, token)
+	qrCodeURL := fmt.Sprintf("%s://%s/api/v1/devices/qr-code?ticket=%s", scheme, c.Request().Host, ticket)
+	pairingCodeURL := fmt.Sprintf("%s://%s/api/v1/devices/pairing-code?ticket=%s", scheme, c.Request().Host, ticket)
 
 	consumer, err := rabbitmq.NewConsumer(*h.rabbitURL)
 	if err != nil {
@@ -70,8 +102,14 @@ func (h *DeviceHandler) Create(c echo.Context) error {
 	}
 	defer consumer.Close()
 
+	pendingMethod, err := h.pendingLinkMethod(matrixUsername)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find pending device link: %v", err))
+		return echo.ErrInternalServerError
+	}
+
 	messageCount, err := consumer.GetQueueMessageCount(matrixUsername)
-	if err == nil && messageCount > 0 {
+	if method == LinkMethodQR && pendingMethod != LinkMethodPairingCode && err == nil && messageCount > 0 {
 		return c.JSON(http.StatusCreated, DeviceResponse{
 			Message:   "Scan the QR code to link your device",
 			QrCodeURL: qrCodeURL,
@@ -86,13 +124,33 @@ func (h *DeviceHandler) Create(c echo.Context) error {
 	addDeviceReq := &matrixclient.AddDeviceRequest{
 		Username:     matrixUsername,
 		PlatformName: req.Platform,
+		Method:       method,
+	}
+	if method == LinkMethodPairingCode {
+		addDeviceReq.PhoneNumber = req.PhoneNumber
 	}
-	_, err = matrixClient.AddDevice(addDeviceReq)
+
+	addDeviceResp, err := matrixClient.AddDevice(addDeviceReq)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Matrix device addition failed: %v\n%s", err, debug.Stack()))
 		return echo.ErrInternalServerError
 	}
 
+	// Recorded only once the link is under way, so a failed request does not
+	// leave a method behind that rejects the next pairing stream
+	if err := models.SetPendingDeviceLink(h.db.DB(), matrixUsername, method); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record pending device link: %v", err))
+	}
+
+	if method == LinkMethodPairingCode {
+		logger.Info("Device pairing code requested successfully")
+		return c.JSON(http.StatusCreated, DeviceResponse{
+			Message:        "Enter the pairing code on your phone to link your device",
+			PairingCode:    addDeviceResp.PairingCode,
+			PairingCodeURL: pairingCodeURL,
+		})
+	}
+
 	logger.Info("Device addition requested successfully")
 	return c.JSON(http.StatusCreated, DeviceResponse{
 		Message:   "Scan the QR code to link your device",
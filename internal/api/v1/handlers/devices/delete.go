@@ -76,6 +76,10 @@ func (h *DeviceHandler) Delete(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	if err := models.DeleteDevice(h.db.DB(), matrixUsername, req.Platform, req.DeviceID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to remove device from local registry: %v", err))
+	}
+
 	logger.Info("Device deleted successfully")
 	return c.JSON(http.StatusOK, DeviceResponse{
 		Message: "Device deleted successfully",
@@ -2,8 +2,11 @@ package devices
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"interface-api/internal/database/models"
+	"interface-api/pkg/devicesync"
 	"interface-api/pkg/logger"
 	"interface-api/pkg/matrixclient"
 
@@ -13,12 +16,14 @@ import (
 // List godoc
 //
 //	@Summary		List all devices
-//	@Description	List all devices for the Matrix identity
+//	@Description	List devices for the Matrix identity from the local device registry. Pass refresh=true to reconcile with the Matrix client first.
 //	@Tags			devices
 //	@Accept			json
 //	@Produce		json
-//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Param			Authorization		header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
 //	@Security		BearerAuth
+//	@Param			refresh				query		bool			false	"Reconcile with the Matrix client before listing"
+//	@Param			include_unlinked	query		bool			false	"Include devices that are no longer linked"
 //	@Success		200	{array}		Device			"List of devices"
 //	@Failure		401	{object}	ErrorResponse	"Invalid or expired matrix token"
 //	@Failure		403	{object}	ErrorResponse	"Invalid or expired matrix token"
@@ -33,28 +38,56 @@ func (h *DeviceHandler) List(c echo.Context) error {
 
 	matrixUsername := matrixIdentity.MatrixUsername
 
-	matrixClient, err := matrixclient.New()
+	devices, err := models.FindDevicesByUsername(h.db.DB(), matrixUsername)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to create Matrix client: %v", err))
+		logger.Error(fmt.Sprintf("Failed to load devices: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	listDevicesReq := &matrixclient.ListDevicesRequest{
-		Username: matrixUsername,
-	}
-	devices, err := matrixClient.ListDevices(listDevicesReq)
-	if err != nil {
-		logger.Error(fmt.Sprintf("Matrix device list retrieval failed: %v", err))
-		return echo.ErrInternalServerError
+	if c.QueryParam("refresh") == "true" {
+		matrixClient, err := matrixclient.New()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to create Matrix client: %v", err))
+			return echo.ErrInternalServerError
+		}
+
+		if err := devicesync.SyncUser(h.db.DB(), matrixClient, matrixUsername); err != nil {
+			logger.Error(fmt.Sprintf("Matrix device list reconciliation failed: %v", err))
+			return echo.ErrInternalServerError
+		}
+
+		devices, err = models.FindDevicesByUsername(h.db.DB(), matrixUsername)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to load devices: %v", err))
+			return echo.ErrInternalServerError
+		}
 	}
 
+	includeUnlinked := c.QueryParam("include_unlinked") == "true"
+
 	response := make(ListDevicesResponse, 0, len(devices))
 	for _, device := range devices {
-		response = append(response, Device{
-			Platform: device.BridgeName,
-			DeviceID: device.DeviceID,
-		})
+		if device.Status == models.DeviceStatusUnlinked && !includeUnlinked {
+			continue
+		}
+		response = append(response, newDeviceResponse(device))
 	}
 
-	return c.JSON(200, response)
+	return c.JSON(http.StatusOK, response)
+}
+
+func newDeviceResponse(device models.Device) Device {
+	response := Device{
+		Platform:            device.Platform,
+		DeviceID:            device.DeviceID,
+		Label:               device.Label,
+		Status:              string(device.Status),
+		LinkedAt:            device.LinkedAt.Format(time.RFC3339),
+		ConsecutiveFailures: device.ConsecutiveFailures,
+	}
+	if device.LastSendAt != nil {
+		lastSendAt := device.LastSendAt.Format(time.RFC3339)
+		response.LastSendAt = &lastSendAt
+	}
+	return response
 }
@@ -0,0 +1,76 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/devicesync"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DispatchMessage godoc
+//
+//	@Summary		Send a message via an automatically selected device
+//	@Description	Queue a message on a healthy device linked for the platform. Active devices are preferred over degraded ones and the device is picked by the configured strategy (round robin or least recently used). If delivery fails the worker retries on another device of the same platform. Either text or file must be provided (or both).
+//	@Tags			messages
+//	@Accept			json,mpfd
+//	@Produce		json
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Param			request		body		SendMessageRequest	false	"Message to send (JSON)"
+//	@Param			contact		formData	string				false	"Contact (multipart)"
+//	@Param			platform	formData	string				false	"Platform (multipart)"
+//	@Param			text		formData	string				false	"Message text (multipart, optional if file provided)"
+//	@Param			file		formData	file				false	"File to upload (multipart)"
+//	@Success		200			{object}	SendMessageResponse	"Message queued successfully"
+//	@Failure		400			{object}	ErrorResponse		"Invalid request body or validation error"
+//	@Failure		401			{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		403			{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		404			{object}	ErrorResponse		"No available device for platform"
+//	@Failure		413			{object}	ErrorResponse		"Uploaded file exceeds the platform size limit"
+//	@Failure		415			{object}	ErrorResponse		"Uploaded file type is not allowed or does not match its extension"
+//	@Failure		500			{object}	ErrorResponse		"Internal server error"
+//	@Router			/api/v1/messages [post]
+func (h *DeviceHandler) DispatchMessage(c echo.Context) error {
+	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+	if !ok {
+		logger.Error("Matrix identity not found in context")
+		return echo.ErrUnauthorized
+	}
+
+	message, err := h.bindMessage(c)
+	if err != nil {
+		return err
+	}
+
+	device, err := devicesync.SelectDevice(h.db.DB(), matrixIdentity.MatrixUsername, message.PlatformName, nil)
+	if errors.Is(err, devicesync.ErrNoAvailableDevice) {
+		logger.Info(fmt.Sprintf("Message dispatch failed: no available %s device", message.PlatformName))
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "No available device for platform",
+		})
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Device selection failed: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	message.DeviceID = device.DeviceID
+	message.Username = matrixIdentity.MatrixUsername
+	message.AutoSelect = true
+	message.AttemptedDevices = []string{device.DeviceID}
+
+	if err := h.publishMessage(message); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("Message queued successfully on selected %s device", message.PlatformName))
+	return c.JSON(http.StatusOK, SendMessageResponse{
+		Message:  "Message queued successfully",
+		DeviceID: device.DeviceID,
+	})
+}
@@ -2,33 +2,79 @@ package devices
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"time"
 
 	"interface-api/internal/database/models"
+	"interface-api/pkg/devicesync"
 	"interface-api/pkg/logger"
+	"interface-api/pkg/matrixclient"
+	"interface-api/pkg/qrimage"
 	"interface-api/pkg/rabbitmq"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/streadway/amqp"
+	"gorm.io/gorm"
 )
 
+const qrWriteWait = 10 * time.Second
+
+var errNoPendingDevice = errors.New("no pending device to add")
+
+func getDurationSeconds(key string, defaultValue time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return defaultValue
+}
+
 // QRCode godoc
 //
 //	@Summary		WebSocket qr-code endpoint (Not executable in Swagger UI)
-//	@Description	Establishes a WebSocket connection to stream real-time add devices qr-code. Authentication via query parameter 'token' (e.g., wss://api/v1/devices/qr-code?token=mt_xxxxx). This endpoint cannot be tested in Swagger UI - use a WebSocket client instead.
+//	@Description	Establishes a WebSocket connection to stream real-time add devices qr-code. Authentication via a single-use query parameter 'ticket' (e.g., wss://api/v1/devices/qr-code?ticket=wst_xxxxx) obtained from /ws-tickets or returned by device creation. The 'token' query parameter is still accepted but exposes the long-lived matrix token in URLs. Every frame is a JSON QRCodeMessage: "qr" carries a QR payload to render, "paired" reports the newly linked device and ends the session, "expired" is sent when pairing times out, and "error" carries a code and message, with code "method_mismatch" when the pending device was requested with method "pairing_code". The server pings the client periodically and closes the socket after the final message. This endpoint cannot be tested in Swagger UI - use a WebSocket client instead.
 //	@Tags			devices
 //	@Produce		json
-//	@Param			token	query		string			true	"Matrix token (obtained from /tokens) - format: mt_xxxxx"
-//	@Success		101		{string}	string			"WebSocket connection established"
-//	@Failure		401		{object}	ErrorResponse	"Missing or invalid matrix token"
-//	@Failure		403		{object}	ErrorResponse	"Invalid or expired matrix token"
-//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Param			ticket			query		string			false	"Single-use WebSocket ticket (obtained from /ws-tickets) - format: wst_xxxxx"
+//	@Param			token			query		string			false	"Matrix token (obtained from /tokens) - format: mt_xxxxx"
+//	@Param			include_image	query		bool			false	"Include a base64 encoded PNG of each QR code in the image field"
+//	@Success		101				{object}	QRCodeMessage	"WebSocket connection established"
+//	@Failure		401				{object}	ErrorResponse	"Missing or invalid ticket or matrix token"
+//	@Failure		403				{object}	ErrorResponse	"Invalid, expired or used ticket or matrix token"
+//	@Failure		500				{object}	ErrorResponse	"Internal server error"
 //	@Router			/api/v1/devices/qr-code [get]
 //	@deprecated
 func (h *DeviceHandler) QRCode(c echo.Context) error {
+	return h.streamPairing(c, QRMessageTypeQR)
+}
+
+// PairingCode godoc
+//
+//	@Summary		WebSocket pairing code endpoint (Not executable in Swagger UI)
+//	@Description	Establishes a WebSocket connection to stream the pairing code of a device requested with method "pairing_code". Authentication and framing are the same as the qr-code WebSocket, except that codes are sent as "pairing_code" messages whose data is the code to enter on the phone. A pending device requested with method "qr" is rejected with a "method_mismatch" error. This endpoint cannot be tested in Swagger UI - use a WebSocket client instead.
+//	@Tags			devices
+//	@Produce		json
+//	@Param			ticket	query		string			false	"Single-use WebSocket ticket (obtained from /ws-tickets) - format: wst_xxxxx"
+//	@Param			token	query		string			false	"Matrix token (obtained from /tokens) - format: mt_xxxxx"
+//	@Success		101		{object}	QRCodeMessage	"WebSocket connection established"
+//	@Failure		401		{object}	ErrorResponse	"Missing or invalid ticket or matrix token"
+//	@Failure		403		{object}	ErrorResponse	"Invalid, expired or used ticket or matrix token"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/devices/pairing-code [get]
+func (h *DeviceHandler) PairingCode(c echo.Context) error {
+	return h.streamPairing(c, QRMessageTypePairingCode)
+}
+
+// streamPairing forwards pairing payloads from the add device queue as
+// messages of the given type until a device is paired or the session expires.
+func (h *DeviceHandler) streamPairing(c echo.Context, messageType string) error {
 	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
 	if !ok {
 		logger.Error("Matrix identity not found in context")
@@ -37,11 +83,12 @@ func (h *DeviceHandler) QRCode(c echo.Context) error {
 
 	matrixUsername := matrixIdentity.MatrixUsername
 
-	queueSuffix := os.Getenv("ADD_DEVICE_QUEUE_SUFFIX")
-	if queueSuffix == "" {
-		queueSuffix = "_add_new_device"
-	}
-	queueName := matrixUsername + queueSuffix
+	includeImage := messageType == QRMessageTypeQR && c.QueryParam("include_image") == "true"
+
+	pingInterval := getDurationSeconds("QR_CODE_PING_INTERVAL_SECONDS", 30*time.Second)
+	pongWait := 2 * pingInterval
+	pairingTimeout := getDurationSeconds("QR_CODE_PAIRING_TIMEOUT_SECONDS", 5*time.Minute)
+	pairingPollInterval := getDurationSeconds("QR_CODE_PAIRING_POLL_INTERVAL_SECONDS", 5*time.Second)
 
 	ws, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
@@ -49,19 +96,51 @@ func (h *DeviceHandler) QRCode(c echo.Context) error {
 		return err
 	}
 	defer ws.Close()
+	defer h.syncDevices(matrixUsername)
 
 	logger.Debug("WebSocket connection established")
 
-	consumer, err := rabbitmq.NewConsumer(*h.rabbitURL)
+	matrixClient, err := matrixclient.New()
 	if err != nil {
-		logger.Error(fmt.Sprintf("RabbitMQ consumer creation failed: %v\n%s", err, debug.Stack()))
-		ws.WriteMessage(
-			websocket.TextMessage,
-			fmt.Append(nil, "Error: Oops, something went wrong. Please try again later."),
-		)
+		logger.Error(fmt.Sprintf("Failed to create Matrix client: %v", err))
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorInternal,
+			Message: "Oops, something went wrong. Please try again later.",
+		})
+		return err
+	}
+
+	pendingMethod, err := h.pendingLinkMethod(matrixUsername)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find pending device link: %v", err))
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorInternal,
+			Message: "Oops, something went wrong. Please try again later.",
+		})
+		return err
+	}
+	if pendingMethod != "" && pendingMethod != messageType {
+		logger.Info(fmt.Sprintf("Pairing stream rejected: device was requested with method %s, not %s", pendingMethod, messageType))
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorMethodMismatch,
+			Message: fmt.Sprintf("Your pending device was requested with method %s. Connect to %s instead.", pendingMethod, pairingStreamPath(pendingMethod)),
+		})
+		return nil
+	}
+
+	knownDevices, err := listDeviceKeys(matrixClient, matrixUsername)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to list devices before pairing: %v", err))
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorInternal,
+			Message: "Oops, something went wrong. Please try again later.",
+		})
 		return err
 	}
-	defer consumer.Close()
 
 	messageChan := make(chan []byte, 100)
 
@@ -80,38 +159,36 @@ func (h *DeviceHandler) QRCode(c echo.Context) error {
 		}
 	}
 
-	exchangeName := os.Getenv("ADD_DEVICE_EXCHANGE")
-	if exchangeName == "" {
-		exchangeName = "bridges.topic"
-	}
-
-	bindingKey := os.Getenv("ADD_DEVICE_BINDING_KEY")
-	if bindingKey == "" {
-		bindingKey = "bridges.topic.add_new_device"
+	consumer, err := h.consumePairingQueue(ctx, cancel, matrixUsername, messageHandler)
+	if err == errNoPendingDevice {
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorNoPendingDevice,
+			Message: "You have no pending devices to add. Add a device and try again.",
+		})
+		return err
 	}
-
-	consumeOpts := rabbitmq.DefaultConsumeOptions()
-	consumeOpts.BindExchange = exchangeName
-	consumeOpts.BindingKey = bindingKey
-	consumeOpts.ExchangeType = "topic"
-
-	err = consumer.Consume(ctx, queueName, messageHandler, cancel, consumeOpts)
 	if err != nil {
-		logger.Error(fmt.Sprintf("QR code queue consumption failed: %v\n%s", err, debug.Stack()))
-		ws.WriteMessage(
-			websocket.TextMessage,
-			fmt.Append(nil, "Error: You have no pending devices to add. Add a device and try again."),
-		)
+		closeQRSession(ws, QRCodeMessage{
+			Type:    QRMessageTypeError,
+			Code:    QRErrorInternal,
+			Message: "Oops, something went wrong. Please try again later.",
+		})
 		return err
 	}
+	defer consumer.Close()
 
 	logger.Debug("Started consuming QR code queue")
 
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	done := make(chan struct{})
 
 	go func() {
 		defer close(done)
-		defer close(messageChan)
 		for {
 			_, _, err := ws.ReadMessage()
 			if err != nil {
@@ -122,25 +199,217 @@ func (h *DeviceHandler) QRCode(c echo.Context) error {
 		}
 	}()
 
+	expiresAt := time.Now().Add(pairingTimeout)
+	pairingTimer := time.NewTimer(pairingTimeout)
+	defer pairingTimer.Stop()
+
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	pollTicker := time.NewTicker(pairingPollInterval)
+	defer pollTicker.Stop()
+
 	for {
 		select {
 		case <-done:
 			logger.Debug("WebSocket connection closed")
 			return nil
-		case msg, ok := <-messageChan:
-			if !ok {
-				logger.Debug("QR code message channel closed")
-				return nil
+		case <-ctx.Done():
+			logger.Debug("WebSocket context cancelled")
+			return nil
+		case msg := <-messageChan:
+			qrMessage := QRCodeMessage{
+				Type:      messageType,
+				Data:      string(msg),
+				ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
 			}
-			err := ws.WriteMessage(websocket.TextMessage, msg)
-			if err != nil {
+			if includeImage {
+				image, err := qrimage.PNG(qrMessage.Data, qrimage.DefaultSize)
+				if err != nil {
+					logger.Warn(fmt.Sprintf("QR code image rendering failed: %v", err))
+				} else {
+					qrMessage.Image = base64.StdEncoding.EncodeToString(image)
+				}
+			}
+			if err := writeQRMessage(ws, qrMessage); err != nil {
 				logger.Error(fmt.Sprintf("WebSocket message write failed: %v", err))
 				return err
 			}
-			logger.Debug("QR code sent to client")
-		case <-ctx.Done():
-			logger.Debug("WebSocket context cancelled")
+			logger.Debug(fmt.Sprintf("Pairing %s sent to client", messageType))
+		case <-pingTicker.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(qrWriteWait)); err != nil {
+				logger.Debug(fmt.Sprintf("WebSocket ping failed: %v", err))
+				return nil
+			}
+		case <-pollTicker.C:
+			device, err := findNewDevice(matrixClient, matrixUsername, knownDevices)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Pairing status check failed: %v", err))
+				continue
+			}
+			if device == nil {
+				continue
+			}
+			logger.Info(fmt.Sprintf("New %s device paired via %s", device.BridgeName, messageType))
+			if err := models.DeletePendingDeviceLink(h.db.DB(), matrixUsername); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to delete pending device link: %v", err))
+			}
+			closeQRSession(ws, QRCodeMessage{
+				Type:     QRMessageTypePaired,
+				Platform: device.BridgeName,
+				DeviceID: device.DeviceID,
+			})
 			return nil
+		case <-pairingTimer.C:
+			logger.Info("Pairing session expired")
+			closeQRSession(ws, QRCodeMessage{
+				Type:    QRMessageTypeExpired,
+				Message: "Pairing timed out. Add the device again to get a new code.",
+			})
+			return nil
+		}
+	}
+}
+
+// pendingLinkMethod returns the method the user's pending device addition was
+// requested with, or an empty string when none was recorded. Both methods
+// deliver their payloads on the same add device queue, so the method decides
+// whether they are QR codes or pairing codes.
+func (h *DeviceHandler) pendingLinkMethod(matrixUsername string) (string, error) {
+	link, err := models.FindPendingDeviceLink(h.db.DB(), matrixUsername)
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return link.Method, nil
+}
+
+func pairingStreamPath(method string) string {
+	if method == LinkMethodPairingCode {
+		return "/api/v1/devices/pairing-code"
+	}
+	return "/api/v1/devices/qr-code"
+}
+
+// consumePairingQueue starts consuming the per-user add device queue. It
+// returns errNoPendingDevice when the queue does not exist because no device
+// addition has been requested, and other broker errors as they are.
+func (h *DeviceHandler) consumePairingQueue(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	matrixUsername string,
+	handler rabbitmq.DeliveryHandler,
+) (*rabbitmq.Consumer, error) {
+	queueSuffix := os.Getenv("ADD_DEVICE_QUEUE_SUFFIX")
+	if queueSuffix == "" {
+		queueSuffix = "_add_new_device"
+	}
+	queueName := matrixUsername + queueSuffix
+
+	exchangeName := os.Getenv("ADD_DEVICE_EXCHANGE")
+	if exchangeName == "" {
+		exchangeName = "bridges.topic"
+	}
+
+	bindingKey := os.Getenv("ADD_DEVICE_BINDING_KEY")
+	if bindingKey == "" {
+		bindingKey = "bridges.topic.add_new_device"
+	}
+
+	consumer, err := rabbitmq.NewConsumer(*h.rabbitURL)
+	if err != nil {
+		logger.Error(fmt.Sprintf("RabbitMQ consumer creation failed: %v\n%s", err, debug.Stack()))
+		return nil, err
+	}
+
+	consumeOpts := rabbitmq.DefaultConsumeOptions()
+	consumeOpts.BindExchange = exchangeName
+	consumeOpts.BindingKey = bindingKey
+	consumeOpts.ExchangeType = "topic"
+
+	if err := consumer.Consume(ctx, queueName, handler, cancel, consumeOpts); err != nil {
+		consumer.Close()
+		if rabbitmq.IsNotFound(err) {
+			logger.Info(fmt.Sprintf("No add device queue for user: %v", err))
+			return nil, errNoPendingDevice
+		}
+		logger.Error(fmt.Sprintf("QR code queue consumption failed: %v\n%s", err, debug.Stack()))
+		return nil, err
+	}
+
+	return consumer, nil
+}
+
+func writeQRMessage(ws *websocket.Conn, msg QRCodeMessage) error {
+	ws.SetWriteDeadline(time.Now().Add(qrWriteWait))
+	return ws.WriteJSON(msg)
+}
+
+// closeQRSession sends the final message of a session followed by a close frame
+func closeQRSession(ws *websocket.Conn, msg QRCodeMessage) {
+	if err := writeQRMessage(ws, msg); err != nil {
+		logger.Debug(fmt.Sprintf("WebSocket final message write failed: %v", err))
+		return
+	}
+
+	closeCode := websocket.CloseNormalClosure
+	if msg.Type == QRMessageTypeError {
+		closeCode = websocket.CloseInternalServerErr
+		if msg.Code == QRErrorNoPendingDevice || msg.Code == QRErrorMethodMismatch {
+			closeCode = websocket.ClosePolicyViolation
+		}
+	}
+	ws.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeCode, msg.Type),
+		time.Now().Add(qrWriteWait),
+	)
+}
+
+func listDeviceKeys(matrixClient *matrixclient.Client, matrixUsername string) (map[string]bool, error) {
+	devices, err := matrixClient.ListDevices(&matrixclient.ListDevicesRequest{
+		Username: matrixUsername,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		keys[device.BridgeName+":"+device.DeviceID] = true
+	}
+	return keys, nil
+}
+
+// findNewDevice returns the first device reported by the Matrix client that
+// was not linked when the session started.
+func findNewDevice(matrixClient *matrixclient.Client, matrixUsername string, known map[string]bool) (*matrixclient.Device, error) {
+	devices, err := matrixClient.ListDevices(&matrixclient.ListDevicesRequest{
+		Username: matrixUsername,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		if !known[device.BridgeName+":"+device.DeviceID] {
+			return &device, nil
 		}
 	}
+	return nil, nil
+}
+
+// syncDevices records devices linked during the QR flow in the local registry
+func (h *DeviceHandler) syncDevices(matrixUsername string) {
+	matrixClient, err := matrixclient.New()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create Matrix client: %v", err))
+		return
+	}
+
+	if err := devicesync.SyncUser(h.db.DB(), matrixClient, matrixUsername); err != nil {
+		logger.Error(fmt.Sprintf("Device registry sync after QR flow failed: %v", err))
+	}
 }
@@ -0,0 +1,141 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/qrimage"
+
+	"github.com/labstack/echo/v4"
+	"github.com/streadway/amqp"
+)
+
+// QRCodePNG godoc
+//
+//	@Summary		Get the pairing QR code as a PNG image
+//	@Description	Waits for the next pairing payload of a pending device addition and returns it rendered as a PNG image. Request a device first with POST /api/v1/devices.
+//	@Tags			devices
+//	@Produce		png
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Param			size	query		int				false	"Image width in pixels (64-1024, default 256)"
+//	@Success		200		{file}		binary			"QR code image"
+//	@Failure		400		{object}	ErrorResponse	"Invalid size"
+//	@Failure		401		{object}	ErrorResponse	"Invalid or expired matrix token"
+//	@Failure		403		{object}	ErrorResponse	"Invalid or expired matrix token"
+//	@Failure		404		{object}	ErrorResponse	"No pending device to add"
+//	@Failure		409		{object}	ErrorResponse	"Pending device was requested with a pairing code"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Failure		504		{object}	ErrorResponse	"Timed out waiting for a QR code"
+//	@Router			/api/v1/devices/qr-code.png [get]
+func (h *DeviceHandler) QRCodePNG(c echo.Context) error {
+	return h.renderQRCode(c, "image/png", qrimage.PNG)
+}
+
+// QRCodeSVG godoc
+//
+//	@Summary		Get the pairing QR code as an SVG image
+//	@Description	Waits for the next pairing payload of a pending device addition and returns it rendered as an SVG image. Request a device first with POST /api/v1/devices.
+//	@Tags			devices
+//	@Produce		image/svg+xml
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Param			size	query		int				false	"Image width in pixels (64-1024, default 256)"
+//	@Success		200		{file}		binary			"QR code image"
+//	@Failure		400		{object}	ErrorResponse	"Invalid size"
+//	@Failure		401		{object}	ErrorResponse	"Invalid or expired matrix token"
+//	@Failure		403		{object}	ErrorResponse	"Invalid or expired matrix token"
+//	@Failure		404		{object}	ErrorResponse	"No pending device to add"
+//	@Failure		409		{object}	ErrorResponse	"Pending device was requested with a pairing code"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Failure		504		{object}	ErrorResponse	"Timed out waiting for a QR code"
+//	@Router			/api/v1/devices/qr-code.svg [get]
+func (h *DeviceHandler) QRCodeSVG(c echo.Context) error {
+	return h.renderQRCode(c, "image/svg+xml", qrimage.SVG)
+}
+
+func (h *DeviceHandler) renderQRCode(c echo.Context, contentType string, render func(string, int) ([]byte, error)) error {
+	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+	if !ok {
+		logger.Error("Matrix identity not found in context")
+		return echo.ErrUnauthorized
+	}
+
+	size := qrimage.DefaultSize
+	if sizeParam := c.QueryParam("size"); sizeParam != "" {
+		n, err := strconv.Atoi(sizeParam)
+		if err != nil || n < qrimage.MinSize || n > qrimage.MaxSize {
+			logger.Info(fmt.Sprintf("QR code image failed: invalid size %q", sizeParam))
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: qrimage.ErrInvalidSize.Error(),
+			})
+		}
+		size = n
+	}
+
+	pendingMethod, err := h.pendingLinkMethod(matrixIdentity.MatrixUsername)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find pending device link: %v", err))
+		return echo.ErrInternalServerError
+	}
+	if pendingMethod == LinkMethodPairingCode {
+		logger.Info("QR code image failed: pending device was requested with a pairing code")
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Your pending device was requested with method pairing_code. Connect to /api/v1/devices/pairing-code instead.",
+		})
+	}
+
+	waitTimeout := getDurationSeconds("QR_CODE_IMAGE_WAIT_SECONDS", 30*time.Second)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), waitTimeout)
+	defer cancel()
+
+	payloadChan := make(chan []byte, 1)
+	handler := func(delivery amqp.Delivery) error {
+		select {
+		case payloadChan <- delivery.Body:
+		default:
+		}
+		return nil
+	}
+
+	consumer, err := h.consumePairingQueue(ctx, cancel, matrixIdentity.MatrixUsername, handler)
+	if err == errNoPendingDevice {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "You have no pending devices to add. Add a device and try again.",
+		})
+	}
+	if err != nil {
+		return echo.ErrInternalServerError
+	}
+	defer consumer.Close()
+
+	var payload []byte
+	select {
+	case payload = <-payloadChan:
+	case <-ctx.Done():
+		if c.Request().Context().Err() != nil {
+			logger.Debug("QR code image request cancelled by client")
+			return nil
+		}
+		logger.Info("QR code image failed: timed out waiting for a QR code")
+		return c.JSON(http.StatusGatewayTimeout, ErrorResponse{
+			Error: "Timed out waiting for a QR code. Try again.",
+		})
+	}
+
+	image, err := render(string(payload), size)
+	if err != nil {
+		logger.Error(fmt.Sprintf("QR code image rendering failed: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	logger.Debug("QR code image sent to client")
+	return c.Blob(http.StatusOK, contentType, image)
+}
@@ -2,29 +2,36 @@ package devices
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"runtime/debug"
 	"strings"
 
 	"interface-api/internal/database/models"
+	"interface-api/pkg/attachment"
 	"interface-api/pkg/logger"
 	"interface-api/pkg/rabbitmq"
 
 	"github.com/labstack/echo/v4"
 )
 
+// multipartOverhead leaves room for the text fields and part headers on top of
+// the largest accepted file.
+const multipartOverhead = 1 << 20
+
 type queuedMessage struct {
-	DeviceID      string `json:"device_id"`
-	Contact       string `json:"contact"`
-	PlatformName  string `json:"platform_name"`
-	Text          string `json:"text"`
-	Username      string `json:"username"`
-	FileContent   string `json:"file_content,omitempty"`
-	FileExtension string `json:"file_extension,omitempty"`
+	DeviceID         string   `json:"device_id"`
+	Contact          string   `json:"contact"`
+	PlatformName     string   `json:"platform_name"`
+	Text             string   `json:"text"`
+	Username         string   `json:"username"`
+	FileContent      string   `json:"file_content,omitempty"`
+	FileExtension    string   `json:"file_extension,omitempty"`
+	AutoSelect       bool     `json:"auto_select,omitempty"`
+	AttemptedDevices []string `json:"attempted_devices,omitempty"`
 }
 
 // SendMessage godoc
@@ -46,6 +53,8 @@ type queuedMessage struct {
 //	@Failure		400			{object}	ErrorResponse		"Invalid request body or validation error"
 //	@Failure		401			{object}	ErrorResponse		"Invalid or expired matrix token"
 //	@Failure		403			{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		413			{object}	ErrorResponse		"Uploaded file exceeds the platform size limit"
+//	@Failure		415			{object}	ErrorResponse		"Uploaded file type is not allowed or does not match its extension"
 //	@Failure		500			{object}	ErrorResponse		"Internal server error"
 //	@Router			/api/v1/devices/{device_id}/message [post]
 func (h *DeviceHandler) SendMessage(c echo.Context) error {
@@ -63,17 +72,44 @@ func (h *DeviceHandler) SendMessage(c echo.Context) error {
 		})
 	}
 
+	message, err := h.bindMessage(c)
+	if err != nil {
+		return err
+	}
+
+	message.DeviceID = deviceID
+	message.Username = matrixIdentity.MatrixUsername
+
+	if err := h.publishMessage(message); err != nil {
+		return err
+	}
+
+	logger.Info("Message queued successfully")
+	return c.JSON(http.StatusOK, SendMessageResponse{
+		Message: "Message queued successfully",
+	})
+}
+
+// bindMessage reads a JSON or multipart send request and validates its
+// contact, platform, text and attachment.
+func (h *DeviceHandler) bindMessage(c echo.Context) (*queuedMessage, error) {
 	var req SendMessageRequest
-	var fileContent string
-	var fileExtension string
+	var fileName string
+	var fileBytes []byte
 
 	contentType := c.Request().Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "multipart/form-data") {
-		if err := c.Request().ParseMultipartForm(32 << 20); err != nil { // 32 MB max
+		maxBodySize := h.attachments.MaxUploadSize() + multipartOverhead
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxBodySize)
+
+		if err := c.Request().ParseMultipartForm(32 << 20); err != nil { // 32 MB in memory, rest on disk
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				logger.Info(fmt.Sprintf("Message send failed: request body exceeds %d bytes", maxBytesErr.Limit))
+				return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Uploaded file is too large")
+			}
 			logger.Info(fmt.Sprintf("Message send failed: cannot parse multipart form - %v", err))
-			return c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: "Invalid multipart form data",
-			})
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid multipart form data")
 		}
 
 		req.Contact = c.FormValue("contact")
@@ -82,70 +118,87 @@ func (h *DeviceHandler) SendMessage(c echo.Context) error {
 
 		file, err := c.FormFile("file")
 		if err == nil && file != nil {
+			if maxSize := h.attachments.MaxSize(req.Platform); maxSize > 0 && file.Size > maxSize {
+				logger.Info(fmt.Sprintf("Message send failed: uploaded file exceeds %d bytes", maxSize))
+				return nil, echo.NewHTTPError(
+					http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Uploaded file exceeds the maximum size of %d bytes for this platform", maxSize),
+				)
+			}
+
 			src, err := file.Open()
 			if err != nil {
 				logger.Info(fmt.Sprintf("Message send failed: cannot open uploaded file - %v", err))
-				return c.JSON(http.StatusBadRequest, ErrorResponse{
-					Error: "Failed to process uploaded file",
-				})
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to process uploaded file")
 			}
 			defer src.Close()
 
-			fileBytes, err := io.ReadAll(src)
+			fileBytes, err = io.ReadAll(src)
 			if err != nil {
 				logger.Info(fmt.Sprintf("Message send failed: cannot read uploaded file - %v", err))
-				return c.JSON(http.StatusBadRequest, ErrorResponse{
-					Error: "Failed to read uploaded file",
-				})
-			}
-
-			fileContent = base64.StdEncoding.EncodeToString(fileBytes)
-			fileExtension = strings.TrimPrefix(filepath.Ext(file.Filename), ".")
-			if fileExtension == "" {
-				logger.Info("Message send failed: uploaded file has no extension")
-				return c.JSON(http.StatusBadRequest, ErrorResponse{
-					Error: "Uploaded file must have a file extension",
-				})
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Failed to read uploaded file")
 			}
+			fileName = file.Filename
 		}
 	} else {
 		if err := c.Bind(&req); err != nil {
 			logger.Info(fmt.Sprintf("Message send failed: invalid request body - %v", err))
-			return c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: "Invalid request body. Must be a JSON object.",
-			})
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request body. Must be a JSON object.")
 		}
 	}
 
 	if strings.TrimSpace(req.Contact) == "" {
 		logger.Info("Message send failed: missing contact")
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Missing required field: contact",
-		})
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Missing required field: contact")
 	}
 
 	if strings.TrimSpace(req.Platform) == "" {
 		logger.Info("Message send failed: missing platform")
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Missing required field: platform",
-		})
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Missing required field: platform")
 	}
 
-	if strings.TrimSpace(req.Text) == "" && fileContent == "" {
+	if strings.TrimSpace(req.Text) == "" && fileBytes == nil {
 		logger.Info("Message send failed: missing text and file")
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: "Either text or file must be provided",
-		})
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Either text or file must be provided")
 	}
 
-	matrixUsername := matrixIdentity.MatrixUsername
+	message := &queuedMessage{
+		Contact:      req.Contact,
+		PlatformName: req.Platform,
+		Text:         req.Text,
+	}
 
+	if fileBytes != nil {
+		extension, err := h.attachments.Validate(req.Platform, fileName, fileBytes)
+		if err != nil {
+			logger.Info(fmt.Sprintf("Message send failed: %v", err))
+			switch {
+			case errors.Is(err, attachment.ErrFileTooLarge):
+				return nil, echo.NewHTTPError(
+					http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Uploaded file exceeds the maximum size of %d bytes for this platform", h.attachments.MaxSize(req.Platform)),
+				)
+			case errors.Is(err, attachment.ErrUnsupportedType), errors.Is(err, attachment.ErrTypeMismatch):
+				return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+			default:
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Uploaded file must have a file extension")
+			}
+		}
+
+		message.FileContent = base64.StdEncoding.EncodeToString(fileBytes)
+		message.FileExtension = extension
+	}
+
+	return message, nil
+}
+
+func (h *DeviceHandler) publishMessage(message *queuedMessage) error {
 	exchangeName := os.Getenv("MESSAGE_EXCHANGE_NAME")
 	if exchangeName == "" {
 		exchangeName = "shortmesh.messages"
 	}
 
-	routingKey := fmt.Sprintf("message.%s.%s", req.Platform, matrixUsername)
+	routingKey := fmt.Sprintf("message.%s.%s", message.PlatformName, message.Username)
 
 	producer, err := rabbitmq.NewProducer(*h.rabbitURL)
 	if err != nil {
@@ -159,23 +212,10 @@ func (h *DeviceHandler) SendMessage(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	message := queuedMessage{
-		DeviceID:      deviceID,
-		Contact:       req.Contact,
-		PlatformName:  req.Platform,
-		Text:          req.Text,
-		Username:      matrixUsername,
-		FileContent:   fileContent,
-		FileExtension: fileExtension,
-	}
-
 	if err := producer.Publish(exchangeName, routingKey, message, rabbitmq.DefaultPublishOptions()); err != nil {
 		logger.Error(fmt.Sprintf("RabbitMQ message publish failed: %v\n%s", err, debug.Stack()))
 		return echo.ErrInternalServerError
 	}
 
-	logger.Info("Message queued successfully")
-	return c.JSON(http.StatusOK, SendMessageResponse{
-		Message: "Message queued successfully",
-	})
+	return nil
 }
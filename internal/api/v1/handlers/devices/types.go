@@ -5,14 +5,16 @@ import (
 	"os"
 
 	"interface-api/internal/database"
+	"interface-api/pkg/attachment"
 
 	"github.com/gorilla/websocket"
 )
 
 type DeviceHandler struct {
-	rabbitURL *string
-	db        database.Service
-	upgrader  *websocket.Upgrader
+	rabbitURL   *string
+	db          database.Service
+	upgrader    *websocket.Upgrader
+	attachments *attachment.Validator
 }
 
 func NewDeviceHandler(db database.Service) *DeviceHandler {
@@ -22,8 +24,9 @@ func NewDeviceHandler(db database.Service) *DeviceHandler {
 	}
 
 	return &DeviceHandler{
-		db:        db,
-		rabbitURL: &rabbitURL,
+		db:          db,
+		rabbitURL:   &rabbitURL,
+		attachments: attachment.New(),
 	}
 }
 
@@ -42,17 +45,26 @@ func NewDeviceWebsocketHandler(db database.Service) *DeviceHandler {
 	}
 
 	return &DeviceHandler{
-		db:        db,
-		rabbitURL: &rabbitURL,
-		upgrader:  &upgrader,
+		db:          db,
+		rabbitURL:   &rabbitURL,
+		upgrader:    &upgrader,
+		attachments: attachment.New(),
 	}
 }
 
 // CreateDeviceRequest represents the request body for creating a device
 type CreateDeviceRequest struct {
-	Platform string `json:"platform" example:"wa" validate:"required"`
+	Platform    string `json:"platform" example:"wa" validate:"required"`
+	Method      string `json:"method,omitempty" example:"qr" enums:"qr,pairing_code"`
+	PhoneNumber string `json:"phone_number,omitempty" example:"+237123456789"`
 }
 
+// Device linking methods
+const (
+	LinkMethodQR          = "qr"
+	LinkMethodPairingCode = "pairing_code"
+)
+
 // DeleteDeviceRequest represents the request body for deleting a device
 type DeleteDeviceRequest struct {
 	// Get the device ID from ListDevices (GET /api/v1/devices) response
@@ -70,13 +82,16 @@ type SendMessageRequest struct {
 
 // SendMessageResponse represents the response after queuing a message
 type SendMessageResponse struct {
-	Message string `json:"message" example:"Message queued successfully"`
+	Message  string `json:"message" example:"Message queued successfully"`
+	DeviceID string `json:"device_id,omitempty" example:"device_123"`
 }
 
 // DeviceResponse represents the response after device operations
 type DeviceResponse struct {
-	Message   string `json:"message,omitempty" example:"Scan the QR code to link your device"`
-	QrCodeURL string `json:"qr_code_url,omitempty" example:"wss://example.com/api/v1/devices/qr-code?token=mt_xxxxx"`
+	Message        string `json:"message,omitempty" example:"Scan the QR code to link your device"`
+	QrCodeURL      string `json:"qr_code_url,omitempty" example:"wss://example.com/api/v1/devices/qr-code?ticket=wst_xxxxx"`
+	PairingCode    string `json:"pairing_code,omitempty" example:"ABCD-EFGH"`
+	PairingCodeURL string `json:"pairing_code_url,omitempty" example:"wss://example.com/api/v1/devices/pairing-code?ticket=wst_xxxxx"`
 }
 
 // ListDevicesResponse represents the response for listing devices
@@ -84,11 +99,51 @@ type ListDevicesResponse []Device
 
 // Device represents a single device in the list of devices
 type Device struct {
-	Platform string `json:"platform" example:"wa"`
-	DeviceID string `json:"device_id" example:"237123456789"`
+	Platform            string  `json:"platform" example:"wa"`
+	DeviceID            string  `json:"device_id" example:"237123456789"`
+	Label               string  `json:"label" example:"Support phone"`
+	Status              string  `json:"status" example:"active" enums:"active,degraded,disconnected,unlinked"`
+	LinkedAt            string  `json:"linked_at" example:"2026-10-18T09:30:00Z"`
+	LastSendAt          *string `json:"last_send_at" example:"2026-10-18T10:00:00Z"`
+	ConsecutiveFailures int     `json:"consecutive_failures" example:"0"`
+}
+
+// UpdateDeviceRequest represents the request body for updating a device
+type UpdateDeviceRequest struct {
+	// Get the platform from ListDevices (GET /api/v1/devices) response
+	Platform string `json:"platform" example:"wa" validate:"required"`
+	Label    string `json:"label" example:"Support phone"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error" example:"message"`
 }
+
+// QR code WebSocket message types
+const (
+	QRMessageTypeQR          = "qr"
+	QRMessageTypePairingCode = "pairing_code"
+	QRMessageTypePaired      = "paired"
+	QRMessageTypeExpired     = "expired"
+	QRMessageTypeError       = "error"
+)
+
+// QR code WebSocket error codes
+const (
+	QRErrorNoPendingDevice = "no_pending_device"
+	QRErrorMethodMismatch  = "method_mismatch"
+	QRErrorInternal        = "internal_error"
+)
+
+// QRCodeMessage represents a message sent over the QR code WebSocket
+type QRCodeMessage struct {
+	Type      string `json:"type" example:"qr" enums:"qr,pairing_code,paired,expired,error"`
+	Data      string `json:"data,omitempty" example:"2@abc123..."`
+	Image     string `json:"image,omitempty" example:"iVBORw0KGgoAAAANSUhEUgAA..."`
+	ExpiresAt string `json:"expires_at,omitempty" example:"2026-01-01T00:05:00Z"`
+	Platform  string `json:"platform,omitempty" example:"wa"`
+	DeviceID  string `json:"device_id,omitempty" example:"device_123"`
+	Code      string `json:"code,omitempty" example:"no_pending_device"`
+	Message   string `json:"message,omitempty" example:"You have no pending devices to add. Add a device and try again."`
+}
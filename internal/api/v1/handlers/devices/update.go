@@ -0,0 +1,85 @@
+package devices
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Update godoc
+//
+//	@Summary		Update a device
+//	@Description	Update the label of a device in the local device registry
+//	@Tags			devices
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Param			device_id	path		string				true	"Device ID"
+//	@Param			request		body		UpdateDeviceRequest	true	"Device update request"
+//	@Success		200			{object}	Device				"Device updated successfully"
+//	@Failure		400			{object}	ErrorResponse		"Invalid request body or validation error"
+//	@Failure		401			{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		403			{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		404			{object}	ErrorResponse		"Device not found"
+//	@Failure		500			{object}	ErrorResponse		"Internal server error"
+//	@Router			/api/v1/devices/{device_id} [put]
+func (h *DeviceHandler) Update(c echo.Context) error {
+	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+	if !ok {
+		logger.Error("Matrix identity not found in context")
+		return echo.ErrUnauthorized
+	}
+
+	deviceID := c.Param("device_id")
+	if strings.TrimSpace(deviceID) == "" {
+		logger.Info("Device update failed: missing device_id")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "device_id is required",
+		})
+	}
+
+	var req UpdateDeviceRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Info(fmt.Sprintf("Device update failed: invalid request body - %v", err))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body. Must be a JSON object.",
+		})
+	}
+
+	if strings.TrimSpace(req.Platform) == "" {
+		logger.Info("Device update failed: missing platform")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Missing required field: platform",
+		})
+	}
+
+	label := strings.TrimSpace(req.Label)
+	if len(label) > 100 {
+		logger.Info("Device update failed: label too long")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Label cannot exceed 100 characters",
+		})
+	}
+
+	device, err := models.UpdateDeviceLabel(h.db.DB(), matrixIdentity.MatrixUsername, req.Platform, deviceID, label)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Info("Device update failed: device not found")
+			return c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Device not found",
+			})
+		}
+		logger.Error(fmt.Sprintf("Failed to update device: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	logger.Info("Device updated successfully")
+	return c.JSON(http.StatusOK, newDeviceResponse(*device))
+}
@@ -0,0 +1,30 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS godoc
+//
+//	@Summary		Access token signing keys
+//	@Description	Public keys, as a JSON Web Key Set, for verifying access tokens issued by /api/v1/oauth/token. Retired keys stay listed until the tokens they signed have expired.
+//	@Tags			oauth
+//	@Produce		json
+//	@Success		200	{object}	JWKSResponse
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/oauth/jwks [get]
+func (h *OAuthHandler) JWKS(c echo.Context) error {
+	keys, err := h.accessTokens.JWKS()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to load access token keys: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, JWKSResponse{Keys: keys})
+}
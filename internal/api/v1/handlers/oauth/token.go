@@ -0,0 +1,134 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/crypto"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Token godoc
+//
+//	@Summary		Issue an access token
+//	@Description	OAuth 2.0 client credentials grant (RFC 6749 section 4.4). Exchange a credential's client_id and client_secret, sent with Basic auth or as form fields, for a short-lived signed access token (JWT) to use as "Authorization: Bearer <token>" on credential routes. The token carries the credential's scopes, or the space separated subset requested in scope. Verify tokens with the keys from /api/v1/oauth/jwks.
+//	@Tags			oauth
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Param			grant_type		formData	string	true	"Must be client_credentials"
+//	@Param			scope			formData	string	false	"Space separated scopes to limit the token to"
+//	@Param			client_id		formData	string	false	"Client ID, when not using Basic auth"
+//	@Param			client_secret	formData	string	false	"Client secret, when not using Basic auth"
+//	@Success		200				{object}	TokenResponse
+//	@Failure		400				{object}	ErrorResponse	"Invalid request, grant type or scope"
+//	@Failure		401				{object}	ErrorResponse	"Invalid client credentials"
+//	@Failure		500				{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/oauth/token [post]
+func (h *OAuthHandler) Token(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	if grantType := c.FormValue("grant_type"); grantType != "client_credentials" {
+		logger.Info(fmt.Sprintf("Access token request failed: unsupported grant type '%s'", grantType))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:            "unsupported_grant_type",
+			ErrorDescription: "grant_type must be client_credentials",
+		})
+	}
+
+	clientID, clientSecret, basic := c.Request().BasicAuth()
+	if !basic {
+		clientID = c.FormValue("client_id")
+		clientSecret = c.FormValue("client_secret")
+	}
+	if clientID == "" || clientSecret == "" {
+		return invalidClient(c, basic)
+	}
+
+	credential, err := models.FindCredentialByClientID(h.db.DB(), clientID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Error("Invalid client credentials")
+			return invalidClient(c, basic)
+		}
+		logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	match, needsRehash, err := crypto.VerifySecret(clientSecret, credential.ClientSecret)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to verify client secret: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	if !match {
+		logger.Error("Invalid client credentials")
+		return invalidClient(c, basic)
+	}
+
+	if needsRehash {
+		if err := credential.UpgradeSecretHash(h.db.DB(), clientSecret); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to upgrade client secret hash: %v", err))
+		}
+	}
+
+	if err := credential.CheckUsable(); err != nil {
+		logger.Warn(fmt.Sprintf("Rejected credential '%s': %v", credential.ClientID, err))
+		return invalidClient(c, basic)
+	}
+
+	if ip := c.RealIP(); !credential.AllowedIPs.Allows(ip) {
+		logger.Warn(fmt.Sprintf("Rejected access token request for '%s' from disallowed IP %s", credential.ClientID, ip))
+		return invalidClient(c, basic)
+	}
+
+	scopes := []string(credential.Scopes)
+	if requested := strings.Fields(c.FormValue("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !credential.HasScope(scope) {
+				logger.Info(fmt.Sprintf("Access token request failed: scope '%s' not granted to '%s'", scope, credential.ClientID))
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:            "invalid_scope",
+					ErrorDescription: fmt.Sprintf("scope '%s' is not granted to this client", scope),
+				})
+			}
+		}
+		scopes = requested
+	}
+
+	token, err := h.accessTokens.Issue(credential, scopes)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to issue access token: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	if err := credential.UpdateLastUsed(h.db.DB()); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to update credential last used time: %v", err))
+	}
+
+	logger.Info(fmt.Sprintf("Issued access token for '%s'", credential.ClientID))
+	return c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(h.accessTokens.TTL().Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	})
+}
+
+// invalidClient rejects the client as RFC 6749 section 5.2 describes, with a
+// Basic challenge when the client used Basic auth.
+func invalidClient(c echo.Context, basic bool) error {
+	if basic {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	return c.JSON(http.StatusUnauthorized, ErrorResponse{
+		Error:            "invalid_client",
+		ErrorDescription: "client authentication failed",
+	})
+}
@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"interface-api/internal/database"
+	"interface-api/pkg/accesstoken"
+)
+
+type OAuthHandler struct {
+	db           database.Service
+	accessTokens *accesstoken.Service
+}
+
+func NewOAuthHandler(db database.Service) *OAuthHandler {
+	return &OAuthHandler{
+		db:           db,
+		accessTokens: accesstoken.New(db.DB()),
+	}
+}
+
+// TokenResponse follows RFC 6749 section 5.1
+type TokenResponse struct {
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJSUzI1NiIs..."`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int    `json:"expires_in" example:"900"`
+	Scope       string `json:"scope" example:"tokens:write:create tokens:read:list"`
+}
+
+// JWKSResponse is a JSON Web Key Set (RFC 7517)
+type JWKSResponse struct {
+	Keys []accesstoken.JWK `json:"keys"`
+}
+
+// ErrorResponse follows RFC 6749 section 5.2
+type ErrorResponse struct {
+	Error            string `json:"error" example:"invalid_client"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
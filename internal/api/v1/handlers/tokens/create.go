@@ -1,20 +1,17 @@
 package tokens
 
 import (
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
-	"runtime/debug"
+	"strings"
 	"time"
 
 	"interface-api/internal/api/v1/handlers"
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
-	"interface-api/pkg/masclient"
-	"interface-api/pkg/matrixclient"
+	"interface-api/pkg/provisioning"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
@@ -22,7 +19,7 @@ import (
 // Create godoc
 //
 //	@Summary		Create a Matrix token
-//	@Description	Create a Matrix identity and get a token for Matrix operations. Use use_host=true to reuse admin credentials, or false to create new credentials.
+//	@Description	Create a Matrix identity and get a token for Matrix operations. Use use_host=true to reuse admin credentials, or false to create new credentials. Name, description and labels help tell tokens apart; the creating credential is recorded as the owner. Restrict the token with scopes (messages:send, devices:read, devices:write, webhooks:read, webhooks:write, or resource wildcards such as devices:*); tokens without scopes get full access ("*"). enable_signing returns a signing_secret for HMAC-signed requests, and allowed_ips limits the addresses the token may be used from.
 //	@Tags			tokens,admin
 //	@Accept			json
 //	@Produce		json
@@ -70,7 +67,55 @@ func (h *TokenHandler) Create(c echo.Context) error {
 		expiresAt = &parsedTime
 	}
 
+	scopes := models.Scopes{"*"}
+	if len(req.Scopes) > 0 {
+		scopes = models.Scopes(req.Scopes)
+		if err := models.ValidateTokenScopes(scopes); err != nil {
+			logger.Info(fmt.Sprintf("Token creation failed: %v", err))
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Invalid scopes: %v", err),
+			})
+		}
+	}
+
+	metadata, errMsg := parseTokenMetadata(&req)
+	if errMsg != "" {
+		logger.Info(fmt.Sprintf("Token creation failed: %s", errMsg))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: errMsg,
+		})
+	}
+
+	if credential, ok := c.Get("credential").(*models.Credential); ok {
+		metadata.CredentialID = &credential.ID
+		metadata.CreatedByClientID = credential.ClientID
+	}
+
+	var signingSecret string
+	if req.EnableSigning {
+		var err error
+		signingSecret, metadata.SigningSecret, err = models.NewSigningSecret()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to generate signing secret: %v", err))
+			return echo.ErrInternalServerError
+		}
+	}
+
+	// Provisioning talks to MAS and the Matrix client, so it runs outside the
+	// transaction and is rolled back by compensation if the token is not issued.
+	var provision *models.TokenProvision
+	if !req.UseHost {
+		var err error
+		provision, err = provisioning.Provision(h.db.DB(), expiresAt)
+		if err != nil {
+			return echo.ErrInternalServerError
+		}
+		metadata.MASUserID = provision.MASUserID
+		metadata.MASSessionID = provision.MASSessionID
+	}
+
 	var matrixToken string
+	var identity *models.MatrixIdentity
 	txErr := h.db.DB().Transaction(func(tx *gorm.DB) error {
 		var username, deviceID string
 		var err error
@@ -91,57 +136,8 @@ func (h *TokenHandler) Create(c echo.Context) error {
 			deviceID = adminIdentity.MatrixDeviceID
 			logger.Info("Using host matrix credentials")
 		} else {
-			masClient, err := masclient.New()
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to initialize MAS client: %v", err))
-				return err
-			}
-
-			matrixClient, err := matrixclient.New()
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to initialize Matrix client: %v", err))
-				return err
-			}
-
-			adminToken, err := masClient.GetAdminToken()
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to get MAS admin token:\n%v\n\n%s", err, debug.Stack()))
-				return err
-			}
-
-			u := uuid.New()
-			username = hex.EncodeToString(u[:])[:16]
-
-			userResp, err := masClient.CreateUser(adminToken, username)
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to create MAS user:\n%v\n\n%s", err, debug.Stack()))
-				return err
-			}
-			logger.Info("Created MAS user")
-
-			u = uuid.New()
-			deviceID = hex.EncodeToString(u[:])[:16]
-			sessionResp, err := masClient.CreatePersonalSession(
-				adminToken, userResp.Data.ID, deviceID, expiresAt,
-			)
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to create MAS personal session:\n%v\n\n%s", err, debug.Stack()))
-				return err
-			}
-			matrixAccessToken := sessionResp.Data.Attributes.AccessToken
-			logger.Info("Created MAS personal session")
-
-			storeReq := &matrixclient.StoreCredentialsRequest{
-				Username:    username,
-				AccessToken: matrixAccessToken,
-				DeviceID:    deviceID,
-			}
-			_, err = matrixClient.StoreCredentials(storeReq)
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to store Matrix credentials:\n%v\n\n%s", err, debug.Stack()))
-				return err
-			}
-			logger.Info("Stored Matrix credentials")
+			username = provision.MatrixUsername
+			deviceID = provision.MatrixDeviceID
 		}
 
 		var count int64
@@ -150,7 +146,7 @@ func (h *TokenHandler) Create(c echo.Context) error {
 		}
 		isAdmin := count == 0
 
-		matrixToken, _, err = models.CreateMatrixIdentity(tx, username, deviceID, isAdmin, expiresAt)
+		matrixToken, identity, err = models.CreateMatrixIdentity(tx, username, deviceID, isAdmin, scopes, expiresAt, metadata)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Failed to create matrix identity: %v", err))
 			return err
@@ -164,6 +160,10 @@ func (h *TokenHandler) Create(c echo.Context) error {
 	})
 
 	if txErr != nil {
+		if provision != nil {
+			provisioning.Compensate(h.db.DB(), provision, txErr)
+		}
+
 		var tErr *handlers.TxError
 		if errors.As(txErr, &tErr) {
 			return c.JSON(tErr.StatusCode, ErrorResponse{
@@ -173,9 +173,52 @@ func (h *TokenHandler) Create(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
+	if provision != nil {
+		provisioning.Complete(h.db.DB(), provision)
+	}
+
 	logger.Info("Matrix token created successfully")
 	return c.JSON(http.StatusCreated, CreateResponse{
-		Message: "Matrix token created successfully",
-		Token:   matrixToken,
+		Message:       "Matrix token created successfully",
+		Token:         matrixToken,
+		SigningSecret: signingSecret,
+		Scopes:        scopes,
+		Details:       newTokenResponse(identity),
 	})
 }
+
+// parseTokenMetadata validates the descriptive fields of a create request and
+// returns a client facing message when one is invalid.
+func parseTokenMetadata(req *CreateRequest) (models.TokenMetadata, string) {
+	metadata := models.TokenMetadata{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Labels:      models.Labels(req.Labels),
+	}
+
+	allowedIPs, err := models.ParseIPAllowlist(req.AllowedIPs)
+	if err != nil {
+		return metadata, err.Error()
+	}
+	metadata.AllowedIPs = allowedIPs
+
+	if len(metadata.Name) > maxNameLength {
+		return metadata, fmt.Sprintf("name must be at most %d characters", maxNameLength)
+	}
+	if len(metadata.Description) > maxDescriptionLength {
+		return metadata, fmt.Sprintf("description must be at most %d characters", maxDescriptionLength)
+	}
+	if len(metadata.Labels) > maxLabels {
+		return metadata, fmt.Sprintf("at most %d labels are allowed", maxLabels)
+	}
+	for key, value := range metadata.Labels {
+		if strings.TrimSpace(key) == "" || len(key) > maxLabelKeyLength {
+			return metadata, fmt.Sprintf("label keys must be 1-%d characters", maxLabelKeyLength)
+		}
+		if len(value) > maxLabelValueLength {
+			return metadata, fmt.Sprintf("label values must be at most %d characters", maxLabelValueLength)
+		}
+	}
+
+	return metadata, ""
+}
@@ -6,7 +6,7 @@ import (
 
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
-	"interface-api/pkg/matrixclient"
+	"interface-api/pkg/tokenrevoke"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -15,7 +15,7 @@ import (
 // Delete godoc
 //
 //	@Summary		Delete a Matrix token
-//	@Description	Delete a Matrix token
+//	@Description	Delete a Matrix token. Once no other token uses them, the stored Matrix credentials and the MAS personal session are revoked and the MAS user is deactivated. Teardown that fails is retried by the cleanup worker.
 //	@Tags			tokens,admin
 //	@Accept			json
 //	@Produce		json
@@ -49,41 +49,13 @@ func (h *TokenHandler) Delete(c echo.Context) error {
 		return echo.ErrInternalServerError
 	}
 
-	username := identity.MatrixUsername
-	deviceID := identity.MatrixDeviceID
-
-	var count int64
-	if err := h.db.DB().Model(&models.MatrixIdentity{}).
-		Where("matrix_username = ? AND matrix_device_id = ? AND id != ?", username, deviceID, id).
-		Count(&count).Error; err != nil {
-		logger.Error(fmt.Sprintf("Failed to check for other tokens: %v", err))
-		return echo.ErrInternalServerError
-	}
-
-	shouldDeleteFromMatrix := count == 0
-
-	if err := h.db.DB().Delete(&models.MatrixIdentity{}, id).Error; err != nil {
+	if err := h.db.DB().Delete(&models.MatrixIdentity{}, identity.ID).Error; err != nil {
 		logger.Error(fmt.Sprintf("Failed to delete token: %v", err))
 		return echo.ErrInternalServerError
 	}
 
-	if shouldDeleteFromMatrix {
-		matrixClient, err := matrixclient.New()
-		if err != nil {
-			logger.Warn(fmt.Sprintf("Failed to initialize Matrix client for cleanup: %v", err))
-		} else {
-			deleteReq := &matrixclient.DeleteTokenRequest{
-				Username: username,
-			}
-			_, err = matrixClient.DeleteToken(deleteReq)
-			if err != nil {
-				logger.Warn(fmt.Sprintf("Failed to delete credentials from Matrix client: %v", err))
-			} else {
-				logger.Info("Deleted credentials from Matrix client")
-			}
-		}
-	} else {
-		logger.Debug(fmt.Sprintf("Token deleted from database only - credentials still in use by %d other token(s)", count))
+	if err := tokenrevoke.Revoke(h.db.DB(), &identity); err != nil {
+		logger.Warn(fmt.Sprintf("Token %d deleted but teardown was incomplete and will be retried: %v", identity.ID, err))
 	}
 
 	logger.Info("Token deleted successfully")
@@ -0,0 +1,100 @@
+package tokens
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Self godoc
+//
+//	@Summary		Inspect the current Matrix token
+//	@Description	Return the scopes, username, expiry and last-used time of the bearer token making the request. Checking a token this way does not update its last-used time.
+//	@Tags			tokens
+//	@Produce		json
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Success		200	{object}	IntrospectResponse	"Token details"
+//	@Failure		401	{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		403	{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Router			/api/v1/tokens/self [get]
+func (h *TokenHandler) Self(c echo.Context) error {
+	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+	if !ok {
+		logger.Error("Matrix identity not found in context")
+		return echo.ErrUnauthorized
+	}
+
+	return c.JSON(http.StatusOK, newIntrospectResponse(matrixIdentity))
+}
+
+// Introspect godoc
+//
+//	@Summary		Introspect a Matrix token
+//	@Description	RFC 7662 style token introspection. Returns active=false for unknown or expired tokens. Looking up a token does not update its last-used time.
+//	@Tags			tokens,admin
+//	@Accept			json,x-www-form-urlencoded
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Security		CookieAuth
+//	@Param			request	body		IntrospectRequest	true	"Token to introspect"
+//	@Success		200		{object}	IntrospectResponse	"Introspection result"
+//	@Failure		400		{object}	ErrorResponse		"Invalid request"
+//	@Failure		500		{object}	ErrorResponse		"Internal server error"
+//	@Router			/api/v1/tokens/introspect [post]
+//	@Router			/api/v1/admin/tokens/introspect [post]
+func (h *TokenHandler) Introspect(c echo.Context) error {
+	var req IntrospectRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Info(fmt.Sprintf("Token introspection failed: invalid request body - %v", err))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Token) == "" {
+		logger.Info("Token introspection failed: missing token")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Missing required field: token",
+		})
+	}
+
+	identity, err := models.FindMatrixIdentityByToken(h.db.DB(), req.Token)
+	if err == gorm.ErrRecordNotFound {
+		return c.JSON(http.StatusOK, IntrospectResponse{Active: false})
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to introspect token: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	return c.JSON(http.StatusOK, newIntrospectResponse(identity))
+}
+
+func newIntrospectResponse(identity *models.MatrixIdentity) IntrospectResponse {
+	response := IntrospectResponse{
+		Active:    true,
+		Scope:     strings.Join(identity.Scopes, " "),
+		Scopes:    identity.Scopes,
+		TokenType: "bearer",
+		Username:  identity.MatrixUsername,
+		TokenID:   identity.ID,
+		Name:      identity.Name,
+		Iat:       identity.CreatedAt.Unix(),
+	}
+	if identity.ExpiresAt != nil {
+		exp := identity.ExpiresAt.Unix()
+		response.Exp = &exp
+	}
+	if identity.LastUsedAt != nil {
+		lastUsed := identity.LastUsedAt.Unix()
+		response.LastUsedAt = &lastUsed
+	}
+	return response
+}
@@ -3,6 +3,8 @@ package tokens
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
@@ -13,21 +15,135 @@ import (
 // List godoc
 //
 //	@Summary		List Matrix tokens
-//	@Description	List all Matrix tokens
+//	@Description	List Matrix tokens, newest first. Token hashes are never returned. Filter by owner credential, Matrix username, expiry state and last-used window. Pagination is applied when page or page_size is set; the total number of matches is returned in the X-Total-Count header.
 //	@Tags			tokens,admin
 //	@Accept			json
 //	@Produce		json
 //	@Security		BasicAuth
 //	@Security		CookieAuth
-//	@Success		200	{array}		models.MatrixIdentity	"List of tokens"
-//	@Failure		500	{object}	ErrorResponse			"Internal server error"
+//	@Param			client_id			query		string			false	"Only tokens created by this credential, including after it was deleted"
+//	@Param			username			query		string			false	"Only tokens for this Matrix username"
+//	@Param			expiry				query		string			false	"Expiry state"	Enums(active, expired, never)
+//	@Param			last_used_after		query		string			false	"Only tokens used at or after this time (RFC3339)"
+//	@Param			last_used_before	query		string			false	"Only tokens last used before this time (RFC3339)"
+//	@Param			page				query		int				false	"Page number, starting at 1"
+//	@Param			page_size			query		int				false	"Tokens per page (1-100, default 50)"
+//	@Success		200					{array}		TokenResponse	"List of tokens"
+//	@Failure		400					{object}	ErrorResponse	"Invalid filter"
+//	@Failure		500					{object}	ErrorResponse	"Internal server error"
 //	@Router			/api/v1/tokens [get]
 //	@Router			/api/v1/admin/tokens [get]
 func (h *TokenHandler) List(c echo.Context) error {
-	var tokens []models.MatrixIdentity
-	if err := h.db.DB().Find(&tokens).Error; err != nil {
+	var filter models.MatrixIdentityFilter
+
+	filter.CreatedByClientID = c.QueryParam("client_id")
+	filter.MatrixUsername = c.QueryParam("username")
+
+	switch expiry := c.QueryParam("expiry"); expiry {
+	case "", models.ExpiryStateActive, models.ExpiryStateExpired, models.ExpiryStateNever:
+		filter.ExpiryState = expiry
+	default:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "expiry must be one of: active, expired, never",
+		})
+	}
+
+	var err error
+	if filter.LastUsedAfter, err = parseTimeParam(c, "last_used_after"); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if filter.LastUsedBefore, err = parseTimeParam(c, "last_used_before"); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	pageParam, pageSizeParam := c.QueryParam("page"), c.QueryParam("page_size")
+	if pageParam != "" || pageSizeParam != "" {
+		page, pageSize := 1, defaultPageSize
+		if pageParam != "" {
+			if page, err = strconv.Atoi(pageParam); err != nil || page < 1 {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error: "page must be a positive integer",
+				})
+			}
+		}
+		if pageSizeParam != "" {
+			if pageSize, err = strconv.Atoi(pageSizeParam); err != nil || pageSize < 1 || pageSize > maxPageSize {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error: fmt.Sprintf("page_size must be between 1 and %d", maxPageSize),
+				})
+			}
+		}
+		filter.Limit = pageSize
+		filter.Offset = (page - 1) * pageSize
+	}
+
+	identities, total, err := models.ListMatrixIdentities(h.db.DB(), filter)
+	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to list tokens: %v", err))
 		return echo.ErrInternalServerError
 	}
+
+	tokens := make([]TokenResponse, 0, len(identities))
+	for i := range identities {
+		tokens = append(tokens, newTokenResponse(&identities[i]))
+	}
+
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
 	return c.JSON(http.StatusOK, tokens)
 }
+
+func parseTimeParam(c echo.Context, name string) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s format. Must be RFC3339 (e.g., 2026-12-31T23:59:59Z)", name)
+	}
+	return &parsed, nil
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format("2006-01-02T15:04:05Z07:00")
+	return &formatted
+}
+
+func newTokenResponse(identity *models.MatrixIdentity) TokenResponse {
+	labels := map[string]string(identity.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	scopes := []string(identity.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	allowedIPs := []string(identity.AllowedIPs)
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+
+	return TokenResponse{
+		ID:                identity.ID,
+		Name:              identity.Name,
+		Description:       identity.Description,
+		Labels:            labels,
+		MatrixUsername:    identity.MatrixUsername,
+		MatrixDeviceID:    identity.MatrixDeviceID,
+		IsAdmin:           identity.IsAdmin,
+		Scopes:            scopes,
+		AllowedIPs:        allowedIPs,
+		CredentialID:      identity.CredentialID,
+		CreatedByClientID: identity.CreatedByClientID,
+		ExpiresAt:         formatOptionalTime(identity.ExpiresAt),
+		LastUsedAt:        formatOptionalTime(identity.LastUsedAt),
+		RotatedAt:         formatOptionalTime(identity.RotatedAt),
+		SigningEnabled:    len(identity.SigningSecret) > 0,
+		CreatedAt:         identity.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:         identity.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
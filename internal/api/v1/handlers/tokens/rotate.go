@@ -0,0 +1,120 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Rotate godoc
+//
+//	@Summary		Rotate a Matrix token
+//	@Description	Issue a new token for the same Matrix user and device. The new token keeps the scopes, metadata, allowed IPs, expiry and webhooks of the old one, and gets a new signing secret if the old one had one. The old token stays valid for the grace period (default TOKEN_ROTATION_GRACE_PERIOD_SECONDS) so consumers can switch over, then it is removed by the cleanup worker. A token can be rotated only once; rotate the new token instead.
+//	@Tags			tokens,admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		BasicAuth
+//	@Security		CookieAuth
+//	@Param			id		path		string			true	"Token ID"
+//	@Param			request	body		RotateRequest	false	"Rotation options"
+//	@Success		201		{object}	RotateResponse	"Token rotated successfully"
+//	@Failure		400		{object}	ErrorResponse	"Invalid request"
+//	@Failure		404		{object}	ErrorResponse	"Token not found"
+//	@Failure		409		{object}	ErrorResponse	"Token has already been rotated"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/api/v1/tokens/{id}/rotate [post]
+//	@Router			/api/v1/admin/tokens/{id}/rotate [post]
+func (h *TokenHandler) Rotate(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		logger.Info("Token rotation failed: ID is required")
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "ID is required",
+		})
+	}
+
+	var req RotateRequest
+	if err := c.Bind(&req); err != nil {
+		logger.Info(fmt.Sprintf("Token rotation failed: invalid request body - %v", err))
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request body. Must be a JSON object.",
+		})
+	}
+
+	gracePeriod := h.rotationGracePeriod
+	if req.GracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*req.GracePeriodSeconds) * time.Second
+		if *req.GracePeriodSeconds < 0 || gracePeriod > maxGracePeriod {
+			logger.Info("Token rotation failed: grace period out of range")
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("grace_period_seconds must be between 0 and %d", int(maxGracePeriod.Seconds())),
+			})
+		}
+	}
+
+	var identity models.MatrixIdentity
+	err := h.db.DB().Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC()).First(&identity, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Info("Token rotation failed: token not found")
+			return c.JSON(http.StatusNotFound, ErrorResponse{
+				Error: "Token not found",
+			})
+		}
+		logger.Error(fmt.Sprintf("Failed to find token: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	if identity.RotatedAt != nil {
+		logger.Info(fmt.Sprintf("Token rotation failed: token %d has already been rotated", identity.ID))
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error: "Token has already been rotated",
+		})
+	}
+
+	// A signing secret is rotated along with the token it belongs to
+	var signingSecret string
+	var encryptedSigningSecret []byte
+	if len(identity.SigningSecret) > 0 {
+		signingSecret, encryptedSigningSecret, err = models.NewSigningSecret()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to generate signing secret: %v", err))
+			return echo.ErrInternalServerError
+		}
+	}
+
+	token, rotated, err := models.RotateMatrixIdentity(h.db.DB(), &identity, gracePeriod, encryptedSigningSecret)
+	if err != nil {
+		if errors.Is(err, models.ErrTokenAlreadyRotated) {
+			logger.Info(fmt.Sprintf("Token rotation failed: token %d has already been rotated", identity.ID))
+			return c.JSON(http.StatusConflict, ErrorResponse{
+				Error: "Token has already been rotated",
+			})
+		}
+		logger.Error(fmt.Sprintf("Failed to rotate token: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	var old models.MatrixIdentity
+	if err := h.db.DB().First(&old, identity.ID).Error; err != nil {
+		logger.Error(fmt.Sprintf("Failed to reload rotated token: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	logger.Info("Matrix token rotated successfully")
+	return c.JSON(http.StatusCreated, RotateResponse{
+		Message:           "Matrix token rotated successfully",
+		Token:             token,
+		SigningSecret:     signingSecret,
+		Details:           newTokenResponse(rotated),
+		OldTokenID:        old.ID,
+		OldTokenExpiresAt: old.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
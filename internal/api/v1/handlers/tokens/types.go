@@ -1,23 +1,119 @@
 package tokens
 
-import "interface-api/internal/database"
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"interface-api/internal/database"
+)
 
 type TokenHandler struct {
-	db database.Service
+	db                  database.Service
+	rotationGracePeriod time.Duration
 }
 
 func NewTokenHandler(db database.Service) *TokenHandler {
-	return &TokenHandler{db: db}
+	rotationGracePeriod := 24 * time.Hour
+	if seconds := os.Getenv("TOKEN_ROTATION_GRACE_PERIOD_SECONDS"); seconds != "" {
+		if n, err := strconv.Atoi(seconds); err == nil && n >= 0 {
+			rotationGracePeriod = time.Duration(n) * time.Second
+		}
+	}
+
+	return &TokenHandler{
+		db:                  db,
+		rotationGracePeriod: rotationGracePeriod,
+	}
 }
 
+const (
+	maxNameLength        = 100
+	maxDescriptionLength = 500
+	maxLabels            = 20
+	maxLabelKeyLength    = 63
+	maxLabelValueLength  = 255
+	defaultPageSize      = 50
+	maxPageSize          = 100
+	maxGracePeriod       = 30 * 24 * time.Hour
+)
+
 type CreateRequest struct {
-	UseHost   bool    `json:"use_host" example:"false"`
-	ExpiresAt *string `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+	UseHost     bool              `json:"use_host" example:"false"`
+	ExpiresAt   *string           `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+	Scopes      []string          `json:"scopes,omitempty" example:"messages:send,devices:read"`
+	Name        string            `json:"name,omitempty" example:"billing-service"`
+	Description string            `json:"description,omitempty" example:"Sends invoice reminders"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	// Issue a secret for signing requests instead of sending the bearer token
+	EnableSigning bool `json:"enable_signing,omitempty" example:"false"`
+	// IPs or CIDR ranges the token may be used from; empty allows any
+	AllowedIPs []string `json:"allowed_ips,omitempty" example:"10.0.0.0/8,203.0.113.7"`
 }
 
 type CreateResponse struct {
-	Message string `json:"message"`
-	Token   string `json:"token" example:"mt_xxxxx"`
+	Message       string        `json:"message"`
+	Token         string        `json:"token" example:"mt_xxxxx"`
+	SigningSecret string        `json:"signing_secret,omitempty"`
+	Scopes        []string      `json:"scopes" example:"messages:send,devices:read"`
+	Details       TokenResponse `json:"details"`
+}
+
+// TokenResponse describes a Matrix token without its secret
+type TokenResponse struct {
+	ID             uint              `json:"id" example:"1"`
+	Name           string            `json:"name" example:"billing-service"`
+	Description    string            `json:"description" example:"Sends invoice reminders"`
+	Labels         map[string]string `json:"labels"`
+	MatrixUsername string            `json:"matrix_username" example:"a1b2c3d4e5f6a7b8"`
+	MatrixDeviceID string            `json:"matrix_device_id" example:"a1b2c3d4e5f6a7b8"`
+	IsAdmin        bool              `json:"is_admin" example:"false"`
+	Scopes         []string          `json:"scopes" example:"messages:send"`
+	AllowedIPs     []string          `json:"allowed_ips" example:"10.0.0.0/8"`
+	CredentialID   *uint             `json:"credential_id" example:"1"`
+	// client_id of the credential that created the token, kept after the
+	// credential is deleted
+	CreatedByClientID string  `json:"created_by_client_id" example:"my-app"`
+	ExpiresAt         *string `json:"expires_at" example:"2026-12-31T23:59:59Z"`
+	LastUsedAt        *string `json:"last_used_at" example:"2026-10-18T09:30:00Z"`
+	// Set once the token has been replaced by a rotation
+	RotatedAt      *string `json:"rotated_at,omitempty" example:"2026-10-18T09:30:00Z"`
+	SigningEnabled bool    `json:"signing_enabled" example:"false"`
+	CreatedAt      string  `json:"created_at" example:"2026-10-18T09:30:00Z"`
+	UpdatedAt      string  `json:"updated_at" example:"2026-10-18T09:30:00Z"`
+}
+
+type RotateRequest struct {
+	GracePeriodSeconds *int `json:"grace_period_seconds,omitempty" example:"86400"`
+}
+
+type RotateResponse struct {
+	Message           string        `json:"message"`
+	Token             string        `json:"token" example:"mt_xxxxx"`
+	SigningSecret     string        `json:"signing_secret,omitempty"`
+	Details           TokenResponse `json:"details"`
+	OldTokenID        uint          `json:"old_token_id" example:"1"`
+	OldTokenExpiresAt string        `json:"old_token_expires_at" example:"2026-10-19T09:30:00Z"`
+}
+
+// IntrospectRequest follows RFC 7662 and is accepted as a form or JSON body
+type IntrospectRequest struct {
+	Token         string `json:"token" form:"token" example:"mt_xxxxx"`
+	TokenTypeHint string `json:"token_type_hint,omitempty" form:"token_type_hint" example:"access_token"`
+}
+
+// IntrospectResponse follows RFC 7662. Inactive tokens only carry active=false.
+type IntrospectResponse struct {
+	Active     bool     `json:"active" example:"true"`
+	Scope      string   `json:"scope,omitempty" example:"messages:send devices:read"`
+	Scopes     []string `json:"scopes,omitempty" example:"messages:send,devices:read"`
+	TokenType  string   `json:"token_type,omitempty" example:"bearer"`
+	Username   string   `json:"username,omitempty" example:"a1b2c3d4e5f6a7b8"`
+	TokenID    uint     `json:"token_id,omitempty" example:"1"`
+	Name       string   `json:"name,omitempty" example:"billing-service"`
+	Exp        *int64   `json:"exp,omitempty" example:"1798761599"`
+	Iat        int64    `json:"iat,omitempty" example:"1792315800"`
+	LastUsedAt *int64   `json:"last_used_at,omitempty" example:"1792315800"`
 }
 
 type DeleteResponse struct {
@@ -0,0 +1,45 @@
+package wstickets
+
+import (
+	"fmt"
+	"net/http"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Create godoc
+//
+//	@Summary		Create a WebSocket ticket
+//	@Description	Issue a short-lived, single-use ticket for authenticating WebSocket connections (e.g., wss://api/v1/devices/qr-code?ticket=wst_xxxxx) without putting the matrix token in the URL
+//	@Tags			ws-tickets
+//	@Produce		json
+//	@Param			Authorization	header	string	false	"Matrix token in format: Bearer mt_xxxxx (obtained from /tokens)"
+//	@Security		BearerAuth
+//	@Success		201	{object}	WSTicketResponse	"Ticket created successfully"
+//	@Failure		401	{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		403	{object}	ErrorResponse		"Invalid or expired matrix token"
+//	@Failure		500	{object}	ErrorResponse		"Internal server error"
+//	@Router			/api/v1/ws-tickets [post]
+func (h *WSTicketHandler) Create(c echo.Context) error {
+	matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+	if !ok {
+		logger.Error("Matrix identity not found in context")
+		return echo.ErrUnauthorized
+	}
+
+	ticket, wsTicket, err := models.CreateWSTicket(h.db.DB(), matrixIdentity.ID, h.ttl)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create WebSocket ticket: %v", err))
+		return echo.ErrInternalServerError
+	}
+
+	logger.Debug("WebSocket ticket created")
+	return c.JSON(http.StatusCreated, WSTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: wsTicket.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresIn: int(h.ttl.Seconds()),
+	})
+}
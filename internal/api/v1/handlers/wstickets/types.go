@@ -0,0 +1,30 @@
+package wstickets
+
+import (
+	"time"
+
+	"interface-api/internal/database"
+	"interface-api/internal/database/models"
+)
+
+type WSTicketHandler struct {
+	db  database.Service
+	ttl time.Duration
+}
+
+func NewWSTicketHandler(db database.Service) *WSTicketHandler {
+	return &WSTicketHandler{
+		db:  db,
+		ttl: models.WSTicketTTL(),
+	}
+}
+
+type WSTicketResponse struct {
+	Ticket    string `json:"ticket" example:"wst_xxxxx"`
+	ExpiresAt string `json:"expires_at" example:"2026-01-01T00:01:00Z"`
+	ExpiresIn int    `json:"expires_in" example:"60"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -4,9 +4,12 @@ import (
 	"interface-api/internal/api/v1/handlers/adminsession"
 	"interface-api/internal/api/v1/handlers/credentials"
 	"interface-api/internal/api/v1/handlers/devices"
+	"interface-api/internal/api/v1/handlers/oauth"
 	"interface-api/internal/api/v1/handlers/tokens"
 	"interface-api/internal/api/v1/handlers/webhooks"
+	"interface-api/internal/api/v1/handlers/wstickets"
 	"interface-api/internal/database"
+	"interface-api/internal/database/models"
 	"interface-api/internal/middleware"
 
 	"github.com/labstack/echo/v4"
@@ -18,35 +21,55 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 	webhookHandler := webhooks.NewWebhookHandler(db)
 	adminSessionHandler := adminsession.NewAdminSessionHandler(db)
 	credentialHandler := credentials.NewCredentialHandler(db)
+	wsTicketHandler := wstickets.NewWSTicketHandler(db)
+	oauthHandler := oauth.NewOAuthHandler(db)
 
 	bearerAuth := middleware.NewBearerAuth(db)
 	credentialAuth := middleware.NewCredentialAuth(db)
 	adminAuth := middleware.NewAdminAuth(db)
 
+	// OAuth
+	g.POST("/oauth/token", oauthHandler.Token)
+	g.GET("/oauth/jwks", oauthHandler.JWKS)
+
 	// Credentials
 	g.POST(
 		"/credentials",
 		credentialHandler.Create,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsCreate),
 	)
 	g.GET(
 		"/credentials",
 		credentialHandler.List,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("credentials:read:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsList),
 	)
 	g.PUT(
 		"/credentials/:client_id",
 		credentialHandler.Update,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsUpdate),
 	)
 	g.DELETE(
 		"/credentials/:client_id",
 		credentialHandler.Delete,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsDelete),
+	)
+
+	// Admin login lockouts
+	g.GET(
+		"/login-lockouts",
+		adminSessionHandler.ListLoginLockouts,
+		credentialAuth.Authenticate(),
+		credentialAuth.RequireScope(models.ScopeLoginLockoutsList),
+	)
+	g.DELETE(
+		"/login-lockouts",
+		adminSessionHandler.ClearLoginLockouts,
+		credentialAuth.Authenticate(),
+		credentialAuth.RequireScope(models.ScopeLoginLockoutsClear),
 	)
 
 	// Tokens
@@ -54,37 +77,126 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 		"/tokens",
 		tokenHandler.Create,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("tokens:write:create"),
+		credentialAuth.RequireScope(models.ScopeTokensCreate),
 	)
 	g.GET(
 		"/tokens",
 		tokenHandler.List,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("tokens:read:*"),
+		credentialAuth.RequireScope(models.ScopeTokensList),
+	)
+	g.GET("/tokens/self", tokenHandler.Self, bearerAuth.AuthenticateWithoutUse())
+	g.POST(
+		"/tokens/introspect",
+		tokenHandler.Introspect,
+		credentialAuth.Authenticate(),
+		credentialAuth.RequireScope(models.ScopeTokensIntrospect),
 	)
 	g.DELETE(
 		"/tokens/:id",
 		tokenHandler.Delete,
 		credentialAuth.Authenticate(),
-		credentialAuth.RequireScope("tokens:write:delete"),
+		credentialAuth.RequireScope(models.ScopeTokensDelete),
+	)
+	g.POST(
+		"/tokens/:id/rotate",
+		tokenHandler.Rotate,
+		credentialAuth.Authenticate(),
+		credentialAuth.RequireScope(models.ScopeTokensRotate),
 	)
 
 	// Devices
-	g.POST("/devices", deviceWsHandler.Create, bearerAuth.Authenticate())
-	g.GET("/devices", deviceWsHandler.List, bearerAuth.Authenticate())
-	g.GET("/devices/qr-code", deviceWsHandler.QRCode, bearerAuth.AuthenticateWebSocket())
-	g.DELETE("/devices", deviceWsHandler.Delete, bearerAuth.Authenticate())
+	g.POST(
+		"/devices",
+		deviceWsHandler.Create,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.GET(
+		"/devices",
+		deviceWsHandler.List,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesRead),
+	)
+	g.GET(
+		"/devices/qr-code",
+		deviceWsHandler.QRCode,
+		bearerAuth.AuthenticateWebSocket(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.GET(
+		"/devices/pairing-code",
+		deviceWsHandler.PairingCode,
+		bearerAuth.AuthenticateWebSocket(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.GET(
+		"/devices/qr-code.png",
+		deviceWsHandler.QRCodePNG,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.GET(
+		"/devices/qr-code.svg",
+		deviceWsHandler.QRCodeSVG,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.PUT(
+		"/devices/:device_id",
+		deviceWsHandler.Update,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	g.DELETE(
+		"/devices",
+		deviceWsHandler.Delete,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
 	g.POST(
 		"/devices/:device_id/message",
 		deviceWsHandler.SendMessage,
 		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeMessagesSend),
 	)
 
+	// Messages
+	g.POST(
+		"/messages",
+		deviceWsHandler.DispatchMessage,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeMessagesSend),
+	)
+
+	// WebSocket tickets
+	g.POST("/ws-tickets", wsTicketHandler.Create, bearerAuth.Authenticate())
+
 	// Webhooks
-	g.POST("/webhooks", webhookHandler.Add, bearerAuth.Authenticate())
-	g.GET("/webhooks", webhookHandler.List, bearerAuth.Authenticate())
-	g.PUT("/webhooks/:id", webhookHandler.Update, bearerAuth.Authenticate())
-	g.DELETE("/webhooks/:id", webhookHandler.Delete, bearerAuth.Authenticate())
+	g.POST(
+		"/webhooks",
+		webhookHandler.Add,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
+	)
+	g.GET(
+		"/webhooks",
+		webhookHandler.List,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeWebhooksRead),
+	)
+	g.PUT(
+		"/webhooks/:id",
+		webhookHandler.Update,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
+	)
+	g.DELETE(
+		"/webhooks/:id",
+		webhookHandler.Delete,
+		bearerAuth.Authenticate(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
+	)
 
 	// Admin routes
 	adminGroup := g.Group("/admin")
@@ -106,28 +218,43 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 		credentialHandler.Create,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsCreate),
 	)
 	adminGroup.GET(
 		"/credentials",
 		credentialHandler.List,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("credentials:read:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsList),
 	)
 	adminGroup.PUT(
 		"/credentials/:client_id",
 		credentialHandler.Update,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsUpdate),
 	)
 	adminGroup.DELETE(
 		"/credentials/:client_id",
 		credentialHandler.Delete,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("credentials:write:*"),
+		credentialAuth.RequireScope(models.ScopeCredentialsDelete),
+	)
+
+	adminGroup.GET(
+		"/login-lockouts",
+		adminSessionHandler.ListLoginLockouts,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectCredential(),
+		credentialAuth.RequireScope(models.ScopeLoginLockoutsList),
+	)
+	adminGroup.DELETE(
+		"/login-lockouts",
+		adminSessionHandler.ClearLoginLockouts,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectCredential(),
+		credentialAuth.RequireScope(models.ScopeLoginLockoutsClear),
 	)
 
 	adminGroup.POST(
@@ -135,21 +262,35 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 		tokenHandler.Create,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("tokens:write:create"),
+		credentialAuth.RequireScope(models.ScopeTokensCreate),
 	)
 	adminGroup.GET(
 		"/tokens",
 		tokenHandler.List,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("tokens:read:*"),
+		credentialAuth.RequireScope(models.ScopeTokensList),
+	)
+	adminGroup.POST(
+		"/tokens/introspect",
+		tokenHandler.Introspect,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectCredential(),
+		credentialAuth.RequireScope(models.ScopeTokensIntrospect),
 	)
 	adminGroup.DELETE(
 		"/tokens/:id",
 		tokenHandler.Delete,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectCredential(),
-		credentialAuth.RequireScope("tokens:write:delete"),
+		credentialAuth.RequireScope(models.ScopeTokensDelete),
+	)
+	adminGroup.POST(
+		"/tokens/:id/rotate",
+		tokenHandler.Rotate,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectCredential(),
+		credentialAuth.RequireScope(models.ScopeTokensRotate),
 	)
 
 	adminGroup.POST(
@@ -157,30 +298,76 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 		deviceWsHandler.Create,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
 	)
 	adminGroup.GET(
 		"/devices",
 		deviceWsHandler.List,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesRead),
 	)
 	adminGroup.GET(
 		"/devices/qr-code",
 		deviceWsHandler.QRCode,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	adminGroup.POST(
+		"/ws-tickets",
+		wsTicketHandler.Create,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+	)
+	adminGroup.GET(
+		"/devices/pairing-code",
+		deviceWsHandler.PairingCode,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	adminGroup.GET(
+		"/devices/qr-code.png",
+		deviceWsHandler.QRCodePNG,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	adminGroup.GET(
+		"/devices/qr-code.svg",
+		deviceWsHandler.QRCodeSVG,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
+	)
+	adminGroup.PUT(
+		"/devices/:device_id",
+		deviceWsHandler.Update,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
 	)
 	adminGroup.DELETE(
 		"/devices",
 		deviceWsHandler.Delete,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeDevicesWrite),
 	)
 	adminGroup.POST(
 		"/devices/:device_id/message",
 		deviceWsHandler.SendMessage,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeMessagesSend),
+	)
+	adminGroup.POST(
+		"/messages",
+		deviceWsHandler.DispatchMessage,
+		adminAuth.RequireAuth(),
+		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeMessagesSend),
 	)
 
 	adminGroup.GET(
@@ -188,23 +375,27 @@ func RegisterRoutes(g *echo.Group, db database.Service) {
 		webhookHandler.List,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeWebhooksRead),
 	)
 	adminGroup.POST(
 		"/webhooks",
 		webhookHandler.Add,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
 	)
 	adminGroup.PUT(
 		"/webhooks/:id",
 		webhookHandler.Update,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
 	)
 	adminGroup.DELETE(
 		"/webhooks/:id",
 		webhookHandler.Delete,
 		adminAuth.RequireAuth(),
 		adminAuth.InjectMatrixToken(),
+		bearerAuth.RequireScope(models.ScopeWebhooksWrite),
 	)
 }
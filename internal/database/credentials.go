@@ -1,6 +1,7 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -20,7 +21,7 @@ func InitializeSuperAdminCredentials(db *gorm.DB) error {
 		return nil
 	}
 
-	secretHash, err := crypto.Hash(clientSecret)
+	secretHash, err := crypto.HashSecret(clientSecret)
 	if err != nil {
 		return fmt.Errorf("failed to hash client secret: %w", err)
 	}
@@ -30,6 +31,7 @@ func InitializeSuperAdminCredentials(db *gorm.DB) error {
 		clientID,
 		secretHash,
 		models.RoleSuperAdmin,
+		nil,
 		"System super admin credentials from environment",
 	)
 	if err != nil {
@@ -39,3 +41,32 @@ func InitializeSuperAdminCredentials(db *gorm.DB) error {
 	logger.Info("Super admin credentials initialized")
 	return nil
 }
+
+// LoadCredentialRoles registers the operator-defined roles listed in the JSON
+// file named by CREDENTIAL_ROLES_FILE, a map of role names to the scopes they
+// grant.
+func LoadCredentialRoles() error {
+	path := os.Getenv("CREDENTIAL_ROLES_FILE")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read credential roles: %w", err)
+	}
+
+	var roles map[models.CredentialRole]models.Scopes
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return fmt.Errorf("failed to parse credential roles: %w", err)
+	}
+
+	for role, scopes := range roles {
+		if err := models.RegisterRole(role, scopes); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Registered credential role '%s'", role))
+	}
+
+	return nil
+}
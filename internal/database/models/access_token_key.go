@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccessTokenKey is an RSA key that signs OAuth access tokens. The private key
+// is stored PKCS#8 encoded and encrypted.
+type AccessTokenKey struct {
+	ID         uint      `json:"id"`
+	KeyID      string    `json:"key_id" gorm:"uniqueIndex;not null"`
+	PrivateKey []byte    `json:"-" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (AccessTokenKey) TableName() string {
+	return "access_token_keys"
+}
+
+func CreateAccessTokenKey(db *gorm.DB, keyID string, encryptedKey []byte) (*AccessTokenKey, error) {
+	key := &AccessTokenKey{
+		KeyID:      keyID,
+		PrivateKey: encryptedKey,
+		CreatedAt:  time.Now().UTC(),
+	}
+	err := db.Create(key).Error
+	return key, err
+}
+
+// FindAccessTokenKeys returns the keys created after the given time, newest
+// first.
+func FindAccessTokenKeys(db *gorm.DB, createdAfter time.Time) ([]AccessTokenKey, error) {
+	var keys []AccessTokenKey
+	err := db.Where("created_at > ?", createdAfter).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"interface-api/pkg/crypto"
+
+	"gorm.io/gorm"
+)
+
+// AdminSession is an admin UI login. Only a hash of the session token is
+// stored, and the Matrix token attached to the session is encrypted.
+type AdminSession struct {
+	ID           uint      `json:"id"`
+	TokenHash    []byte    `json:"-" gorm:"uniqueIndex;not null"`
+	CredentialID *uint     `json:"credential_id"`
+	MatrixToken  []byte    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (AdminSession) TableName() string {
+	return "admin_sessions"
+}
+
+// DecryptMatrixToken returns the Matrix token attached to the session, or an
+// empty string if there is none
+func (s *AdminSession) DecryptMatrixToken() (string, error) {
+	if len(s.MatrixToken) == 0 {
+		return "", nil
+	}
+	token, err := crypto.Decrypt(s.MatrixToken)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+func CreateAdminSession(db *gorm.DB, token string, credentialID *uint, expiresAt time.Time) (*AdminSession, error) {
+	hash, err := crypto.Hash(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &AdminSession{
+		TokenHash:    hash,
+		CredentialID: credentialID,
+		ExpiresAt:    expiresAt.UTC(),
+	}
+	err = db.Create(session).Error
+	return session, err
+}
+
+// FindAdminSession returns the unexpired session for a session token
+func FindAdminSession(db *gorm.DB, token string) (*AdminSession, error) {
+	hash, err := crypto.Hash(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var session AdminSession
+	err = db.Where("token_hash = ? AND expires_at > ?", hash, time.Now().UTC()).First(&session).Error
+	return &session, err
+}
+
+// ExtendAdminSession moves the expiry of a session
+func ExtendAdminSession(db *gorm.DB, token string, expiresAt time.Time) error {
+	return updateAdminSession(db, token, map[string]any{"expires_at": expiresAt.UTC()})
+}
+
+// SetAdminSessionMatrixToken encrypts and attaches a Matrix token to a session
+func SetAdminSessionMatrixToken(db *gorm.DB, token, matrixToken string) error {
+	encrypted, err := crypto.Encrypt([]byte(matrixToken))
+	if err != nil {
+		return err
+	}
+	return updateAdminSession(db, token, map[string]any{"matrix_token": encrypted})
+}
+
+func updateAdminSession(db *gorm.DB, token string, updates map[string]any) error {
+	hash, err := crypto.Hash(token)
+	if err != nil {
+		return err
+	}
+
+	result := db.Model(&AdminSession{}).
+		Where("token_hash = ? AND expires_at > ?", hash, time.Now().UTC()).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func DeleteAdminSession(db *gorm.DB, token string) error {
+	hash, err := crypto.Hash(token)
+	if err != nil {
+		return err
+	}
+	return db.Where("token_hash = ?", hash).Delete(&AdminSession{}).Error
+}
+
+func DeleteExpiredAdminSessions(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now().UTC()).Delete(&AdminSession{})
+	return result.RowsAffected, result.Error
+}
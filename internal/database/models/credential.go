@@ -3,10 +3,14 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"interface-api/pkg/crypto"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -14,10 +18,42 @@ import (
 type CredentialRole string
 
 const (
-	RoleSuperAdmin CredentialRole = "super_admin"
-	RoleUser       CredentialRole = "user"
+	RoleSuperAdmin  CredentialRole = "super_admin"
+	RoleUser        CredentialRole = "user"
+	RoleAuditor     CredentialRole = "auditor"
+	RoleTokenIssuer CredentialRole = "token_issuer"
 )
 
+// Scopes checked on the credential and token management routes. A wildcard
+// such as "tokens:*" or "tokens:read:*" may be granted as well.
+const (
+	ScopeCredentialsCreate  = "credentials:write:create"
+	ScopeCredentialsUpdate  = "credentials:write:update"
+	ScopeCredentialsDelete  = "credentials:write:delete"
+	ScopeCredentialsList    = "credentials:read:list"
+	ScopeTokensCreate       = "tokens:write:create"
+	ScopeTokensDelete       = "tokens:write:delete"
+	ScopeTokensRotate       = "tokens:write:rotate"
+	ScopeTokensList         = "tokens:read:list"
+	ScopeTokensIntrospect   = "tokens:read:introspect"
+	ScopeLoginLockoutsList  = "credentials:read:lockouts"
+	ScopeLoginLockoutsClear = "credentials:write:lockouts"
+)
+
+var credentialScopes = []string{
+	ScopeCredentialsCreate,
+	ScopeCredentialsUpdate,
+	ScopeCredentialsDelete,
+	ScopeCredentialsList,
+	ScopeTokensCreate,
+	ScopeTokensDelete,
+	ScopeTokensRotate,
+	ScopeTokensList,
+	ScopeTokensIntrospect,
+	ScopeLoginLockoutsList,
+	ScopeLoginLockoutsClear,
+}
+
 type Scopes []string
 
 func (s Scopes) Value() (driver.Value, error) {
@@ -46,13 +82,72 @@ func (s *Scopes) Scan(value any) error {
 	return json.Unmarshal(bytes, s)
 }
 
+// roleScopes holds the scopes each role grants by default, which are also the
+// most a credential of that role may be given.
 var roleScopes = map[CredentialRole]Scopes{
 	RoleSuperAdmin: {"*"},
 	RoleUser: {
-		"tokens:write:create",
+		ScopeTokensCreate,
 		"devices:*",
 		"webhooks:*",
 	},
+	RoleAuditor: {
+		ScopeCredentialsList,
+		ScopeTokensList,
+		ScopeTokensIntrospect,
+		ScopeLoginLockoutsList,
+	},
+	RoleTokenIssuer: {
+		ScopeTokensCreate,
+		ScopeTokensRotate,
+		ScopeTokensDelete,
+		ScopeTokensList,
+		ScopeTokensIntrospect,
+	},
+}
+
+var builtinRoles = []CredentialRole{RoleSuperAdmin, RoleUser, RoleAuditor, RoleTokenIssuer}
+
+// RegisterRole adds an operator-defined role granting the given scopes. It must
+// be called before the server starts handling requests.
+func RegisterRole(role CredentialRole, scopes Scopes) error {
+	if strings.TrimSpace(string(role)) == "" {
+		return fmt.Errorf("role name is required")
+	}
+	if slices.Contains(builtinRoles, role) {
+		return fmt.Errorf("role '%s' is built in and cannot be redefined", role)
+	}
+	if len(scopes) == 0 {
+		return fmt.Errorf("role '%s' must grant at least one scope", role)
+	}
+	for _, scope := range scopes {
+		if err := validateCredentialScope(scope); err != nil {
+			return fmt.Errorf("role '%s': %w", role, err)
+		}
+	}
+
+	roleScopes[role] = slices.Clone(scopes)
+	return nil
+}
+
+func IsKnownRole(role CredentialRole) bool {
+	_, ok := roleScopes[role]
+	return ok
+}
+
+// validateCredentialScope checks that a scope is "*", a known credential or
+// token scope, or a wildcard covering at least one of them.
+func validateCredentialScope(scope string) error {
+	if scope == "*" || slices.Contains(credentialScopes, scope) || slices.Contains(tokenScopes, scope) {
+		return nil
+	}
+	if prefix, ok := strings.CutSuffix(scope, "*"); ok && strings.HasSuffix(prefix, ":") {
+		matches := func(s string) bool { return strings.HasPrefix(s, prefix) }
+		if slices.ContainsFunc(credentialScopes, matches) || slices.ContainsFunc(tokenScopes, matches) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown scope '%s'", scope)
 }
 
 func GetDefaultScopesForRole(role CredentialRole) Scopes {
@@ -69,8 +164,14 @@ func ValidateScopesForRole(role CredentialRole, scopes Scopes) error {
 		return nil
 	}
 
-	allowedScopes := roleScopes[role]
+	allowedScopes, ok := roleScopes[role]
+	if !ok {
+		return fmt.Errorf("unknown role '%s'", role)
+	}
 	for _, scope := range scopes {
+		if err := validateCredentialScope(scope); err != nil {
+			return err
+		}
 		if !contains(allowedScopes, scope) {
 			return fmt.Errorf("scope '%s' not allowed for role '%s'", scope, role)
 		}
@@ -94,22 +195,40 @@ func contains(scopes Scopes, scope string) bool {
 }
 
 type Credential struct {
-	ID           uint           `json:"id"`
-	ClientID     string         `json:"client_id" gorm:"uniqueIndex;not null"`
-	ClientSecret []byte         `json:"-" gorm:"not null"`
-	Role         CredentialRole `json:"role" gorm:"not null"`
-	Scopes       Scopes         `json:"scopes" gorm:"type:text"`
-	Description  string         `json:"description"`
-	Active       bool           `json:"active" gorm:"default:true"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
+	ID            uint           `json:"id"`
+	ClientID      string         `json:"client_id" gorm:"uniqueIndex;not null"`
+	ClientSecret  []byte         `json:"-" gorm:"not null"`
+	SigningSecret []byte         `json:"-"`
+	Role          CredentialRole `json:"role" gorm:"not null"`
+	Scopes        Scopes         `json:"scopes" gorm:"type:text"`
+	Description   string         `json:"description"`
+	Active        bool           `json:"active" gorm:"default:true"`
+	AllowedIPs    IPAllowlist    `json:"allowed_ips" gorm:"column:allowed_ips;type:text"`
+	ExpiresAt     *time.Time     `json:"expires_at"`
+	LastUsedAt    *time.Time     `json:"last_used_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+
+	// restrictedScopes narrows the credential to the scopes of the access
+	// token it authenticated with
+	restrictedScopes Scopes
 }
 
 func (Credential) TableName() string {
 	return "credentials"
 }
 
+// RestrictScopes limits the credential, for the current request, to the given
+// scopes on top of its own.
+func (c *Credential) RestrictScopes(scopes Scopes) {
+	c.restrictedScopes = scopes
+}
+
 func (c *Credential) HasScope(scope string) bool {
+	if c.restrictedScopes != nil && !contains(c.restrictedScopes, scope) {
+		return false
+	}
+
 	if c.Role == RoleSuperAdmin {
 		return true
 	}
@@ -128,19 +247,73 @@ func (c *Credential) HasScope(scope string) bool {
 	return false
 }
 
+var (
+	ErrCredentialInactive = errors.New("credential is inactive")
+	ErrCredentialExpired  = errors.New("credential has expired")
+)
+
+// CheckUsable reports why a credential may not authenticate, if it may not
+func (c *Credential) CheckUsable() error {
+	if !c.Active {
+		return ErrCredentialInactive
+	}
+	if c.ExpiresAt != nil && !c.ExpiresAt.After(time.Now().UTC()) {
+		return ErrCredentialExpired
+	}
+	return nil
+}
+
+// UpgradeSecretHash re-hashes a verified client secret with Argon2id and the
+// current parameters, replacing a legacy or outdated hash.
+func (c *Credential) UpgradeSecretHash(db *gorm.DB, secret string) error {
+	hash, err := crypto.HashSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(&Credential{}).Where("id = ?", c.ID).UpdateColumn("client_secret", hash).Error; err != nil {
+		return err
+	}
+	c.ClientSecret = hash
+	return nil
+}
+
+// SigningKey returns the decrypted request-signing secret
+func (c *Credential) SigningKey() ([]byte, error) {
+	return decryptSigningSecret(c.SigningSecret)
+}
+
+func (c *Credential) UpdateLastUsed(db *gorm.DB) error {
+	now := time.Now().UTC()
+	c.LastUsedAt = &now
+	return db.Model(&Credential{}).Where("id = ?", c.ID).UpdateColumn("last_used_at", now).Error
+}
+
 func FindCredentialByClientID(db *gorm.DB, clientID string) (*Credential, error) {
 	var credential Credential
 	err := db.Where("client_id = ?", clientID).First(&credential).Error
 	return &credential, err
 }
 
-func UpsertCredential(db *gorm.DB, clientID string, secretHash []byte, role CredentialRole, description string) (*Credential, error) {
+func FindCredentialByID(db *gorm.DB, id uint) (*Credential, error) {
+	var credential Credential
+	err := db.First(&credential, id).Error
+	return &credential, err
+}
+
+// UpsertCredential creates or replaces a credential. Nil scopes fall back to
+// the role's defaults.
+func UpsertCredential(db *gorm.DB, clientID string, secretHash []byte, role CredentialRole, scopes Scopes, description string) (*Credential, error) {
+	if scopes == nil {
+		scopes = GetDefaultScopesForRole(role)
+	}
+
 	now := time.Now().UTC()
 	credential := &Credential{
 		ClientID:     clientID,
 		ClientSecret: secretHash,
 		Role:         role,
-		Scopes:       GetDefaultScopesForRole(role),
+		Scopes:       scopes,
 		Description:  description,
 		Active:       true,
 		CreatedAt:    now,
@@ -155,6 +328,32 @@ func UpsertCredential(db *gorm.DB, clientID string, secretHash []byte, role Cred
 	return credential, err
 }
 
+// DeleteCredentialWithTokens deletes a credential along with the given tokens
+// it created and their webhooks and WebSocket tickets. Any other tokens it
+// created are kept with their credential_id cleared, so they do not point at a
+// credential that no longer exists; created_by_client_id still records it.
+func DeleteCredentialWithTokens(db *gorm.DB, credential *Credential, identityIDs []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if len(identityIDs) > 0 {
+			if err := tx.Where("matrix_identity_id IN ?", identityIDs).Delete(&Webhook{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("matrix_identity_id IN ?", identityIDs).Delete(&WSTicket{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&MatrixIdentity{}, identityIDs).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&MatrixIdentity{}).
+			Where("credential_id = ?", credential.ID).
+			Update("credential_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(credential).Error
+	})
+}
+
 func DeactivateCredential(db *gorm.DB, clientID string) error {
 	return db.Model(&Credential{}).
 		Where("client_id = ?", clientID).
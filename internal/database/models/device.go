@@ -0,0 +1,193 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceStatus string
+
+const (
+	DeviceStatusActive       DeviceStatus = "active"
+	DeviceStatusDegraded     DeviceStatus = "degraded"
+	DeviceStatusDisconnected DeviceStatus = "disconnected"
+	DeviceStatusUnlinked     DeviceStatus = "unlinked"
+)
+
+type Device struct {
+	ID                  uint         `json:"id"`
+	MatrixUsername      string       `json:"matrix_username"`
+	Platform            string       `json:"platform"`
+	DeviceID            string       `json:"device_id"`
+	Label               string       `json:"label"`
+	Status              DeviceStatus `json:"status"`
+	LinkedAt            time.Time    `json:"linked_at"`
+	LastSendAt          *time.Time   `json:"last_send_at"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastFailureAt       *time.Time   `json:"last_failure_at"`
+	LastError           string       `json:"last_error"`
+	LastSelectedAt      *time.Time   `json:"last_selected_at"`
+	CreatedAt           time.Time    `json:"created_at"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+func (Device) TableName() string {
+	return "devices"
+}
+
+func FindDevicesByUsername(db *gorm.DB, matrixUsername string) ([]Device, error) {
+	var devices []Device
+	err := db.Where("matrix_username = ?", matrixUsername).Order("linked_at ASC").Find(&devices).Error
+	return devices, err
+}
+
+func FindDevicesByUsernameAndStatus(db *gorm.DB, matrixUsername string, status DeviceStatus) ([]Device, error) {
+	var devices []Device
+	err := db.Where("matrix_username = ? AND status = ?", matrixUsername, status).Order("linked_at ASC").Find(&devices).Error
+	return devices, err
+}
+
+func FindDevice(db *gorm.DB, matrixUsername, platform, deviceID string) (*Device, error) {
+	var device Device
+	err := db.Where("matrix_username = ? AND platform = ? AND device_id = ?", matrixUsername, platform, deviceID).
+		First(&device).Error
+	return &device, err
+}
+
+// UpsertLinkedDevice records a device reported by the Matrix client as linked.
+// Devices seen for the first time, or seen again after being unlinked, get a
+// fresh link timestamp.
+func UpsertLinkedDevice(db *gorm.DB, matrixUsername, platform, deviceID string) (*Device, bool, error) {
+	now := time.Now().UTC()
+
+	device, err := FindDevice(db, matrixUsername, platform, deviceID)
+	if err == gorm.ErrRecordNotFound {
+		device = &Device{
+			MatrixUsername: matrixUsername,
+			Platform:       platform,
+			DeviceID:       deviceID,
+			Status:         DeviceStatusActive,
+			LinkedAt:       now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := db.Create(device).Error; err != nil {
+			return nil, false, err
+		}
+		return device, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if device.Status == DeviceStatusUnlinked {
+		device.Status = DeviceStatusActive
+		device.LinkedAt = now
+		device.ConsecutiveFailures = 0
+		if err := db.Model(device).Updates(map[string]any{
+			"status":               device.Status,
+			"linked_at":            device.LinkedAt,
+			"consecutive_failures": 0,
+			"last_error":           "",
+		}).Error; err != nil {
+			return nil, false, err
+		}
+		return device, true, nil
+	}
+
+	return device, false, nil
+}
+
+func UpdateDeviceStatus(db *gorm.DB, id uint, status DeviceStatus) error {
+	return db.Model(&Device{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func UpdateDeviceLabel(db *gorm.DB, matrixUsername, platform, deviceID, label string) (*Device, error) {
+	device, err := FindDevice(db, matrixUsername, platform, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(device).Update("label", label).Error; err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// RecordDeviceSendSuccess stores the last send time and clears the failure
+// streak, restoring degraded or disconnected devices to active. It returns the
+// status the device had before the update.
+func RecordDeviceSendSuccess(db *gorm.DB, matrixUsername, platform, deviceID string) (DeviceStatus, error) {
+	device, err := FindDevice(db, matrixUsername, platform, deviceID)
+	if err != nil {
+		return "", err
+	}
+	previous := device.Status
+
+	updates := map[string]any{
+		"last_send_at":         time.Now().UTC(),
+		"consecutive_failures": 0,
+		"last_error":           "",
+	}
+	if previous == DeviceStatusDegraded || previous == DeviceStatusDisconnected {
+		updates["status"] = DeviceStatusActive
+	}
+
+	if err := db.Model(device).Updates(updates).Error; err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
+// RecordDeviceSendFailure increments the failure streak of a device and
+// returns the updated record.
+func RecordDeviceSendFailure(db *gorm.DB, matrixUsername, platform, deviceID, lastError string) (*Device, error) {
+	device, err := FindDevice(db, matrixUsername, platform, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(device).Updates(map[string]any{
+		"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+		"last_failure_at":      time.Now().UTC(),
+		"last_error":           lastError,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	err = db.First(device, device.ID).Error
+	return device, err
+}
+
+// FindSelectableDevices returns the active and degraded devices of a user on a
+// platform, active devices first, each group ordered by the given column.
+// Devices in exclude are skipped.
+func FindSelectableDevices(db *gorm.DB, matrixUsername, platform, orderColumn string, exclude []string) ([]Device, error) {
+	var devices []Device
+	query := db.Where("matrix_username = ? AND platform = ? AND status IN ?",
+		matrixUsername, platform, []DeviceStatus{DeviceStatusActive, DeviceStatusDegraded})
+	if len(exclude) > 0 {
+		query = query.Where("device_id NOT IN ?", exclude)
+	}
+	err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE status WHEN ? THEN 0 ELSE 1 END",
+			Vars: []any{DeviceStatusActive},
+		}}).
+		Order(orderColumn + " IS NOT NULL").
+		Order(orderColumn + " ASC").
+		Order("linked_at ASC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func MarkDeviceSelected(db *gorm.DB, id uint) error {
+	return db.Model(&Device{}).Where("id = ?", id).UpdateColumn("last_selected_at", time.Now().UTC()).Error
+}
+
+func DeleteDevice(db *gorm.DB, matrixUsername, platform, deviceID string) error {
+	return db.Where("matrix_username = ? AND platform = ? AND device_id = ?", matrixUsername, platform, deviceID).
+		Delete(&Device{}).Error
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const maxIPAllowlistEntries = 50
+
+// ErrIPNotAllowed is returned when a credential or token is used from an
+// address outside its allowlist
+var ErrIPNotAllowed = errors.New("client IP address is not allowed")
+
+// IPAllowlist holds the CIDR ranges a credential or token may be used from.
+// An empty allowlist allows every address.
+type IPAllowlist []string
+
+func (l IPAllowlist) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "[]", nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *IPAllowlist) Scan(value any) error {
+	if value == nil {
+		*l = IPAllowlist{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		str, ok := value.(string)
+		if !ok {
+			*l = IPAllowlist{}
+			return nil
+		}
+		bytes = []byte(str)
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// ParseIPAllowlist validates CIDR ranges and single addresses and returns them
+// in canonical form, with single addresses as /32 or /128 ranges.
+func ParseIPAllowlist(entries []string) (IPAllowlist, error) {
+	if len(entries) > maxIPAllowlistEntries {
+		return nil, fmt.Errorf("at most %d allowed IP ranges are allowed", maxIPAllowlistEntries)
+	}
+
+	allowlist := make(IPAllowlist, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		var prefix netip.Prefix
+		if strings.Contains(entry, "/") {
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+			}
+			prefix = p.Masked()
+		} else {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", entry)
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		allowlist = append(allowlist, prefix.String())
+	}
+	return allowlist, nil
+}
+
+// Allows reports whether the address is in one of the ranges. An empty
+// allowlist allows everything; an unparsable address is never allowed.
+func (l IPAllowlist) Allows(ip string) bool {
+	if len(l) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, entry := range l {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestParseIPAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    IPAllowlist
+	}{
+		{"single IPv4 address", []string{"203.0.113.7"}, IPAllowlist{"203.0.113.7/32"}},
+		{"single IPv6 address", []string{"2001:db8::1"}, IPAllowlist{"2001:db8::1/128"}},
+		{"IPv4-mapped address", []string{"::ffff:203.0.113.7"}, IPAllowlist{"203.0.113.7/32"}},
+		{"CIDR range is masked", []string{"10.1.2.3/8"}, IPAllowlist{"10.0.0.0/8"}},
+		{"IPv6 range", []string{"2001:db8::/32"}, IPAllowlist{"2001:db8::/32"}},
+		{"whitespace is trimmed", []string{" 192.0.2.1 "}, IPAllowlist{"192.0.2.1/32"}},
+		{"empty list", []string{}, IPAllowlist{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIPAllowlist(tt.entries)
+			if err != nil {
+				t.Fatalf("ParseIPAllowlist(%v) error: %v", tt.entries, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseIPAllowlist(%v) = %v, want %v", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIPAllowlist_Invalid(t *testing.T) {
+	for _, entries := range [][]string{
+		{"not-an-ip"},
+		{"10.0.0.0/33"},
+		{"2001:db8::/129"},
+		{"10.0.0.1", ""},
+	} {
+		if _, err := ParseIPAllowlist(entries); err == nil {
+			t.Errorf("ParseIPAllowlist(%v) should fail", entries)
+		}
+	}
+}
+
+func TestParseIPAllowlist_EntryLimit(t *testing.T) {
+	entries := make([]string, maxIPAllowlistEntries+1)
+	for i := range entries {
+		entries[i] = fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+	}
+
+	if _, err := ParseIPAllowlist(entries[:maxIPAllowlistEntries]); err != nil {
+		t.Errorf("ParseIPAllowlist() with %d entries error: %v", maxIPAllowlistEntries, err)
+	}
+	if _, err := ParseIPAllowlist(entries); err == nil {
+		t.Errorf("ParseIPAllowlist() with %d entries should fail", len(entries))
+	}
+}
+
+func TestIPAllowlist_Allows(t *testing.T) {
+	allowlist, err := ParseIPAllowlist([]string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseIPAllowlist() error: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.20.30.40", true},
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"::ffff:10.1.1.1", true},
+		{"::ffff:203.0.113.8", false},
+		{"2001:db8::42", true},
+		{"2001:db9::1", false},
+		{"192.168.1.1", false},
+		{"", false},
+		{"not-an-ip", false},
+	}
+	for _, tt := range tests {
+		if got := allowlist.Allows(tt.ip); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if !(IPAllowlist{}).Allows("198.51.100.1") {
+		t.Error("An empty allowlist should allow every address")
+	}
+	if (IPAllowlist{"203.0.113.7/32"}).Allows("203.0.113.7/32") {
+		t.Error("A CIDR range is not a client address and should not be allowed")
+	}
+}
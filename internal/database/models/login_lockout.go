@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginLockout holds the failed admin logins recorded against a username or an
+// IP address, so every instance enforces the same lockouts.
+type LoginLockout struct {
+	Kind        string `gorm:"primaryKey"`
+	Value       string `gorm:"primaryKey"`
+	Failures    int
+	Lockouts    int
+	Tokens      float64
+	LastRefill  time.Time
+	LockedUntil time.Time
+	LastFailure time.Time
+	ExpiresAt   time.Time
+}
+
+func (LoginLockout) TableName() string {
+	return "login_lockouts"
+}
+
+// FindLoginLockout returns the lockout of the given kind and value, or
+// gorm.ErrRecordNotFound when there is none or it has expired
+func FindLoginLockout(db *gorm.DB, kind, value string) (*LoginLockout, error) {
+	var lockout LoginLockout
+	err := db.Where("kind = ? AND value = ? AND expires_at > ?", kind, value, time.Now().UTC()).
+		First(&lockout).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// FindLoginLockouts returns the unexpired lockouts of the given kind, sorted
+// by value
+func FindLoginLockouts(db *gorm.DB, kind string) ([]LoginLockout, error) {
+	var lockouts []LoginLockout
+	err := db.Where("kind = ? AND expires_at > ?", kind, time.Now().UTC()).
+		Order("value").
+		Find(&lockouts).Error
+	return lockouts, err
+}
+
+// UpdateLoginLockout applies fn to the lockout of the given kind and value in
+// a transaction. The row is created first so that the write lock is held
+// while it is read, and concurrent failures are not lost.
+func UpdateLoginLockout(db *gorm.DB, kind, value string, fn func(*LoginLockout)) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&LoginLockout{Kind: kind, Value: value}).Error
+		if err != nil {
+			return err
+		}
+
+		var lockout LoginLockout
+		if err := tx.Where("kind = ? AND value = ?", kind, value).First(&lockout).Error; err != nil {
+			return err
+		}
+
+		fn(&lockout)
+		lockout.Kind, lockout.Value = kind, value
+		return tx.Save(&lockout).Error
+	})
+}
+
+func DeleteLoginLockout(db *gorm.DB, kind, value string) (bool, error) {
+	result := db.Where("kind = ? AND value = ?", kind, value).Delete(&LoginLockout{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func DeleteLoginLockouts(db *gorm.DB, kind string) (int64, error) {
+	result := db.Where("kind = ?", kind).Delete(&LoginLockout{})
+	return result.RowsAffected, result.Error
+}
+
+func DeleteExpiredLoginLockouts(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now().UTC()).Delete(&LoginLockout{})
+	return result.RowsAffected, result.Error
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,22 +15,125 @@ import (
 	"gorm.io/gorm"
 )
 
+// Scopes that can be granted to Matrix tokens. A resource wildcard such as
+// "devices:*" or the global "*" may be used as well.
+const (
+	ScopeMessagesSend  = "messages:send"
+	ScopeDevicesRead   = "devices:read"
+	ScopeDevicesWrite  = "devices:write"
+	ScopeWebhooksRead  = "webhooks:read"
+	ScopeWebhooksWrite = "webhooks:write"
+)
+
+var tokenScopes = []string{
+	ScopeMessagesSend,
+	ScopeDevicesRead,
+	ScopeDevicesWrite,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+}
+
+type Labels map[string]string
+
+func (l Labels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "{}", nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *Labels) Scan(value any) error {
+	if value == nil {
+		*l = Labels{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		str, ok := value.(string)
+		if !ok {
+			*l = Labels{}
+			return nil
+		}
+		bytes = []byte(str)
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// TokenMetadata describes a Matrix token, the credential that created it and
+// the MAS user and personal session provisioned for it, if any.
+type TokenMetadata struct {
+	Name         string
+	Description  string
+	Labels       Labels
+	CredentialID *uint
+	// CreatedByClientID is the client_id of the creating credential, kept
+	// after that credential is deleted
+	CreatedByClientID string
+	MASUserID         string
+	MASSessionID      string
+	// SigningSecret is the encrypted request-signing secret, if enabled
+	SigningSecret []byte
+	AllowedIPs    IPAllowlist
+}
+
 type MatrixIdentity struct {
-	ID             uint       `json:"id"`
-	MatrixUsername string     `json:"matrix_username"`
-	MatrixDeviceID string     `json:"matrix_device_id"`
-	TokenHash      []byte     `json:"token_hash"`
-	IsAdmin        bool       `json:"is_admin"`
-	ExpiresAt      *time.Time `json:"expires_at"`
-	LastUsedAt     *time.Time `json:"last_used_at"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID                uint        `json:"id"`
+	Name              string      `json:"name"`
+	Description       string      `json:"description"`
+	Labels            Labels      `json:"labels" gorm:"type:text"`
+	MatrixUsername    string      `json:"matrix_username"`
+	MatrixDeviceID    string      `json:"matrix_device_id"`
+	TokenHash         []byte      `json:"-"`
+	SigningSecret     []byte      `json:"-"`
+	IsAdmin           bool        `json:"is_admin"`
+	Scopes            Scopes      `json:"scopes" gorm:"type:text"`
+	AllowedIPs        IPAllowlist `json:"allowed_ips" gorm:"column:allowed_ips;type:text"`
+	CredentialID      *uint       `json:"credential_id"`
+	CreatedByClientID string      `json:"created_by_client_id"`
+	MASUserID         string      `json:"-" gorm:"column:mas_user_id"`
+	MASSessionID      string      `json:"-" gorm:"column:mas_session_id"`
+	ExpiresAt         *time.Time  `json:"expires_at"`
+	LastUsedAt        *time.Time  `json:"last_used_at"`
+	ExpiryNotifiedAt  *time.Time  `json:"-"`
+	RotatedAt         *time.Time  `json:"rotated_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
 }
 
 func (MatrixIdentity) TableName() string {
 	return "matrix_identities"
 }
 
+// SigningKey returns the decrypted request-signing secret
+func (m *MatrixIdentity) SigningKey() ([]byte, error) {
+	return decryptSigningSecret(m.SigningSecret)
+}
+
+func (m *MatrixIdentity) HasScope(scope string) bool {
+	return contains(m.Scopes, scope)
+}
+
+// ValidateTokenScopes checks that every scope is a known token scope or a
+// wildcard over a known resource.
+func ValidateTokenScopes(scopes Scopes) error {
+	for _, scope := range scopes {
+		if scope == "*" || slices.Contains(tokenScopes, scope) {
+			continue
+		}
+		if resource, ok := strings.CutSuffix(scope, ":*"); ok {
+			if slices.ContainsFunc(tokenScopes, func(s string) bool {
+				return strings.HasPrefix(s, resource+":")
+			}) {
+				continue
+			}
+		}
+		return fmt.Errorf("unknown token scope '%s'", scope)
+	}
+	return nil
+}
+
 func (m *MatrixIdentity) UpdateLastUsed(db *gorm.DB) error {
 	now := time.Now().UTC()
 	m.LastUsedAt = &now
@@ -53,7 +161,16 @@ func FindMatrixIdentityByToken(db *gorm.DB, token string) (*MatrixIdentity, erro
 	return &identity, err
 }
 
-func CreateMatrixIdentity(db *gorm.DB, matrixUsername, matrixDeviceID string, isAdmin bool, expiresAt *time.Time) (string, *MatrixIdentity, error) {
+// FindActiveMatrixIdentityByID returns an unexpired identity, for
+// authentication by token ID rather than by the token itself.
+func FindActiveMatrixIdentityByID(db *gorm.DB, id uint) (*MatrixIdentity, error) {
+	var identity MatrixIdentity
+	err := db.Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", id, time.Now().UTC()).
+		First(&identity).Error
+	return &identity, err
+}
+
+func CreateMatrixIdentity(db *gorm.DB, matrixUsername, matrixDeviceID string, isAdmin bool, scopes Scopes, expiresAt *time.Time, metadata TokenMetadata) (string, *MatrixIdentity, error) {
 	tokenPrefix := os.Getenv("MATRIX_TOKEN_PREFIX")
 	if tokenPrefix == "" {
 		tokenPrefix = "mt_"
@@ -72,12 +189,22 @@ func CreateMatrixIdentity(db *gorm.DB, matrixUsername, matrixDeviceID string, is
 	}
 
 	identity := &MatrixIdentity{
-		MatrixUsername: matrixUsername,
-		MatrixDeviceID: matrixDeviceID,
-		TokenHash:      hash,
-		IsAdmin:        isAdmin,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+		Name:              metadata.Name,
+		Description:       metadata.Description,
+		Labels:            metadata.Labels,
+		CredentialID:      metadata.CredentialID,
+		CreatedByClientID: metadata.CreatedByClientID,
+		MASUserID:         metadata.MASUserID,
+		MASSessionID:      metadata.MASSessionID,
+		SigningSecret:     metadata.SigningSecret,
+		AllowedIPs:        metadata.AllowedIPs,
+		MatrixUsername:    matrixUsername,
+		MatrixDeviceID:    matrixDeviceID,
+		TokenHash:         hash,
+		IsAdmin:           isAdmin,
+		Scopes:            scopes,
+		CreatedAt:         now,
+		UpdatedAt:         now,
 	}
 
 	if expiresAt != nil {
@@ -91,8 +218,183 @@ func CreateMatrixIdentity(db *gorm.DB, matrixUsername, matrixDeviceID string, is
 	return tokenPrefix + token, identity, nil
 }
 
+// ErrTokenAlreadyRotated is returned when rotating a token that has already
+// been replaced and is only valid for its grace period
+var ErrTokenAlreadyRotated = errors.New("token has already been rotated")
+
+// RotateMatrixIdentity issues a new token for the same Matrix user and device
+// as the given identity. Webhooks and the admin flag move to the new identity,
+// and the old token is marked rotated and stays valid for the grace period,
+// after which the cleanup worker removes it like any expired token.
+// signingSecret is the encrypted signing secret of the new token, or nil when
+// signing is off. A token can be rotated only once.
+func RotateMatrixIdentity(db *gorm.DB, old *MatrixIdentity, gracePeriod time.Duration, signingSecret []byte) (string, *MatrixIdentity, error) {
+	var token string
+	var identity *MatrixIdentity
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Claim the old token first, so two concurrent rotations cannot both
+		// succeed and a token in its grace period is never rotated again
+		now := time.Now().UTC()
+		graceExpiry := now.Add(gracePeriod)
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(graceExpiry) {
+			graceExpiry = *old.ExpiresAt
+		}
+
+		result := tx.Model(&MatrixIdentity{}).Where("id = ? AND rotated_at IS NULL", old.ID).Updates(map[string]any{
+			"is_admin":   false,
+			"expires_at": graceExpiry,
+			"rotated_at": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTokenAlreadyRotated
+		}
+
+		var err error
+		token, identity, err = CreateMatrixIdentity(
+			tx,
+			old.MatrixUsername,
+			old.MatrixDeviceID,
+			old.IsAdmin,
+			old.Scopes,
+			old.ExpiresAt,
+			TokenMetadata{
+				Name:              old.Name,
+				Description:       old.Description,
+				Labels:            old.Labels,
+				CredentialID:      old.CredentialID,
+				CreatedByClientID: old.CreatedByClientID,
+				MASUserID:         old.MASUserID,
+				MASSessionID:      old.MASSessionID,
+				SigningSecret:     signingSecret,
+				AllowedIPs:        old.AllowedIPs,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&Webhook{}).Where("matrix_identity_id = ?", old.ID).
+			Update("matrix_identity_id", identity.ID).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return token, identity, nil
+}
+
+// FindExpiringMatrixIdentities returns unexpired identities that expire before
+// the given time and have not been warned about yet. Rotated identities are
+// left out, as they expire on purpose at the end of their grace period.
+func FindExpiringMatrixIdentities(db *gorm.DB, before time.Time) ([]MatrixIdentity, error) {
+	var identities []MatrixIdentity
+	err := db.Where("expires_at IS NOT NULL AND expires_at > ? AND expires_at <= ? AND expiry_notified_at IS NULL AND rotated_at IS NULL",
+		time.Now().UTC(), before).
+		Find(&identities).Error
+	return identities, err
+}
+
+func MarkMatrixIdentityExpiryNotified(db *gorm.DB, id uint) error {
+	return db.Model(&MatrixIdentity{}).Where("id = ?", id).Update("expiry_notified_at", time.Now().UTC()).Error
+}
+
+func FindExpiredMatrixIdentities(db *gorm.DB) ([]MatrixIdentity, error) {
+	var identities []MatrixIdentity
+	err := db.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now().UTC()).Find(&identities).Error
+	return identities, err
+}
+
+// CountMatrixIdentitiesSharing returns how many identities other than the given
+// one use the same Matrix user and, when sameDevice is set, the same device.
+// Identities with the excluded IDs are not counted.
+func CountMatrixIdentitiesSharing(db *gorm.DB, identity *MatrixIdentity, sameDevice bool, exclude ...uint) (int64, error) {
+	query := db.Model(&MatrixIdentity{}).
+		Where("matrix_username = ? AND id != ?", identity.MatrixUsername, identity.ID)
+	if sameDevice {
+		query = query.Where("matrix_device_id = ?", identity.MatrixDeviceID)
+	}
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// FindMatrixIdentitiesByCredential returns every token created by the
+// credential, including expired and rotated ones.
+func FindMatrixIdentitiesByCredential(db *gorm.DB, credentialID uint) ([]MatrixIdentity, error) {
+	var identities []MatrixIdentity
+	err := db.Where("credential_id = ?", credentialID).Order("id").Find(&identities).Error
+	return identities, err
+}
+
 func FindAdminMatrixIdentity(db *gorm.DB) (*MatrixIdentity, error) {
 	var identity MatrixIdentity
 	err := db.Where("is_admin = ?", true).First(&identity).Error
 	return &identity, err
 }
+
+// Token expiry states accepted by MatrixIdentityFilter
+const (
+	ExpiryStateActive  = "active"
+	ExpiryStateExpired = "expired"
+	ExpiryStateNever   = "never"
+)
+
+type MatrixIdentityFilter struct {
+	CreatedByClientID string
+	MatrixUsername    string
+	ExpiryState       string
+	LastUsedAfter     *time.Time
+	LastUsedBefore    *time.Time
+	Limit             int
+	Offset            int
+}
+
+// ListMatrixIdentities returns the identities matching the filter, newest
+// first, along with the total number of matches before pagination.
+func ListMatrixIdentities(db *gorm.DB, filter MatrixIdentityFilter) ([]MatrixIdentity, int64, error) {
+	query := db.Model(&MatrixIdentity{})
+
+	if filter.CreatedByClientID != "" {
+		query = query.Where("created_by_client_id = ?", filter.CreatedByClientID)
+	}
+	if filter.MatrixUsername != "" {
+		query = query.Where("matrix_username = ?", filter.MatrixUsername)
+	}
+
+	now := time.Now().UTC()
+	switch filter.ExpiryState {
+	case ExpiryStateActive:
+		query = query.Where("expires_at IS NULL OR expires_at > ?", now)
+	case ExpiryStateExpired:
+		query = query.Where("expires_at IS NOT NULL AND expires_at <= ?", now)
+	case ExpiryStateNever:
+		query = query.Where("expires_at IS NULL")
+	}
+
+	if filter.LastUsedAfter != nil {
+		query = query.Where("last_used_at >= ?", *filter.LastUsedAfter)
+	}
+	if filter.LastUsedBefore != nil {
+		query = query.Where("last_used_at < ?", *filter.LastUsedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+
+	var identities []MatrixIdentity
+	err := query.Order("created_at DESC").Order("id DESC").Find(&identities).Error
+	return identities, total, err
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"interface-api/migrations"
+	"interface-api/pkg/migrator"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("HASH_KEY", "dGVzdC1oYXNoLWtleS10ZXN0LWhhc2gta2V5LTEyMzQ=")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database handle: %v", err)
+	}
+	// Every connection to :memory: is a separate database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := migrator.NewManager(db, migrations.GetAllMigrations()).Up(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestRotateMatrixIdentity_RotateTwice(t *testing.T) {
+	db := newTestDB(t)
+
+	expiresAt := time.Now().UTC().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	_, original, err := CreateMatrixIdentity(db, "alice", "DEVICE", false, Scopes{ScopeMessagesSend}, &expiresAt, TokenMetadata{})
+	if err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+
+	_, rotated, err := RotateMatrixIdentity(db, original, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("First rotation error: %v", err)
+	}
+	if rotated.ExpiresAt == nil || !rotated.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Rotated token expires at %v, want %v", rotated.ExpiresAt, expiresAt)
+	}
+
+	var old MatrixIdentity
+	if err := db.First(&old, original.ID).Error; err != nil {
+		t.Fatalf("Failed to reload old token: %v", err)
+	}
+	if old.RotatedAt == nil {
+		t.Error("Old token should be marked rotated")
+	}
+	if old.ExpiresAt == nil || !old.ExpiresAt.Before(expiresAt) {
+		t.Errorf("Old token expires at %v, want the grace expiry", old.ExpiresAt)
+	}
+
+	if _, _, err := RotateMatrixIdentity(db, &old, time.Hour, nil); !errors.Is(err, ErrTokenAlreadyRotated) {
+		t.Fatalf("Second rotation of the old token error = %v, want ErrTokenAlreadyRotated", err)
+	}
+
+	_, again, err := RotateMatrixIdentity(db, rotated, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("Rotation of the new token error: %v", err)
+	}
+	if again.ExpiresAt == nil || !again.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Token rotated twice expires at %v, want the original %v", again.ExpiresAt, expiresAt)
+	}
+}
+
+func TestFindExpiringMatrixIdentities_SkipsRotated(t *testing.T) {
+	db := newTestDB(t)
+
+	expiresAt := time.Now().UTC().Add(30 * 24 * time.Hour)
+	_, original, err := CreateMatrixIdentity(db, "alice", "DEVICE", false, Scopes{ScopeMessagesSend}, &expiresAt, TokenMetadata{})
+	if err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+	if _, _, err := RotateMatrixIdentity(db, original, time.Hour, nil); err != nil {
+		t.Fatalf("RotateMatrixIdentity() error: %v", err)
+	}
+
+	identities, err := FindExpiringMatrixIdentities(db, time.Now().UTC().Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("FindExpiringMatrixIdentities() error: %v", err)
+	}
+	if len(identities) != 0 {
+		t.Errorf("FindExpiringMatrixIdentities() = %d identities, want the rotated token left out", len(identities))
+	}
+}
+
+func TestDeleteCredentialWithTokens_KeepsCreator(t *testing.T) {
+	db := newTestDB(t)
+
+	credential, err := UpsertCredential(db, "my-app", []byte("hash"), RoleTokenIssuer, nil, "")
+	if err != nil {
+		t.Fatalf("UpsertCredential() error: %v", err)
+	}
+	_, identity, err := CreateMatrixIdentity(db, "alice", "DEVICE", false, Scopes{ScopeMessagesSend}, nil, TokenMetadata{
+		CredentialID:      &credential.ID,
+		CreatedByClientID: credential.ClientID,
+	})
+	if err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+
+	if err := DeleteCredentialWithTokens(db, credential, nil); err != nil {
+		t.Fatalf("DeleteCredentialWithTokens() error: %v", err)
+	}
+
+	identities, total, err := ListMatrixIdentities(db, MatrixIdentityFilter{CreatedByClientID: "my-app"})
+	if err != nil {
+		t.Fatalf("ListMatrixIdentities() error: %v", err)
+	}
+	if total != 1 || identities[0].ID != identity.ID {
+		t.Fatalf("ListMatrixIdentities() = %d token(s), want the kept token", total)
+	}
+	if identities[0].CredentialID != nil {
+		t.Errorf("Kept token credential_id = %d, want it cleared", *identities[0].CredentialID)
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PendingDeviceLink records how the last device addition for a Matrix user
+// was requested, so the pairing streams can tell QR codes from pairing codes
+// on the user's add device queue.
+type PendingDeviceLink struct {
+	MatrixUsername string    `json:"matrix_username" gorm:"primaryKey"`
+	Method         string    `json:"method" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (PendingDeviceLink) TableName() string {
+	return "pending_device_links"
+}
+
+// SetPendingDeviceLink records the method of a device addition, replacing any
+// earlier one for the user
+func SetPendingDeviceLink(db *gorm.DB, matrixUsername, method string) error {
+	link := &PendingDeviceLink{
+		MatrixUsername: matrixUsername,
+		Method:         method,
+		CreatedAt:      time.Now().UTC(),
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "matrix_username"}},
+		DoUpdates: clause.AssignmentColumns([]string{"method", "created_at"}),
+	}).Create(link).Error
+}
+
+func FindPendingDeviceLink(db *gorm.DB, matrixUsername string) (*PendingDeviceLink, error) {
+	var link PendingDeviceLink
+	err := db.Where("matrix_username = ?", matrixUsername).First(&link).Error
+	return &link, err
+}
+
+func DeletePendingDeviceLink(db *gorm.DB, matrixUsername string) error {
+	return db.Where("matrix_username = ?", matrixUsername).Delete(&PendingDeviceLink{}).Error
+}
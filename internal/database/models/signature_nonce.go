@@ -0,0 +1,48 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SignatureNonce is a nonce of a signed request, kept until the request's
+// timestamp is too old to be accepted so the request cannot be replayed
+// against any instance.
+type SignatureNonce struct {
+	NonceHash string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+func (SignatureNonce) TableName() string {
+	return "signature_nonces"
+}
+
+// RecordSignatureNonce stores the nonce signed by the given ID and reports
+// false if it was already used and has not expired
+func RecordSignatureNonce(db *gorm.DB, id, nonce string, expiresAt time.Time) (bool, error) {
+	sum := sha256.Sum256([]byte(id + "\x00" + nonce))
+	hash := hex.EncodeToString(sum[:])
+
+	if err := db.Where("nonce_hash = ? AND expires_at <= ?", hash, time.Now().UTC()).
+		Delete(&SignatureNonce{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&SignatureNonce{
+		NonceHash: hash,
+		ExpiresAt: expiresAt.UTC(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func DeleteExpiredSignatureNonces(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now().UTC()).Delete(&SignatureNonce{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+
+	"interface-api/pkg/crypto"
+)
+
+// ErrSigningDisabled is returned for credentials and tokens without a signing
+// secret
+var ErrSigningDisabled = errors.New("request signing is not enabled")
+
+// NewSigningSecret generates a secret for HMAC request signing. It returns the
+// secret to hand to the client once and its encrypted form for storage.
+func NewSigningSecret() (string, []byte, error) {
+	secret, err := crypto.GenerateSecureToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encrypted, err := crypto.Encrypt([]byte(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, encrypted, nil
+}
+
+func decryptSigningSecret(encrypted []byte) ([]byte, error) {
+	if len(encrypted) == 0 {
+		return nil, ErrSigningDisabled
+	}
+	return crypto.Decrypt(encrypted)
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Provisioning steps, in the order TokenHandler.Create runs them
+const (
+	ProvisionStepMASUser           = "mas_user"
+	ProvisionStepMASSession        = "mas_session"
+	ProvisionStepMatrixCredentials = "matrix_credentials"
+	ProvisionStepIdentity          = "identity"
+)
+
+// Provision statuses. Records are deleted once provisioning completes or its
+// resources have been released, so only in-flight and failed ones remain.
+const (
+	ProvisionStatusPending = "pending"
+	ProvisionStatusFailed  = "failed"
+)
+
+// TokenProvision records the external resources created for a new Matrix
+// token so they can be released if provisioning does not complete.
+type TokenProvision struct {
+	ID             uint      `json:"id"`
+	MatrixUsername string    `json:"matrix_username"`
+	MatrixDeviceID string    `json:"matrix_device_id"`
+	MASUserID      string    `json:"mas_user_id" gorm:"column:mas_user_id"`
+	MASSessionID   string    `json:"mas_session_id" gorm:"column:mas_session_id"`
+	Step           string    `json:"step"`
+	Status         string    `json:"status"`
+	LastError      string    `json:"last_error"`
+	Attempts       int       `json:"attempts"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (TokenProvision) TableName() string {
+	return "token_provisions"
+}
+
+func CreateTokenProvision(db *gorm.DB, matrixUsername, matrixDeviceID string) (*TokenProvision, error) {
+	now := time.Now().UTC()
+	provision := &TokenProvision{
+		MatrixUsername: matrixUsername,
+		MatrixDeviceID: matrixDeviceID,
+		Step:           ProvisionStepMASUser,
+		Status:         ProvisionStatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := db.Create(provision).Error; err != nil {
+		return nil, err
+	}
+	return provision, nil
+}
+
+func SaveTokenProvision(db *gorm.DB, provision *TokenProvision) error {
+	provision.UpdatedAt = time.Now().UTC()
+	return db.Save(provision).Error
+}
+
+func DeleteTokenProvision(db *gorm.DB, id uint) error {
+	return db.Delete(&TokenProvision{}, id).Error
+}
+
+// FindUnreconciledTokenProvisions returns failed provisions and pending ones
+// that have not progressed since staleBefore.
+func FindUnreconciledTokenProvisions(db *gorm.DB, staleBefore time.Time) ([]TokenProvision, error) {
+	var provisions []TokenProvision
+	err := db.Where("status = ? OR (status = ? AND updated_at <= ?)",
+		ProvisionStatusFailed, ProvisionStatusPending, staleBefore).
+		Order("id").
+		Find(&provisions).Error
+	return provisions, err
+}
+
+// MatrixIdentityExistsFor reports whether a token was issued for the Matrix
+// user and device of the provision.
+func MatrixIdentityExistsFor(db *gorm.DB, provision *TokenProvision) (bool, error) {
+	var count int64
+	err := db.Model(&MatrixIdentity{}).
+		Where("matrix_username = ? AND matrix_device_id = ?", provision.MatrixUsername, provision.MatrixDeviceID).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenRevocation records the external resources of a deleted token that could
+// not be released, so the cleanup worker can retry until they are.
+type TokenRevocation struct {
+	ID                uint      `json:"id"`
+	MatrixUsername    string    `json:"matrix_username"`
+	MASUserID         string    `json:"mas_user_id" gorm:"column:mas_user_id"`
+	MASSessionID      string    `json:"mas_session_id" gorm:"column:mas_session_id"`
+	DeleteCredentials bool      `json:"delete_credentials"`
+	DeactivateUser    bool      `json:"deactivate_user"`
+	LastError         string    `json:"last_error"`
+	Attempts          int       `json:"attempts"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (TokenRevocation) TableName() string {
+	return "token_revocations"
+}
+
+func SaveTokenRevocation(db *gorm.DB, revocation *TokenRevocation) error {
+	now := time.Now().UTC()
+	if revocation.CreatedAt.IsZero() {
+		revocation.CreatedAt = now
+	}
+	revocation.UpdatedAt = now
+	return db.Save(revocation).Error
+}
+
+func DeleteTokenRevocation(db *gorm.DB, id uint) error {
+	return db.Delete(&TokenRevocation{}, id).Error
+}
+
+func FindTokenRevocations(db *gorm.DB) ([]TokenRevocation, error) {
+	var revocations []TokenRevocation
+	err := db.Order("id").Find(&revocations).Error
+	return revocations, err
+}
+
+// MatrixUserInUse reports whether any token uses the Matrix user
+func MatrixUserInUse(db *gorm.DB, matrixUsername string) (bool, error) {
+	var count int64
+	err := db.Model(&MatrixIdentity{}).Where("matrix_username = ?", matrixUsername).Count(&count).Error
+	return count > 0, err
+}
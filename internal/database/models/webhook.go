@@ -41,12 +41,32 @@ func FindWebhooksByIdentity(db *gorm.DB, matrixIdentityID uint) ([]Webhook, erro
 	return webhooks, err
 }
 
+func CountWebhooksByIdentity(db *gorm.DB, matrixIdentityID uint) (int64, error) {
+	var count int64
+	err := db.Model(&Webhook{}).Where("matrix_identity_id = ?", matrixIdentityID).Count(&count).Error
+	return count, err
+}
+
 func FindActiveWebhooksByIdentity(db *gorm.DB, matrixIdentityID uint) ([]Webhook, error) {
 	var webhooks []Webhook
 	err := db.Where("matrix_identity_id = ? AND active = ?", matrixIdentityID, true).Find(&webhooks).Error
 	return webhooks, err
 }
 
+// FindActiveWebhookURLsByUsername returns the distinct URLs of the active
+// webhooks on any token of the Matrix user. A URL registered on several tokens,
+// such as a rotated token and its replacement, is returned once.
+func FindActiveWebhookURLsByUsername(db *gorm.DB, matrixUsername string) ([]string, error) {
+	var urls []string
+	err := db.Model(&Webhook{}).
+		Joins("JOIN matrix_identities ON matrix_identities.id = webhooks.matrix_identity_id").
+		Where("matrix_identities.matrix_username = ? AND webhooks.active = ?", matrixUsername, true).
+		Distinct().
+		Order("webhooks.url").
+		Pluck("webhooks.url", &urls).Error
+	return urls, err
+}
+
 func FindAllActiveWebhooks(db *gorm.DB) ([]Webhook, error) {
 	var webhooks []Webhook
 	err := db.Where("active = ?", true).Find(&webhooks).Error
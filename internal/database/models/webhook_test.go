@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestFindActiveWebhookURLsByUsername_Distinct(t *testing.T) {
+	db := newTestDB(t)
+
+	_, first, err := CreateMatrixIdentity(db, "alice", "DEVICE", false, nil, nil, TokenMetadata{})
+	if err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+	_, second, err := CreateMatrixIdentity(db, "alice", "DEVICE", false, nil, nil, TokenMetadata{})
+	if err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+
+	for _, webhook := range []struct {
+		identity uint
+		url      string
+	}{
+		{first.ID, "https://example.com/hook"},
+		{second.ID, "https://example.com/hook"},
+		{second.ID, "https://example.com/other"},
+	} {
+		if _, err := CreateWebhook(db, webhook.identity, webhook.url); err != nil {
+			t.Fatalf("CreateWebhook() error: %v", err)
+		}
+	}
+
+	urls, err := FindActiveWebhookURLsByUsername(db, "alice")
+	if err != nil {
+		t.Fatalf("FindActiveWebhookURLsByUsername() error: %v", err)
+	}
+	if len(urls) != 2 || urls[0] != "https://example.com/hook" || urls[1] != "https://example.com/other" {
+		t.Errorf("FindActiveWebhookURLsByUsername() = %v, want each URL once", urls)
+	}
+}
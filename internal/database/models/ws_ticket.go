@@ -0,0 +1,125 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"interface-api/pkg/crypto"
+
+	"gorm.io/gorm"
+)
+
+type WSTicket struct {
+	ID               uint      `json:"id"`
+	MatrixIdentityID uint      `json:"matrix_identity_id"`
+	TicketHash       []byte    `json:"-"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (WSTicket) TableName() string {
+	return "ws_tickets"
+}
+
+func wsTicketPrefix() string {
+	prefix := os.Getenv("WS_TICKET_PREFIX")
+	if prefix == "" {
+		prefix = "wst_"
+	}
+	return prefix
+}
+
+// WSTicketTTL returns the lifetime of WebSocket tickets, read from
+// WS_TICKET_TTL_SECONDS
+func WSTicketTTL() time.Duration {
+	if seconds := os.Getenv("WS_TICKET_TTL_SECONDS"); seconds != "" {
+		if n, err := strconv.Atoi(seconds); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return 60 * time.Second
+}
+
+// IsWSTicket reports whether the value carries the WebSocket ticket prefix
+func IsWSTicket(value string) bool {
+	return strings.HasPrefix(value, wsTicketPrefix())
+}
+
+func CreateWSTicket(db *gorm.DB, matrixIdentityID uint, ttl time.Duration) (string, *WSTicket, error) {
+	now := time.Now().UTC()
+
+	ticket, err := crypto.GenerateSecureToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hash, err := crypto.Hash(ticket)
+	if err != nil {
+		return "", nil, err
+	}
+
+	wsTicket := &WSTicket{
+		MatrixIdentityID: matrixIdentityID,
+		TicketHash:       hash,
+		ExpiresAt:        now.Add(ttl),
+		CreatedAt:        now,
+	}
+
+	if err := db.Create(wsTicket).Error; err != nil {
+		return "", nil, err
+	}
+
+	return wsTicketPrefix() + ticket, wsTicket, nil
+}
+
+// RedeemWSTicket consumes a ticket and returns the identity it was issued to.
+// A ticket can be redeemed once; expired, unknown or already used tickets
+// return gorm.ErrRecordNotFound. When check is not nil it runs on the identity
+// before the ticket is consumed, and an error from it leaves the ticket
+// unused.
+func RedeemWSTicket(db *gorm.DB, ticket string, check func(*MatrixIdentity) error) (*MatrixIdentity, error) {
+	hash, err := crypto.Hash(strings.TrimPrefix(ticket, wsTicketPrefix()))
+	if err != nil {
+		return nil, err
+	}
+
+	var identity MatrixIdentity
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var wsTicket WSTicket
+		if err := tx.Where("ticket_hash = ? AND expires_at > ?", hash, time.Now().UTC()).
+			First(&wsTicket).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND (expires_at IS NULL OR expires_at > ?)", wsTicket.MatrixIdentityID, time.Now().UTC()).
+			First(&identity).Error; err != nil {
+			return err
+		}
+
+		if check != nil {
+			if err := check(&identity); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Delete(&WSTicket{}, wsTicket.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func DeleteExpiredWSTickets(db *gorm.DB) (int64, error) {
+	result := db.Where("expires_at <= ?", time.Now().UTC()).Delete(&WSTicket{})
+	return result.RowsAffected, result.Error
+}
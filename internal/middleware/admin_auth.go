@@ -5,7 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"sync"
+	"os"
+	"strconv"
 	"time"
 
 	"interface-api/internal/database"
@@ -16,35 +17,36 @@ import (
 	"gorm.io/gorm"
 )
 
+// SessionCookieName is the cookie carrying the admin session token
+const SessionCookieName = "shortmesh_admin_token"
+
 type AdminSession struct {
 	ExpiresAt    time.Time
 	MatrixToken  string
 	CredentialID *uint
+	CreatedAt    time.Time
 }
 
-var (
-	adminSessions     = make(map[string]*AdminSession)
-	adminSessionMutex sync.RWMutex
-)
-
-func init() {
-	go cleanupExpiredAdminSessions()
+// SessionIdleTimeout is how long an admin session lasts without requests,
+// from ADMIN_SESSION_IDLE_TIMEOUT_MINUTES (default 120)
+func SessionIdleTimeout() time.Duration {
+	if minutes := os.Getenv("ADMIN_SESSION_IDLE_TIMEOUT_MINUTES"); minutes != "" {
+		if n, err := strconv.Atoi(minutes); err == nil && n > 0 {
+			return time.Duration(n) * time.Minute
+		}
+	}
+	return 2 * time.Hour
 }
 
-func cleanupExpiredAdminSessions() {
-	ticker := time.NewTicker(15 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		adminSessionMutex.Lock()
-		now := time.Now()
-		for token, session := range adminSessions {
-			if now.After(session.ExpiresAt) {
-				delete(adminSessions, token)
-			}
+// sessionMaxLifetime caps how far activity can extend a session, from
+// ADMIN_SESSION_MAX_LIFETIME_HOURS (default 24)
+func sessionMaxLifetime() time.Duration {
+	if hours := os.Getenv("ADMIN_SESSION_MAX_LIFETIME_HOURS"); hours != "" {
+		if n, err := strconv.Atoi(hours); err == nil && n > 0 {
+			return time.Duration(n) * time.Hour
 		}
-		adminSessionMutex.Unlock()
 	}
+	return 24 * time.Hour
 }
 
 func GenerateSessionToken() (string, error) {
@@ -55,57 +57,64 @@ func GenerateSessionToken() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-func StoreSession(token string, expiration time.Time, credentialID *uint) {
-	adminSessionMutex.Lock()
-	defer adminSessionMutex.Unlock()
-	adminSessions[token] = &AdminSession{
+func StoreSession(token string, expiration time.Time, credentialID *uint) error {
+	return sessionStore.Create(token, &AdminSession{
 		ExpiresAt:    expiration,
-		MatrixToken:  "",
 		CredentialID: credentialID,
-	}
+		CreatedAt:    time.Now(),
+	})
 }
 
-func IsSessionValid(token string) bool {
-	adminSessionMutex.RLock()
-	session, exists := adminSessions[token]
-	adminSessionMutex.RUnlock()
-
-	if !exists {
-		return false
+func ClearSession(token string) {
+	if err := sessionStore.Delete(token); err != nil {
+		logger.Error(fmt.Sprintf("Failed to clear admin session: %v", err))
 	}
+}
 
-	if time.Now().After(session.ExpiresAt) {
-		adminSessionMutex.Lock()
-		delete(adminSessions, token)
-		adminSessionMutex.Unlock()
-		return false
+func GetMatrixToken(sessionToken string) string {
+	session, err := sessionStore.Get(sessionToken)
+	if err != nil {
+		if err != ErrSessionNotFound {
+			logger.Error(fmt.Sprintf("Failed to load admin session: %v", err))
+		}
+		return ""
 	}
-	return true
+	return session.MatrixToken
 }
 
-func ClearSession(token string) {
-	adminSessionMutex.Lock()
-	defer adminSessionMutex.Unlock()
-	delete(adminSessions, token)
+func SetMatrixToken(sessionToken, matrixToken string) error {
+	return sessionStore.SetMatrixToken(sessionToken, matrixToken)
 }
 
-func GetMatrixToken(sessionToken string) string {
-	adminSessionMutex.RLock()
-	defer adminSessionMutex.RUnlock()
-	if session, exists := adminSessions[sessionToken]; exists {
-		return session.MatrixToken
-	}
-	return ""
+// SetSessionCookie sends the session token cookie expiring with the session
+func SetSessionCookie(c echo.Context, token string, expiration time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiration,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
 }
 
-func SetMatrixToken(sessionToken, matrixToken string) error {
-	adminSessionMutex.Lock()
-	defer adminSessionMutex.Unlock()
-	if session, exists := adminSessions[sessionToken]; exists {
-		session.MatrixToken = matrixToken
-		return nil
+// slideSession pushes the session expiry out by the idle timeout, up to the
+// maximum lifetime. It only writes once the expiry would move by more than a
+// minute so busy sessions do not update the store on every request.
+func slideSession(c echo.Context, token string, session *AdminSession) {
+	expiration := time.Now().Add(SessionIdleTimeout())
+	if limit := session.CreatedAt.Add(sessionMaxLifetime()); expiration.After(limit) {
+		expiration = limit
+	}
+	if expiration.Sub(session.ExpiresAt) < time.Minute {
+		return
 	}
-	return fmt.Errorf("session not found")
+
+	if err := sessionStore.Extend(token, expiration); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to extend admin session: %v", err))
+		return
+	}
+	SetSessionCookie(c, token, expiration)
 }
 
 type AdminAuth struct {
@@ -113,17 +122,32 @@ type AdminAuth struct {
 }
 
 func NewAdminAuth(db database.Service) *AdminAuth {
+	initSessionStore(db.DB())
 	return &AdminAuth{db: db.DB()}
 }
 
+// RequireAuth checks the admin session cookie and extends the session on
+// activity.
 func (a *AdminAuth) RequireAuth() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			cookie, err := c.Cookie("shortmesh_admin_token")
-			if err != nil || !IsSessionValid(cookie.Value) {
+			cookie, err := c.Cookie(SessionCookieName)
+			if err != nil {
 				logger.Error("Invalid or missing admin session cookie")
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
 			}
+
+			session, err := sessionStore.Get(cookie.Value)
+			if err != nil {
+				if err != ErrSessionNotFound {
+					logger.Error(fmt.Sprintf("Failed to load admin session: %v", err))
+					return echo.ErrInternalServerError
+				}
+				logger.Error("Invalid or missing admin session cookie")
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
+			}
+
+			slideSession(c, cookie.Value, session)
 			return next(c)
 		}
 	}
@@ -132,7 +156,7 @@ func (a *AdminAuth) RequireAuth() echo.MiddlewareFunc {
 func (a *AdminAuth) InjectMatrixToken() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			cookie, err := c.Cookie("shortmesh_admin_token")
+			cookie, err := c.Cookie(SessionCookieName)
 			if err != nil {
 				logger.Error("Missing admin session cookie")
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
@@ -150,6 +174,11 @@ func (a *AdminAuth) InjectMatrixToken() echo.MiddlewareFunc {
 				return c.JSON(http.StatusForbidden, map[string]string{"error": "Invalid matrix token"})
 			}
 
+			if ip := c.RealIP(); !matrixIdentity.AllowedIPs.Allows(ip) {
+				logger.Warn(fmt.Sprintf("Rejected session matrix token from disallowed IP %s", ip))
+				return c.JSON(http.StatusForbidden, map[string]string{"error": models.ErrIPNotAllowed.Error()})
+			}
+
 			c.Set("matrix_identity", matrixIdentity)
 			c.Request().Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 			return next(c)
@@ -160,17 +189,18 @@ func (a *AdminAuth) InjectMatrixToken() echo.MiddlewareFunc {
 func (a *AdminAuth) InjectCredential() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			cookie, err := c.Cookie("shortmesh_admin_token")
+			cookie, err := c.Cookie(SessionCookieName)
 			if err != nil {
 				logger.Error("Missing admin session cookie")
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
 			}
 
-			adminSessionMutex.RLock()
-			session, exists := adminSessions[cookie.Value]
-			adminSessionMutex.RUnlock()
-
-			if !exists {
+			session, err := sessionStore.Get(cookie.Value)
+			if err != nil {
+				if err != ErrSessionNotFound {
+					logger.Error(fmt.Sprintf("Failed to load admin session: %v", err))
+					return echo.ErrInternalServerError
+				}
 				logger.Error("Admin session not found for token")
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
 			}
@@ -186,6 +216,19 @@ func (a *AdminAuth) InjectCredential() echo.MiddlewareFunc {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
 			}
 
+			if err := credential.CheckUsable(); err != nil {
+				logger.Warn(fmt.Sprintf("Ending admin session for credential '%s': %v", credential.ClientID, err))
+				ClearSession(cookie.Value)
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or missing session. Please log in."})
+			}
+
+			// The allowlist is checked at login too, but a session cookie must
+			// not carry the credential outside it afterwards
+			if ip := c.RealIP(); !credential.AllowedIPs.Allows(ip) {
+				logger.Warn(fmt.Sprintf("Rejected admin session for credential '%s' from disallowed IP %s", credential.ClientID, ip))
+				return c.JSON(http.StatusForbidden, map[string]string{"error": models.ErrIPNotAllowed.Error()})
+			}
+
 			c.Set("credential", &credential)
 			return next(c)
 		}
@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"interface-api/internal/database"
@@ -31,9 +33,38 @@ func NewBearerAuth(db database.Service) *BearerAuthMiddleware {
 	}
 }
 
+// Authenticate accepts a bearer Matrix token, or a request signed with the
+// token's signing secret and identified by its token ID.
 func (m *BearerAuthMiddleware) Authenticate() echo.MiddlewareFunc {
+	return m.authenticate(true)
+}
+
+// AuthenticateWithoutUse is Authenticate for routes that only inspect the
+// token. It leaves the token's last-used time untouched.
+func (m *BearerAuthMiddleware) AuthenticateWithoutUse() echo.MiddlewareFunc {
+	return m.authenticate(false)
+}
+
+func (m *BearerAuthMiddleware) authenticate(recordUse bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if hasSignature(c.Request()) {
+				matrixIdentity, err := m.authenticateSignature(c.Request())
+				if err != nil {
+					return err
+				}
+
+				if err := checkClientIP(c, matrixIdentity.AllowedIPs); err != nil {
+					return err
+				}
+
+				if recordUse {
+					m.recordUse(matrixIdentity)
+				}
+				c.Set("matrix_identity", matrixIdentity)
+				return next(c)
+			}
+
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
 				logger.Error("Missing authorization header")
@@ -69,6 +100,13 @@ func (m *BearerAuthMiddleware) Authenticate() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
 			}
 
+			if err := checkClientIP(c, matrixIdentity.AllowedIPs); err != nil {
+				return err
+			}
+
+			if recordUse {
+				m.recordUse(matrixIdentity)
+			}
 			c.Set("matrix_identity", matrixIdentity)
 			return next(c)
 		}
@@ -78,10 +116,35 @@ func (m *BearerAuthMiddleware) Authenticate() echo.MiddlewareFunc {
 func (m *BearerAuthMiddleware) AuthenticateWebSocket() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if ticket := c.QueryParam("ticket"); ticket != "" {
+				if !models.IsWSTicket(ticket) {
+					logger.Error("Invalid WebSocket ticket format")
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid ticket format")
+				}
+
+				matrixIdentity, err := models.RedeemWSTicket(m.db, ticket, func(identity *models.MatrixIdentity) error {
+					return checkClientIP(c, identity.AllowedIPs)
+				})
+				if err != nil {
+					if httpErr, ok := err.(*echo.HTTPError); ok {
+						return httpErr
+					}
+					if err == gorm.ErrRecordNotFound {
+						logger.Error("Invalid, expired or already used WebSocket ticket")
+						return echo.NewHTTPError(http.StatusForbidden, "invalid or expired ticket")
+					}
+					logger.Error(fmt.Sprintf("Failed to redeem WebSocket ticket:\n%v\n\n%s", err, debug.Stack()))
+					return echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
+				}
+
+				c.Set("matrix_identity", matrixIdentity)
+				return next(c)
+			}
+
 			token := c.QueryParam("token")
 			if token == "" {
-				logger.Error("Missing token query parameter")
-				return echo.NewHTTPError(http.StatusUnauthorized, "missing token query parameter")
+				logger.Error("Missing ticket or token query parameter")
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing ticket or token query parameter")
 			}
 
 			if !strings.HasPrefix(token, m.matrixPrefix) {
@@ -99,21 +162,87 @@ func (m *BearerAuthMiddleware) AuthenticateWebSocket() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
 			}
 
+			if err := checkClientIP(c, matrixIdentity.AllowedIPs); err != nil {
+				return err
+			}
+
+			m.recordUse(matrixIdentity)
+
 			c.Set("matrix_identity", matrixIdentity)
 			return next(c)
 		}
 	}
 }
 
-func (m *BearerAuthMiddleware) validateMatrixToken(token string) (*models.MatrixIdentity, error) {
-	matrixIdentity, err := models.FindMatrixIdentityByToken(m.db, token)
+// RequireScope rejects requests whose matrix token was not granted the scope.
+// It must run after a middleware that sets the matrix identity.
+func (m *BearerAuthMiddleware) RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			matrixIdentity, ok := c.Get("matrix_identity").(*models.MatrixIdentity)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing authentication")
+			}
+
+			if !matrixIdentity.HasScope(scope) {
+				logger.Warn(fmt.Sprintf("Matrix token missing scope: %s", scope))
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient permissions")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// authenticateSignature verifies a request signed with the signing secret of
+// the token whose ID is in the X-ShortMesh-ID header.
+func (m *BearerAuthMiddleware) authenticateSignature(r *http.Request) (*models.MatrixIdentity, error) {
+	id, err := strconv.ParseUint(r.Header.Get(HeaderSignatureID), 10, 64)
 	if err != nil {
-		return nil, err
+		logger.Error("Signed request with invalid token ID")
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
 	}
 
-	if err := matrixIdentity.UpdateLastUsed(m.db); err != nil {
-		logger.Error(fmt.Sprintf("Failed to update last used timestamp: %v", err))
+	matrixIdentity, err := models.FindActiveMatrixIdentityByID(m.db, uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Error("Signed request for unknown or expired matrix token")
+			return nil, echo.NewHTTPError(http.StatusForbidden, "invalid or expired token")
+		}
+		logger.Error(fmt.Sprintf("Failed to authenticate:\n%v\n\n%s", err, debug.Stack()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
+	}
+
+	secret, err := matrixIdentity.SigningKey()
+	if err != nil {
+		if errors.Is(err, models.ErrSigningDisabled) {
+			logger.Error("Signed request for matrix token without signing enabled")
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "request signing is not enabled for this token")
+		}
+		logger.Error(fmt.Sprintf("Failed to load signing secret: %v", err))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
+	}
+
+	if err := verifySignature(m.db, r, secret); err != nil {
+		if errors.Is(err, errSignatureNonceDB) {
+			logger.Error(err.Error())
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "authentication failed")
+		}
+		logger.Error(fmt.Sprintf("Rejected signed request: %v", err))
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 	}
 
 	return matrixIdentity, nil
 }
+
+func (m *BearerAuthMiddleware) validateMatrixToken(token string) (*models.MatrixIdentity, error) {
+	return models.FindMatrixIdentityByToken(m.db, token)
+}
+
+// recordUse updates the token's last-used time. It runs only once the request
+// has passed every check, so rejected requests leave no trace of use.
+func (m *BearerAuthMiddleware) recordUse(matrixIdentity *models.MatrixIdentity) {
+	if err := matrixIdentity.UpdateLastUsed(m.db); err != nil {
+		logger.Error(fmt.Sprintf("Failed to update last used timestamp: %v", err))
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor returns how the client IP is determined. X-Forwarded-For is
+// only believed when the request comes through the proxies listed in
+// TRUSTED_PROXIES (comma separated IPs or CIDR ranges); otherwise, and for
+// everyone else, the peer address is used so clients cannot spoof it.
+func NewIPExtractor() echo.IPExtractor {
+	var options []echo.TrustOption
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Ignoring invalid TRUSTED_PROXIES entry '%s'", entry))
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	if len(options) == 0 {
+		logger.Info("TRUSTED_PROXIES is not set; X-Forwarded-For is ignored and the peer address is used as the client IP")
+		return echo.ExtractIPDirect()
+	}
+
+	// Echo trusts loopback and private ranges by default; only the configured
+	// proxies should be
+	options = append(options,
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	)
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// checkClientIP rejects requests from outside a credential's or token's
+// allowlist
+func checkClientIP(c echo.Context, allowedIPs models.IPAllowlist) error {
+	if ip := c.RealIP(); !allowedIPs.Allows(ip) {
+		logger.Warn(fmt.Sprintf("Rejected request from disallowed IP %s", ip))
+		return echo.NewHTTPError(http.StatusForbidden, models.ErrIPNotAllowed.Error())
+	}
+	return nil
+}
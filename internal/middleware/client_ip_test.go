@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIPExtractor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		want           string
+	}{
+		{"no trusted proxy ignores the header", "", "10.0.0.5:1234", "198.51.100.1", "10.0.0.5"},
+		{"loopback is not trusted by default", "", "127.0.0.1:1234", "198.51.100.1", "127.0.0.1"},
+		{"trusted proxy forwards the client", "10.0.0.5", "10.0.0.5:1234", "198.51.100.1", "198.51.100.1"},
+		{"trusted proxy range", "10.0.0.0/24", "10.0.0.9:1234", "198.51.100.1", "198.51.100.1"},
+		{"untrusted peer cannot spoof", "10.0.0.5", "10.0.0.6:1234", "198.51.100.1", "10.0.0.6"},
+		{"private peer is not trusted implicitly", "10.0.0.5", "192.168.1.1:1234", "198.51.100.1", "192.168.1.1"},
+		{"spoofed entries before the proxy are skipped", "10.0.0.5", "10.0.0.5:1234", "203.0.113.9, 198.51.100.1", "198.51.100.1"},
+		{"trusted proxy without the header", "10.0.0.5", "10.0.0.5:1234", "", "10.0.0.5"},
+		{"invalid entries are ignored", "not-a-proxy, 10.0.0.5", "10.0.0.5:1234", "198.51.100.1", "198.51.100.1"},
+		{"IPv6 proxy", "2001:db8::1", "[2001:db8::1]:1234", "198.51.100.1", "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trustedProxies)
+			extract := NewIPExtractor()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			if got := extract(req); got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
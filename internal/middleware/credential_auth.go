@@ -1,14 +1,15 @@
 package middleware
 
 import (
-	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"interface-api/internal/database"
 	"interface-api/internal/database/models"
+	"interface-api/pkg/accesstoken"
 	"interface-api/pkg/crypto"
 	"interface-api/pkg/logger"
 
@@ -17,60 +18,47 @@ import (
 )
 
 type CredentialAuthMiddleware struct {
-	db *gorm.DB
+	db           *gorm.DB
+	accessTokens *accesstoken.Service
 }
 
 func NewCredentialAuth(db database.Service) *CredentialAuthMiddleware {
 	return &CredentialAuthMiddleware{
-		db: db.DB(),
+		db:           db.DB(),
+		accessTokens: accesstoken.New(db.DB()),
 	}
 }
 
+// Authenticate accepts Basic auth with a client ID and secret, a bearer access
+// token from the OAuth token endpoint, or a request signed with the
+// credential's signing secret.
 func (m *CredentialAuthMiddleware) Authenticate() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
+			var credential *models.Credential
+			var err error
+			if hasSignature(c.Request()) {
+				credential, err = m.authenticateSignature(c.Request())
+			} else if token, ok := bearerToken(c.Request()); ok {
+				credential, err = m.authenticateAccessToken(token)
+			} else {
+				credential, err = m.authenticateBasic(c.Request())
 			}
-
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "basic" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header")
-			}
-
-			decoded, err := base64.StdEncoding.DecodeString(parts[1])
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+				return err
 			}
 
-			credParts := strings.SplitN(string(decoded), ":", 2)
-			if len(credParts) != 2 {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+			if err := credential.CheckUsable(); err != nil {
+				logger.Warn(fmt.Sprintf("Rejected credential '%s': %v", credential.ClientID, err))
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 			}
 
-			clientID := credParts[0]
-			clientSecret := credParts[1]
-
-			credential, err := models.FindCredentialByClientID(m.db, clientID)
-			if err != nil {
-				if err == gorm.ErrRecordNotFound {
-					logger.Error("Invalid client credentials")
-					return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
-				}
-				logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
-				return echo.ErrInternalServerError
+			if err := checkClientIP(c, credential.AllowedIPs); err != nil {
+				return err
 			}
 
-			secretHash, err := crypto.Hash(clientSecret)
-			if err != nil {
-				logger.Error(fmt.Sprintf("Failed to hash client secret: %v", err))
-				return echo.ErrInternalServerError
-			}
-
-			if subtle.ConstantTimeCompare(secretHash, credential.ClientSecret) != 1 {
-				logger.Error("Invalid client credentials")
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+			if err := credential.UpdateLastUsed(m.db); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to update credential last used time: %v", err))
 			}
 
 			c.Set("credential", credential)
@@ -79,6 +67,133 @@ func (m *CredentialAuthMiddleware) Authenticate() echo.MiddlewareFunc {
 	}
 }
 
+func (m *CredentialAuthMiddleware) authenticateBasic(r *http.Request) (*models.Credential, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "basic" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}
+
+	credParts := strings.SplitN(string(decoded), ":", 2)
+	if len(credParts) != 2 {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}
+
+	clientID := credParts[0]
+	clientSecret := credParts[1]
+
+	credential, err := models.FindCredentialByClientID(m.db, clientID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Error("Invalid client credentials")
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+		}
+		logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
+		return nil, echo.ErrInternalServerError
+	}
+
+	match, needsRehash, err := crypto.VerifySecret(clientSecret, credential.ClientSecret)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to verify client secret: %v", err))
+		return nil, echo.ErrInternalServerError
+	}
+
+	if !match {
+		logger.Error("Invalid client credentials")
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+	}
+
+	if needsRehash {
+		if err := credential.UpgradeSecretHash(m.db, clientSecret); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to upgrade client secret hash: %v", err))
+		}
+	}
+
+	return credential, nil
+}
+
+// authenticateSignature verifies a request signed with the signing secret of
+// the credential named by the X-ShortMesh-ID header.
+func (m *CredentialAuthMiddleware) authenticateSignature(r *http.Request) (*models.Credential, error) {
+	credential, err := models.FindCredentialByClientID(m.db, r.Header.Get(HeaderSignatureID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Error("Signed request from unknown client")
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+		}
+		logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
+		return nil, echo.ErrInternalServerError
+	}
+
+	secret, err := credential.SigningKey()
+	if err != nil {
+		if errors.Is(err, models.ErrSigningDisabled) {
+			logger.Error("Signed request from credential without signing enabled")
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "request signing is not enabled for this credential")
+		}
+		logger.Error(fmt.Sprintf("Failed to load signing secret: %v", err))
+		return nil, echo.ErrInternalServerError
+	}
+
+	if err := verifySignature(m.db, r, secret); err != nil {
+		if errors.Is(err, errSignatureNonceDB) {
+			logger.Error(err.Error())
+			return nil, echo.ErrInternalServerError
+		}
+		logger.Error(fmt.Sprintf("Rejected signed request: %v", err))
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	return credential, nil
+}
+
+// authenticateAccessToken verifies an access token and limits the credential
+// it was issued to to the token's scopes.
+func (m *CredentialAuthMiddleware) authenticateAccessToken(token string) (*models.Credential, error) {
+	claims, err := m.accessTokens.Verify(token)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Rejected access token: %v", err))
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired access token")
+	}
+
+	credential, err := models.FindCredentialByID(m.db, claims.CredentialID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Error("Access token for unknown credential")
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired access token")
+		}
+		logger.Error(fmt.Sprintf("Failed to find credential: %v", err))
+		return nil, echo.ErrInternalServerError
+	}
+
+	// Guards against a deleted credential's ID being reused
+	if credential.ClientID != claims.ClientID {
+		logger.Error("Access token for replaced credential")
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired access token")
+	}
+
+	credential.RestrictScopes(claims.Scopes())
+	return credential, nil
+}
+
+// bearerToken returns the token of a Bearer Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
 func (m *CredentialAuthMiddleware) RequireScope(scope string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
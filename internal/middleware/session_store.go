@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound is returned for unknown and expired admin sessions
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps admin sessions by session token. Implementations must be
+// safe for concurrent use and treat expired sessions as not found.
+type SessionStore interface {
+	Create(token string, session *AdminSession) error
+	Get(token string) (*AdminSession, error)
+	Extend(token string, expiresAt time.Time) error
+	SetMatrixToken(token, matrixToken string) error
+	Delete(token string) error
+}
+
+var (
+	sessionStore     SessionStore
+	sessionStoreOnce sync.Once
+)
+
+// SetSessionStore replaces the admin session store. It must be called before
+// the server starts handling requests.
+func SetSessionStore(store SessionStore) {
+	sessionStoreOnce.Do(func() {})
+	sessionStore = store
+}
+
+// initSessionStore picks the store named by ADMIN_SESSION_STORE, "database"
+// (the default) so sessions survive restarts and are shared between replicas,
+// or "memory" for a single instance.
+func initSessionStore(db *gorm.DB) {
+	sessionStoreOnce.Do(func() {
+		switch store := os.Getenv("ADMIN_SESSION_STORE"); store {
+		case "memory":
+			sessionStore = NewMemorySessionStore()
+		case "", "database":
+			sessionStore = NewDBSessionStore(db)
+		default:
+			logger.Error(fmt.Sprintf("Unknown ADMIN_SESSION_STORE '%s', using database", store))
+			sessionStore = NewDBSessionStore(db)
+		}
+	})
+}
+
+// DBSessionStore keeps sessions in the admin_sessions table. Expired rows are
+// removed by the cleanup worker.
+type DBSessionStore struct {
+	db *gorm.DB
+}
+
+func NewDBSessionStore(db *gorm.DB) *DBSessionStore {
+	return &DBSessionStore{db: db}
+}
+
+func (s *DBSessionStore) Create(token string, session *AdminSession) error {
+	_, err := models.CreateAdminSession(s.db, token, session.CredentialID, session.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if session.MatrixToken != "" {
+		return models.SetAdminSessionMatrixToken(s.db, token, session.MatrixToken)
+	}
+	return nil
+}
+
+func (s *DBSessionStore) Get(token string) (*AdminSession, error) {
+	record, err := models.FindAdminSession(s.db, token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	matrixToken, err := record.DecryptMatrixToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session matrix token: %w", err)
+	}
+
+	return &AdminSession{
+		ExpiresAt:    record.ExpiresAt,
+		MatrixToken:  matrixToken,
+		CredentialID: record.CredentialID,
+		CreatedAt:    record.CreatedAt,
+	}, nil
+}
+
+func (s *DBSessionStore) Extend(token string, expiresAt time.Time) error {
+	return dbSessionError(models.ExtendAdminSession(s.db, token, expiresAt))
+}
+
+func (s *DBSessionStore) SetMatrixToken(token, matrixToken string) error {
+	return dbSessionError(models.SetAdminSessionMatrixToken(s.db, token, matrixToken))
+}
+
+func (s *DBSessionStore) Delete(token string) error {
+	return models.DeleteAdminSession(s.db, token)
+}
+
+func dbSessionError(err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// MemorySessionStore keeps sessions in process memory. Sessions are lost on
+// restart and are not shared between replicas.
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*AdminSession
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	s := &MemorySessionStore{sessions: make(map[string]*AdminSession)}
+	go s.cleanupExpired()
+	return s
+}
+
+func (s *MemorySessionStore) cleanupExpired() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for token, session := range s.sessions {
+			if now.After(session.ExpiresAt) {
+				delete(s.sessions, token)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *MemorySessionStore) Create(token string, session *AdminSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *session
+	s.sessions[token] = &stored
+	return nil
+}
+
+func (s *MemorySessionStore) Get(token string) (*AdminSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, exists := s.sessions[token]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (s *MemorySessionStore) Extend(token string, expiresAt time.Time) error {
+	return s.update(token, func(session *AdminSession) { session.ExpiresAt = expiresAt })
+}
+
+func (s *MemorySessionStore) SetMatrixToken(token, matrixToken string) error {
+	return s.update(token, func(session *AdminSession) { session.MatrixToken = matrixToken })
+}
+
+func (s *MemorySessionStore) update(token string, apply func(*AdminSession)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, exists := s.sessions[token]
+	if !exists || time.Now().After(session.ExpiresAt) {
+		return ErrSessionNotFound
+	}
+	apply(session)
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"interface-api/docs"
 	v1 "interface-api/internal/api/v1"
+	apimiddleware "interface-api/internal/middleware"
 	"interface-api/pkg/adminweb"
 
 	"github.com/labstack/echo/v4"
@@ -16,6 +17,7 @@ import (
 
 func (s *Server) RegisterRoutes() http.Handler {
 	e := echo.New()
+	e.IPExtractor = apimiddleware.NewIPExtractor()
 
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
 		code := http.StatusInternalServerError
@@ -10,5 +10,25 @@ func GetAllMigrations() []migrator.Script {
 		versions.Migration20260212_000003{},
 		versions.Migration20260417_000001{},
 		versions.Migration20260423_000001{},
+		versions.Migration20261018_000001{},
+		versions.Migration20261018_000002{},
+		versions.Migration20261018_000003{},
+		versions.Migration20261018_000004{},
+		versions.Migration20261018_000005{},
+		versions.Migration20261018_000006{},
+		versions.Migration20261018_000007{},
+		versions.Migration20261018_000008{},
+		versions.Migration20261018_000009{},
+		versions.Migration20261018_000010{},
+		versions.Migration20261018_000011{},
+		versions.Migration20261018_000012{},
+		versions.Migration20261018_000013{},
+		versions.Migration20261018_000014{},
+		versions.Migration20261018_000015{},
+		versions.Migration20261018_000016{},
+		versions.Migration20261018_000017{},
+		versions.Migration20261018_000018{},
+		versions.Migration20261018_000019{},
+		versions.Migration20261018_000020{},
 	}
 }
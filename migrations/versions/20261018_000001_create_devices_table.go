@@ -0,0 +1,38 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000001 struct{}
+
+func (m Migration20261018_000001) Version() string {
+	return "20261018_000001"
+}
+
+func (m Migration20261018_000001) Name() string {
+	return "create_devices_table"
+}
+
+func (m Migration20261018_000001) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS devices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			matrix_username TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			device_id TEXT NOT NULL,
+			label TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'active',
+			linked_at DATETIME NOT NULL,
+			last_send_at DATETIME,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+		CREATE UNIQUE INDEX idx_devices_username_platform_device ON devices(matrix_username, platform, device_id);
+		CREATE INDEX idx_devices_status ON devices(status);
+	`).Error
+}
+
+func (m Migration20261018_000001) Down(db *gorm.DB) error {
+	return db.Exec("DROP TABLE IF EXISTS devices").Error
+}
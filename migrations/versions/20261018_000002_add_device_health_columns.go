@@ -0,0 +1,31 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000002 struct{}
+
+func (m Migration20261018_000002) Version() string {
+	return "20261018_000002"
+}
+
+func (m Migration20261018_000002) Name() string {
+	return "add_device_health_columns"
+}
+
+func (m Migration20261018_000002) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE devices ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE devices ADD COLUMN last_failure_at DATETIME;
+		ALTER TABLE devices ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+	`).Error
+}
+
+func (m Migration20261018_000002) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE devices DROP COLUMN consecutive_failures;
+		ALTER TABLE devices DROP COLUMN last_failure_at;
+		ALTER TABLE devices DROP COLUMN last_error;
+	`).Error
+}
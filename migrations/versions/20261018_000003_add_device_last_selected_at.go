@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000003 struct{}
+
+func (m Migration20261018_000003) Version() string {
+	return "20261018_000003"
+}
+
+func (m Migration20261018_000003) Name() string {
+	return "add_device_last_selected_at"
+}
+
+func (m Migration20261018_000003) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE devices ADD COLUMN last_selected_at DATETIME;
+		CREATE INDEX IF NOT EXISTS idx_devices_username_platform_status ON devices(matrix_username, platform, status);
+	`).Error
+}
+
+func (m Migration20261018_000003) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_devices_username_platform_status;
+		ALTER TABLE devices DROP COLUMN last_selected_at;
+	`).Error
+}
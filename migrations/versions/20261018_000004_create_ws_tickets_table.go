@@ -0,0 +1,34 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000004 struct{}
+
+func (m Migration20261018_000004) Version() string {
+	return "20261018_000004"
+}
+
+func (m Migration20261018_000004) Name() string {
+	return "create_ws_tickets_table"
+}
+
+func (m Migration20261018_000004) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS ws_tickets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			matrix_identity_id INTEGER NOT NULL,
+			ticket_hash BLOB NOT NULL,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (matrix_identity_id) REFERENCES matrix_identities(id) ON DELETE CASCADE
+		);
+		CREATE UNIQUE INDEX idx_ws_tickets_ticket_hash ON ws_tickets(ticket_hash);
+		CREATE INDEX idx_ws_tickets_expires_at ON ws_tickets(expires_at);
+	`).Error
+}
+
+func (m Migration20261018_000004) Down(db *gorm.DB) error {
+	return db.Exec("DROP TABLE IF EXISTS ws_tickets").Error
+}
@@ -0,0 +1,27 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000005 struct{}
+
+func (m Migration20261018_000005) Version() string {
+	return "20261018_000005"
+}
+
+func (m Migration20261018_000005) Name() string {
+	return "add_matrix_identity_scopes"
+}
+
+func (m Migration20261018_000005) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN scopes TEXT NOT NULL DEFAULT '["*"]';
+	`).Error
+}
+
+func (m Migration20261018_000005) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities DROP COLUMN scopes;
+	`).Error
+}
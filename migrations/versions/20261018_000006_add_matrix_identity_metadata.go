@@ -0,0 +1,37 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000006 struct{}
+
+func (m Migration20261018_000006) Version() string {
+	return "20261018_000006"
+}
+
+func (m Migration20261018_000006) Name() string {
+	return "add_matrix_identity_metadata"
+}
+
+func (m Migration20261018_000006) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN name TEXT NOT NULL DEFAULT '';
+		ALTER TABLE matrix_identities ADD COLUMN description TEXT NOT NULL DEFAULT '';
+		ALTER TABLE matrix_identities ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+		ALTER TABLE matrix_identities ADD COLUMN credential_id INTEGER;
+		CREATE INDEX IF NOT EXISTS idx_matrix_identities_credential_id ON matrix_identities(credential_id);
+		CREATE INDEX IF NOT EXISTS idx_matrix_identities_matrix_username ON matrix_identities(matrix_username);
+	`).Error
+}
+
+func (m Migration20261018_000006) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_matrix_identities_matrix_username;
+		DROP INDEX IF EXISTS idx_matrix_identities_credential_id;
+		ALTER TABLE matrix_identities DROP COLUMN credential_id;
+		ALTER TABLE matrix_identities DROP COLUMN labels;
+		ALTER TABLE matrix_identities DROP COLUMN description;
+		ALTER TABLE matrix_identities DROP COLUMN name;
+	`).Error
+}
@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000007 struct{}
+
+func (m Migration20261018_000007) Version() string {
+	return "20261018_000007"
+}
+
+func (m Migration20261018_000007) Name() string {
+	return "add_matrix_identity_expiry_notified_at"
+}
+
+func (m Migration20261018_000007) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN expiry_notified_at DATETIME;
+		CREATE INDEX IF NOT EXISTS idx_matrix_identities_expires_at ON matrix_identities(expires_at);
+	`).Error
+}
+
+func (m Migration20261018_000007) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_matrix_identities_expires_at;
+		ALTER TABLE matrix_identities DROP COLUMN expiry_notified_at;
+	`).Error
+}
@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000008 struct{}
+
+func (m Migration20261018_000008) Version() string {
+	return "20261018_000008"
+}
+
+func (m Migration20261018_000008) Name() string {
+	return "add_matrix_identity_mas_ids"
+}
+
+func (m Migration20261018_000008) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN mas_user_id TEXT NOT NULL DEFAULT '';
+		ALTER TABLE matrix_identities ADD COLUMN mas_session_id TEXT NOT NULL DEFAULT '';
+	`).Error
+}
+
+func (m Migration20261018_000008) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities DROP COLUMN mas_session_id;
+		ALTER TABLE matrix_identities DROP COLUMN mas_user_id;
+	`).Error
+}
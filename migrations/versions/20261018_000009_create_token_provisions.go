@@ -0,0 +1,41 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000009 struct{}
+
+func (m Migration20261018_000009) Version() string {
+	return "20261018_000009"
+}
+
+func (m Migration20261018_000009) Name() string {
+	return "create_token_provisions"
+}
+
+func (m Migration20261018_000009) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS token_provisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			matrix_username TEXT NOT NULL,
+			matrix_device_id TEXT NOT NULL,
+			mas_user_id TEXT NOT NULL DEFAULT '',
+			mas_session_id TEXT NOT NULL DEFAULT '',
+			step TEXT NOT NULL,
+			status TEXT NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_token_provisions_status ON token_provisions(status, updated_at);
+	`).Error
+}
+
+func (m Migration20261018_000009) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_token_provisions_status;
+		DROP TABLE IF EXISTS token_provisions;
+	`).Error
+}
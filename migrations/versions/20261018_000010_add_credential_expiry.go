@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000010 struct{}
+
+func (m Migration20261018_000010) Version() string {
+	return "20261018_000010"
+}
+
+func (m Migration20261018_000010) Name() string {
+	return "add_credential_expiry"
+}
+
+func (m Migration20261018_000010) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE credentials ADD COLUMN expires_at DATETIME;
+		ALTER TABLE credentials ADD COLUMN last_used_at DATETIME;
+	`).Error
+}
+
+func (m Migration20261018_000010) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE credentials DROP COLUMN last_used_at;
+		ALTER TABLE credentials DROP COLUMN expires_at;
+	`).Error
+}
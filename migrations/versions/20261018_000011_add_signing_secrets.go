@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000011 struct{}
+
+func (m Migration20261018_000011) Version() string {
+	return "20261018_000011"
+}
+
+func (m Migration20261018_000011) Name() string {
+	return "add_signing_secrets"
+}
+
+func (m Migration20261018_000011) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE credentials ADD COLUMN signing_secret BLOB;
+		ALTER TABLE matrix_identities ADD COLUMN signing_secret BLOB;
+	`).Error
+}
+
+func (m Migration20261018_000011) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities DROP COLUMN signing_secret;
+		ALTER TABLE credentials DROP COLUMN signing_secret;
+	`).Error
+}
@@ -0,0 +1,34 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000012 struct{}
+
+func (m Migration20261018_000012) Version() string {
+	return "20261018_000012"
+}
+
+func (m Migration20261018_000012) Name() string {
+	return "create_access_token_keys"
+}
+
+func (m Migration20261018_000012) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS access_token_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key_id TEXT NOT NULL UNIQUE,
+			private_key BLOB NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_access_token_keys_created_at ON access_token_keys(created_at);
+	`).Error
+}
+
+func (m Migration20261018_000012) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_access_token_keys_created_at;
+		DROP TABLE IF EXISTS access_token_keys;
+	`).Error
+}
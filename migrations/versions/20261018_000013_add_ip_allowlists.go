@@ -0,0 +1,29 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000013 struct{}
+
+func (m Migration20261018_000013) Version() string {
+	return "20261018_000013"
+}
+
+func (m Migration20261018_000013) Name() string {
+	return "add_ip_allowlists"
+}
+
+func (m Migration20261018_000013) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE credentials ADD COLUMN allowed_ips TEXT NOT NULL DEFAULT '[]';
+		ALTER TABLE matrix_identities ADD COLUMN allowed_ips TEXT NOT NULL DEFAULT '[]';
+	`).Error
+}
+
+func (m Migration20261018_000013) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities DROP COLUMN allowed_ips;
+		ALTER TABLE credentials DROP COLUMN allowed_ips;
+	`).Error
+}
@@ -0,0 +1,38 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000014 struct{}
+
+func (m Migration20261018_000014) Version() string {
+	return "20261018_000014"
+}
+
+func (m Migration20261018_000014) Name() string {
+	return "create_admin_sessions"
+}
+
+func (m Migration20261018_000014) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS admin_sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			token_hash BLOB NOT NULL UNIQUE,
+			credential_id INTEGER,
+			matrix_token BLOB,
+			expires_at DATETIME NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			FOREIGN KEY (credential_id) REFERENCES credentials(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_admin_sessions_expires_at ON admin_sessions(expires_at);
+	`).Error
+}
+
+func (m Migration20261018_000014) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_admin_sessions_expires_at;
+		DROP TABLE IF EXISTS admin_sessions;
+	`).Error
+}
@@ -0,0 +1,31 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000015 struct{}
+
+func (m Migration20261018_000015) Version() string {
+	return "20261018_000015"
+}
+
+func (m Migration20261018_000015) Name() string {
+	return "create_pending_device_links"
+}
+
+func (m Migration20261018_000015) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS pending_device_links (
+			matrix_username TEXT PRIMARY KEY,
+			method TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+	`).Error
+}
+
+func (m Migration20261018_000015) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP TABLE IF EXISTS pending_device_links;
+	`).Error
+}
@@ -0,0 +1,32 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000016 struct{}
+
+func (m Migration20261018_000016) Version() string {
+	return "20261018_000016"
+}
+
+func (m Migration20261018_000016) Name() string {
+	return "create_signature_nonces"
+}
+
+func (m Migration20261018_000016) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS signature_nonces (
+			nonce_hash TEXT PRIMARY KEY,
+			expires_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_signature_nonces_expires_at ON signature_nonces(expires_at);
+	`).Error
+}
+
+func (m Migration20261018_000016) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_signature_nonces_expires_at;
+		DROP TABLE IF EXISTS signature_nonces;
+	`).Error
+}
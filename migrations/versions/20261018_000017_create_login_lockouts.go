@@ -0,0 +1,40 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000017 struct{}
+
+func (m Migration20261018_000017) Version() string {
+	return "20261018_000017"
+}
+
+func (m Migration20261018_000017) Name() string {
+	return "create_login_lockouts"
+}
+
+func (m Migration20261018_000017) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS login_lockouts (
+			kind TEXT NOT NULL,
+			value TEXT NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			lockouts INTEGER NOT NULL DEFAULT 0,
+			tokens REAL NOT NULL DEFAULT 0,
+			last_refill DATETIME,
+			locked_until DATETIME,
+			last_failure DATETIME,
+			expires_at DATETIME,
+			PRIMARY KEY (kind, value)
+		);
+		CREATE INDEX IF NOT EXISTS idx_login_lockouts_expires_at ON login_lockouts(expires_at);
+	`).Error
+}
+
+func (m Migration20261018_000017) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_login_lockouts_expires_at;
+		DROP TABLE IF EXISTS login_lockouts;
+	`).Error
+}
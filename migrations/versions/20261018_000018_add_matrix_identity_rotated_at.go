@@ -0,0 +1,27 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000018 struct{}
+
+func (m Migration20261018_000018) Version() string {
+	return "20261018_000018"
+}
+
+func (m Migration20261018_000018) Name() string {
+	return "add_matrix_identity_rotated_at"
+}
+
+func (m Migration20261018_000018) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN rotated_at DATETIME;
+	`).Error
+}
+
+func (m Migration20261018_000018) Down(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities DROP COLUMN rotated_at;
+	`).Error
+}
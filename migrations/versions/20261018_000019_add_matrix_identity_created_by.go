@@ -0,0 +1,32 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000019 struct{}
+
+func (m Migration20261018_000019) Version() string {
+	return "20261018_000019"
+}
+
+func (m Migration20261018_000019) Name() string {
+	return "add_matrix_identity_created_by"
+}
+
+func (m Migration20261018_000019) Up(db *gorm.DB) error {
+	return db.Exec(`
+		ALTER TABLE matrix_identities ADD COLUMN created_by_client_id TEXT NOT NULL DEFAULT '';
+		UPDATE matrix_identities SET created_by_client_id = (
+			SELECT client_id FROM credentials WHERE credentials.id = matrix_identities.credential_id
+		) WHERE credential_id IN (SELECT id FROM credentials);
+		CREATE INDEX IF NOT EXISTS idx_matrix_identities_created_by_client_id ON matrix_identities(created_by_client_id);
+	`).Error
+}
+
+func (m Migration20261018_000019) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP INDEX IF EXISTS idx_matrix_identities_created_by_client_id;
+		ALTER TABLE matrix_identities DROP COLUMN created_by_client_id;
+	`).Error
+}
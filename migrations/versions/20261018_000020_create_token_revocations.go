@@ -0,0 +1,38 @@
+package versions
+
+import (
+	"gorm.io/gorm"
+)
+
+type Migration20261018_000020 struct{}
+
+func (m Migration20261018_000020) Version() string {
+	return "20261018_000020"
+}
+
+func (m Migration20261018_000020) Name() string {
+	return "create_token_revocations"
+}
+
+func (m Migration20261018_000020) Up(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS token_revocations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			matrix_username TEXT NOT NULL,
+			mas_user_id TEXT NOT NULL DEFAULT '',
+			mas_session_id TEXT NOT NULL DEFAULT '',
+			delete_credentials BOOLEAN NOT NULL DEFAULT 0,
+			deactivate_user BOOLEAN NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+	`).Error
+}
+
+func (m Migration20261018_000020) Down(db *gorm.DB) error {
+	return db.Exec(`
+		DROP TABLE IF EXISTS token_revocations;
+	`).Error
+}
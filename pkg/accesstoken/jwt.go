@@ -0,0 +1,155 @@
+package accesstoken
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Access tokens are RS256 JWTs with the "at+jwt" type from RFC 9068
+const (
+	algorithm = "RS256"
+	tokenType = "at+jwt"
+)
+
+// clockLeeway tolerates small clock differences between issuer and verifier
+const clockLeeway = 30 * time.Second
+
+var (
+	ErrMalformedToken   = errors.New("malformed access token")
+	ErrInvalidSignature = errors.New("invalid access token signature")
+	ErrTokenExpired     = errors.New("access token has expired")
+	ErrUnknownKey       = errors.New("access token signed with an unknown key")
+	ErrInvalidIssuer    = errors.New("access token has an unexpected issuer")
+)
+
+// Claims carried by an access token
+type Claims struct {
+	Issuer       string `json:"iss"`
+	Subject      string `json:"sub"`
+	ClientID     string `json:"client_id"`
+	CredentialID uint   `json:"credential_id"`
+	Scope        string `json:"scope,omitempty"`
+	IssuedAt     int64  `json:"iat"`
+	ExpiresAt    int64  `json:"exp"`
+	ID           string `json:"jti"`
+}
+
+// Scopes splits the space separated scope claim
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Sign encodes the claims as a JWT signed with the given key
+func Sign(claims *Claims, keyID string, key *rsa.PrivateKey) (string, error) {
+	headerJSON, err := json.Marshal(header{Algorithm: algorithm, Type: tokenType, KeyID: keyID})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Parse verifies a JWT's signature with the key returned for its key ID and
+// checks that it has not expired.
+func Parse(token string, publicKey func(keyID string) (*rsa.PublicKey, error)) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var h header
+	if err := decodeSegmentJSON(parts[0], &h); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if h.Algorithm != algorithm || h.Type != tokenType || h.KeyID == "" {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := publicKey(h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := decodeSegmentJSON(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	now := time.Now()
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(clockLeeway)) {
+		return nil, ErrTokenExpired
+	}
+	if time.Unix(claims.IssuedAt, 0).After(now.Add(clockLeeway)) {
+		return nil, ErrMalformedToken
+	}
+
+	return &claims, nil
+}
+
+// JWK is the public half of a signing key as published in a JWKS document
+type JWK struct {
+	KeyType   string `json:"kty" example:"RSA"`
+	Use       string `json:"use" example:"sig"`
+	Algorithm string `json:"alg" example:"RS256"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e" example:"AQAB"`
+}
+
+func NewJWK(keyID string, key *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: algorithm,
+		KeyID:     keyID,
+		Modulus:   encodeSegment(key.N.Bytes()),
+		Exponent:  encodeSegment(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegmentJSON(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,120 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func keyLookup(keyID string, key *rsa.PrivateKey) func(string) (*rsa.PublicKey, error) {
+	return func(id string) (*rsa.PublicKey, error) {
+		if id != keyID {
+			return nil, ErrUnknownKey
+		}
+		return &key.PublicKey, nil
+	}
+}
+
+func testClaims(expiresIn time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
+		Issuer:       "test",
+		Subject:      "my-app",
+		ClientID:     "my-app",
+		CredentialID: 7,
+		Scope:        "tokens:write:create tokens:read:list",
+		IssuedAt:     now.Unix(),
+		ExpiresAt:    now.Add(expiresIn).Unix(),
+		ID:           "jti",
+	}
+}
+
+func TestSignParse(t *testing.T) {
+	key := newTestKey(t)
+
+	token, err := Sign(testClaims(time.Minute), "k1", key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	claims, err := Parse(token, keyLookup("k1", key))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.ClientID != "my-app" || claims.CredentialID != 7 {
+		t.Errorf("Parse() claims = %+v", claims)
+	}
+	if scopes := claims.Scopes(); len(scopes) != 2 || scopes[1] != "tokens:read:list" {
+		t.Errorf("Scopes() = %v", scopes)
+	}
+}
+
+func TestParse_Expired(t *testing.T) {
+	key := newTestKey(t)
+
+	token, err := Sign(testClaims(-time.Hour), "k1", key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if _, err := Parse(token, keyLookup("k1", key)); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Parse() error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestParse_Tampered(t *testing.T) {
+	key := newTestKey(t)
+
+	token, err := Sign(testClaims(time.Minute), "k1", key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	forged := testClaims(time.Minute)
+	forged.Scope = "*"
+	other, err := Sign(forged, "k1", newTestKey(t))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	otherParts := strings.Split(other, ".")
+	tampered := parts[0] + "." + otherParts[1] + "." + parts[2]
+
+	if _, err := Parse(tampered, keyLookup("k1", key)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Parse(tampered) error = %v, want ErrInvalidSignature", err)
+	}
+	if _, err := Parse(other, keyLookup("k1", key)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Parse(other key) error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestParse_UnknownKeyAndMalformed(t *testing.T) {
+	key := newTestKey(t)
+
+	token, err := Sign(testClaims(time.Minute), "k2", key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := Parse(token, keyLookup("k1", key)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Parse() error = %v, want ErrUnknownKey", err)
+	}
+
+	for _, token := range []string{"", "a.b", "not.a.jwt", "mt_xxxxx"} {
+		if _, err := Parse(token, keyLookup("k1", key)); !errors.Is(err, ErrMalformedToken) {
+			t.Errorf("Parse(%q) error = %v, want ErrMalformedToken", token, err)
+		}
+	}
+}
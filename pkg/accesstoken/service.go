@@ -0,0 +1,252 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/crypto"
+	"interface-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	keyBits = 2048
+	// keyCacheTTL bounds how long keys created by other instances go unseen
+	keyCacheTTL = time.Minute
+	// minKeyReload stops tokens with unknown key IDs from hammering the database
+	minKeyReload = 5 * time.Second
+)
+
+type signingKey struct {
+	id        string
+	key       *rsa.PrivateKey
+	createdAt time.Time
+}
+
+// Service issues and verifies access tokens. Signing keys live in the
+// database so every instance shares them, and a new key is generated once the
+// current one is older than the rotation period. Retired keys stay published
+// until the tokens they signed have expired.
+type Service struct {
+	db       *gorm.DB
+	issuer   string
+	ttl      time.Duration
+	rotation time.Duration
+
+	mu       sync.Mutex
+	keys     []signingKey
+	loadedAt time.Time
+}
+
+func New(db *gorm.DB) *Service {
+	issuer := os.Getenv("ACCESS_TOKEN_ISSUER")
+	if issuer == "" {
+		issuer = "shortmesh-interface-api"
+	}
+
+	ttl := 15 * time.Minute
+	if seconds := os.Getenv("ACCESS_TOKEN_TTL_SECONDS"); seconds != "" {
+		if n, err := strconv.Atoi(seconds); err == nil && n > 0 {
+			ttl = time.Duration(n) * time.Second
+		}
+	}
+
+	rotation := 30 * 24 * time.Hour
+	if days := os.Getenv("ACCESS_TOKEN_KEY_ROTATION_DAYS"); days != "" {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			rotation = time.Duration(n) * 24 * time.Hour
+		}
+	}
+
+	return &Service{
+		db:       db,
+		issuer:   issuer,
+		ttl:      ttl,
+		rotation: rotation,
+	}
+}
+
+// TTL is how long issued tokens are valid for
+func (s *Service) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue signs an access token for the credential limited to the given scopes
+func (s *Service) Issue(credential *models.Credential, scopes []string) (string, error) {
+	key, err := s.currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := crypto.GenerateSecureToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	return Sign(&Claims{
+		Issuer:       s.issuer,
+		Subject:      credential.ClientID,
+		ClientID:     credential.ClientID,
+		CredentialID: credential.ID,
+		Scope:        strings.Join(scopes, " "),
+		IssuedAt:     now.Unix(),
+		ExpiresAt:    now.Add(s.ttl).Unix(),
+		ID:           jti,
+	}, key.id, key.key)
+}
+
+// Verify checks an access token's signature, expiry and issuer
+func (s *Service) Verify(token string) (*Claims, error) {
+	claims, err := Parse(token, s.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != s.issuer {
+		return nil, ErrInvalidIssuer
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys that may have signed unexpired tokens
+func (s *Service) JWKS() ([]JWK, error) {
+	if _, err := s.currentKey(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jwks := make([]JWK, 0, len(s.keys))
+	for _, k := range s.keys {
+		jwks = append(jwks, NewJWK(k.id, &k.key.PublicKey))
+	}
+	return jwks, nil
+}
+
+func (s *Service) publicKey(keyID string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := s.findKey(keyID); key != nil {
+		return &key.key.PublicKey, nil
+	}
+
+	// The key may have been created by another instance since the last load
+	if time.Since(s.loadedAt) < minKeyReload {
+		return nil, ErrUnknownKey
+	}
+	if err := s.loadKeys(); err != nil {
+		return nil, err
+	}
+	if key := s.findKey(keyID); key != nil {
+		return &key.key.PublicKey, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// currentKey returns the newest key, generating one when there is none or it
+// is due for rotation.
+func (s *Service) currentKey() (*signingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) > keyCacheTTL {
+		if err := s.loadKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.keys) > 0 && time.Since(s.keys[0].createdAt) < s.rotation {
+		return &s.keys[0], nil
+	}
+
+	key, err := s.generateKey()
+	if err != nil {
+		return nil, err
+	}
+	s.keys = append([]signingKey{*key}, s.keys...)
+	return key, nil
+}
+
+func (s *Service) findKey(keyID string) *signingKey {
+	for i := range s.keys {
+		if s.keys[i].id == keyID {
+			return &s.keys[i]
+		}
+	}
+	return nil
+}
+
+// loadKeys reads the keys that are current or still verify unexpired tokens.
+// The caller must hold s.mu.
+func (s *Service) loadKeys() error {
+	records, err := models.FindAccessTokenKeys(s.db, time.Now().UTC().Add(-(s.rotation + s.ttl)))
+	if err != nil {
+		return fmt.Errorf("failed to load access token keys: %w", err)
+	}
+
+	keys := make([]signingKey, 0, len(records))
+	for _, record := range records {
+		der, err := crypto.Decrypt(record.PrivateKey)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Skipping access token key %s: %v", record.KeyID, err))
+			continue
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Skipping access token key %s: %v", record.KeyID, err))
+			continue
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			logger.Error(fmt.Sprintf("Skipping access token key %s: not an RSA key", record.KeyID))
+			continue
+		}
+		keys = append(keys, signingKey{id: record.KeyID, key: rsaKey, createdAt: record.CreatedAt})
+	}
+
+	s.keys = keys
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// generateKey creates and stores a new signing key. The caller must hold s.mu.
+func (s *Service) generateKey() (*signingKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.Encrypt(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access token key: %w", err)
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+
+	record, err := models.CreateAccessTokenKey(s.db, hex.EncodeToString(idBytes), encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store access token key: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Generated access token signing key %s", record.KeyID))
+	return &signingKey{id: record.KeyID, key: key, createdAt: record.CreatedAt}, nil
+}
@@ -676,4 +676,4 @@ In order to be iterable, non-array objects must have a [Symbol.iterator]() metho
  * @license qrcode.react
  * Copyright (c) Paul O'Shannessy
  * SPDX-License-Identifier: ISC
- */var X2e={L:$d.QrCode.Ecc.LOW,M:$d.QrCode.Ecc.MEDIUM,Q:$d.QrCode.Ecc.QUARTILE,H:$d.QrCode.Ecc.HIGH},Q2e=128,Z2e="L",J2e="#FFFFFF",exe="#000000",txe=!1,ZD=4,nxe=.1;function rxe(e,t=0){const n=[];return e.forEach(function(r,o){let a=null;r.forEach(function(i,l){if(!i&&a!==null){n.push(`M${a+t} ${o+t}h${l-a}v1H${a+t}z`),a=null;return}if(l===r.length-1){if(!i)return;a===null?n.push(`M${l+t},${o+t} h1v1H${l+t}z`):n.push(`M${a+t},${o+t} h${l+1-a}v1H${a+t}z`);return}i&&a===null&&(a=l)})}),n.join("")}function oxe(e,t){return e.slice().map((n,r)=>r<t.y||r>=t.y+t.h?n:n.map((o,a)=>a<t.x||a>=t.x+t.w?o:!1))}function axe(e,t,n,r){if(r==null)return null;const o=n?ZD:0,a=e.length+o*2,i=Math.floor(t*nxe),l=a/t,s=(r.width||i)*l,c=(r.height||i)*l,d=r.x==null?e.length/2-s/2:r.x*l,f=r.y==null?e.length/2-c/2:r.y*l;let p=null;if(r.excavate){let m=Math.floor(d),v=Math.floor(f),h=Math.ceil(s+d-m),g=Math.ceil(c+f-v);p={x:m,y:v,w:h,h:g}}return{x:d,y:f,h:c,w:s,excavation:p}}(function(){try{new Path2D().addPath(new Path2D)}catch{return!1}return!0})();function ixe(e){const t=e,{value:n,size:r=Q2e,level:o=Z2e,bgColor:a=J2e,fgColor:i=exe,includeMargin:l=txe,imageSettings:s}=t,c=Y2e(t,["value","size","level","bgColor","fgColor","includeMargin","imageSettings"]);let d=$d.QrCode.encodeText(n,X2e[o]).getModules();const f=l?ZD:0,p=d.length+f*2,m=axe(d,r,l,s);let v=null;s!=null&&m!=null&&(m.excavation!=null&&(d=oxe(d,m.excavation)),v=Y.createElement("image",{xlinkHref:s.src,height:m.h,width:m.w,x:m.x+f,y:m.y+f,preserveAspectRatio:"none"}));const h=rxe(d,f);return Y.createElement("svg",q2e({height:r,width:r,viewBox:`0 0 ${p} ${p}`},c),Y.createElement("path",{fill:a,d:`M0,0 h${p}v${p}H0z`,shapeRendering:"crispEdges"}),Y.createElement("path",{fill:i,d:h,shapeRendering:"crispEdges"}),v)}function lxe(){const{modal:e}=Cu.useApp(),t=Wo("devices:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(!1),[d,f]=u.useState(!1),[p,m]=u.useState(!0),[v,h]=u.useState(null),[g,y]=u.useState("waiting"),[C,b]=u.useState(""),[$,x]=u.useState(""),[S,w]=u.useState(null),[E,P]=u.useState(""),[O,M]=u.useState(""),[k,_]=u.useState({}),[I,N]=u.useState(!1),[R,D]=u.useState(!1),[T,j]=u.useState(!1),[L,z]=u.useState(!1),[B,H]=u.useState(""),[W,V]=u.useState(""),[G,Z]=u.useState([]),q=ye=>{ye(!0),setTimeout(()=>ye(!1),500)},X=u.useRef(null),oe=u.useRef(!1),te=u.useRef(null);u.useEffect(()=>(Q(),()=>{X.current&&X.current.close()}),[]);const Q=async()=>{try{const ye=await Tn("/api/v1/admin/matrix-token-status");if(!ye)return;if(!(await Cn(ye)).has_matrix_token){f(!0),a(!1);return}const pe=await Tn("/api/v1/admin/devices");if(!pe)return;if(pe.status===403||!pe.ok){f(!0);return}const Oe=await Cn(pe);if((Oe==null?void 0:Oe.error)==="Invalid server response"){f(!0);return}r(Array.isArray(Oe)?Oe:[])}catch(ye){console.error("Error loading devices:",ye),Tt.error("Failed to load devices")}finally{a(!1)}},re=async()=>{if(!C.trim()){x("Matrix token is required");return}if(!C.startsWith("mt_")){x("Token must start with mt_");return}try{const ye=await Tn("/api/v1/admin/matrix-token",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({token:C})});if(!ye)return;if(ye.ok)f(!1),b(""),x(""),Tt.success("Matrix token set successfully"),Q();else{const he=await Cn(ye);x(he.error||"Failed to set token")}}catch(ye){console.error("Error setting token:",ye),x("Failed to set token")}},ae=async ye=>{N(!0);try{const he=await Tn("/api/v1/admin/devices",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({platform:ye})});if(!he)return;if(!he.ok){if(he.status===403){l(!1),f(!0);return}const Se=await Cn(he);H(Se.error||"Failed to add device");return}const Oe=(await Cn(he)).qr_code_url;if(!Oe||Oe.includes("token=")&&Oe.endsWith("token=")){Tt.error("Failed to generate QR code"),l(!1);return}m(!1),setTimeout(()=>{ee(Oe)},2e3)}catch(he){console.error("Error creating device:",he),H("Failed to add device")}finally{N(!1)}},ee=ye=>{try{X.current&&X.current.close();const he=new WebSocket(ye);X.current=he,he.receivedData=!1,he.hasError=!1,he.onopen=()=>{y("waiting")},he.onmessage=pe=>{pe.data.startsWith("Error:")?(he.hasError=!0,y("error"),Tt.error(pe.data)):(he.receivedData=!0,h(pe.data))},he.onerror=()=>{console.error("[Device Linking] WebSocket error"),he.hasError=!0,y("error"),Tt.error("Connection error")},he.onclose=()=>{he.receivedData&&!he.hasError&&(y("connected"),Tt.success("Device added successfully"),Q(),setTimeout(()=>{l(!1),J()},2e3))}}catch(he){console.error("[Device Linking] Error connecting WebSocket:",he),y("error")}},J=()=>{m(!0),h(null),y("waiting"),N(!1),H(""),X.current&&(X.current.close(),X.current=null)},ce=(ye,he)=>{e.confirm({title:"Delete Device",content:"Are you sure you want to delete this device?",okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const pe=await Tn("/api/v1/admin/devices",{method:"DELETE",headers:{"Content-Type":"application/json"},body:JSON.stringify({device_id:ye,platform:he})});if(!pe)return;if(pe.ok)Q(),Tt.success("Device deleted successfully");else{if(pe.status===403){f(!0);return}const Oe=await Cn(pe);Tt.error(Oe.error||"Failed to delete device")}}catch(pe){console.error("Error deleting device:",pe),Tt.error("Failed to delete device")}}})},de=()=>{Z(ye=>(ye.forEach(he=>{he.previewUrl&&URL.revokeObjectURL(he.previewUrl)}),[])),c(!1)},xe=ye=>{w(ye),P(""),M(""),Z(he=>(he.forEach(pe=>{pe.previewUrl&&URL.revokeObjectURL(pe.previewUrl)}),[])),V(""),c(!0)},ue=ye=>{const he=Array.from(ye).map(pe=>({file:pe,previewUrl:pe.type.startsWith("image/")?URL.createObjectURL(pe):null}));Z(pe=>[...pe,...he])},le=ye=>{Z(he=>{const pe=he[ye];return pe!=null&&pe.previewUrl&&URL.revokeObjectURL(pe.previewUrl),he.filter((Oe,Se)=>Se!==ye)})},be=async()=>{if(!E.trim()){V("Contact number is required");return}if(!O.trim()){V("Message is required");return}try{const ye=async pe=>{if(pe){const Oe=new FormData;return Oe.append("contact",E),Oe.append("platform",S.platform),Oe.append("text",O),Oe.append("file",pe),Tn(`/api/v1/admin/devices/${S.device_id}/message`,{method:"POST",body:Oe})}return Tn(`/api/v1/admin/devices/${S.device_id}/message`,{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({contact:E,platform:S.platform,text:O})})},he=G.length>0?G.map(pe=>pe.file):[null];for(const pe of he){const Oe=await ye(pe);if(!Oe)return;if(!Oe.ok){if(Oe.status===403){de(),f(!0);return}const Se=await Cn(Oe);V(Se.error||"Failed to send message");return}}de(),Tt.success("Message queued successfully")}catch(ye){console.error("Error sending message:",ye),V("Failed to send message")}},ge=async ye=>{try{await Fw(ye),Tt.success("Copied to clipboard")}catch{Tt.error("Failed to copy")}},me=ye=>{_(he=>({...he,[ye]:!he[ye]}))},we=async()=>{const ye=await Tn("/api/v1/admin/matrix-token-status");if(!ye)return;if(!(await Cn(ye)).has_matrix_token){f(!0);return}H(""),l(!0)},Ee=[{title:"Platform",dataIndex:"platform",key:"platform",width:120,render:ye=>A.jsx(zl,{color:"blue",children:ye.toUpperCase()})},{title:"Device ID",dataIndex:"device_id",key:"device_id",render:(ye,he,pe)=>A.jsxs(xa,{size:"small",children:[A.jsx(qt,{variant:"body2",component:"code",sx:{fontFamily:'"Google Sans Mono", monospace'},children:k[`device-${pe}`]?ye:Yd(ye)}),A.jsx(jm,{title:"Toggle visibility",children:A.jsx(zi,{size:"small",onClick:()=>me(`device-${pe}`),children:k[`device-${pe}`]?A.jsx(Ud,{fontSize:"small"}):A.jsx(Kd,{fontSize:"small"})})}),A.jsx(jm,{title:"Copy",children:A.jsx(zi,{size:"small",onClick:()=>ge(ye),children:A.jsx(Wd,{fontSize:"small"})})})]})},{title:"Actions",key:"actions",width:120,align:"center",render:(ye,he)=>A.jsxs(xa,{size:"small",children:[A.jsx(jm,{title:"Send message",children:A.jsx(zi,{sx:{color:"#e1e1e1",opacity:t?1:.45},size:"small",onClick:()=>t?xe(he):Tt.info("You do not have permission to send messages. Contact admin."),children:A.jsx(X5,{})})}),A.jsx(jm,{title:"Delete",children:A.jsx(zi,{color:t?"error":"default",sx:{opacity:t?1:.45},size:"small",onClick:()=>t?ce(he.device_id,he.platform):Tt.info("You do not have permission to delete devices. Contact admin."),children:A.jsx(dy,{})})})]})}];return A.jsxs(At,{children:[A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Devices"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"View and manage all devices connected to your Matrix identity."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?we():Tt.info("You do not have permission to add devices. Contact admin."),sx:{opacity:t?1:.45},children:"Add Device"})]}),A.jsx(la,{columns:Ee,dataSource:n,loading:o,rowKey:ye=>`${ye.platform}-${ye.device_id}`,pagination:!1,locale:{emptyText:Wo("devices:read:*")?"No devices found":"You do not have access to view devices. Contact admin."}}),A.jsx(Hn,{title:"Add New Device",open:i,onCancel:()=>{oe.current?(oe.current=!1,l(!1),J()):q(j)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:T?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>{l(!1),J()},children:"Cancel"},"cancel")],width:600,children:p?A.jsxs(At,{sx:{pt:2},children:[A.jsx(qt,{color:"text.secondary",gutterBottom:!0,children:"Select the platform for your new device"}),A.jsx(T5,{container:!0,spacing:2,sx:{mt:2},children:[{platform:"wa",name:"WhatsApp",image:"/admin/whatsapp.png"},{platform:"signal",name:"Signal",image:"/admin/signal.png"},{platform:"telegram",name:"Telegram",image:"/admin/telegram.png"}].map(ye=>A.jsx(T5,{item:!0,xs:12,sm:4,children:A.jsx(Gpe,{children:A.jsx(Jpe,{onClick:()=>ae(ye.platform),disabled:I,children:A.jsxs(ome,{sx:{textAlign:"center",py:4},children:[A.jsx("img",{src:ye.image,alt:ye.name,style:{width:80,height:80,objectFit:"contain"}}),A.jsx(qt,{variant:"h6",sx:{mt:2},children:ye.name})]})})})},ye.platform))}),I&&A.jsxs(At,{sx:{textAlign:"center",mt:3},children:[A.jsx(Fd,{size:"default"}),A.jsx(qt,{color:"text.secondary",sx:{mt:1},children:"Setting up device..."})]}),B&&A.jsx(At,{sx:{mt:2},children:A.jsx(Cd,{severity:"error",children:B})})]}):A.jsxs(At,{sx:{pt:2,textAlign:"center"},children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Scan QR Code"}),A.jsx(qt,{color:"text.secondary",sx:{mb:3},children:"Use your device to scan this QR code"}),v?A.jsx(At,{sx:{display:"flex",justifyContent:"center",mb:2},children:A.jsx(ixe,{value:v,size:300})}):A.jsx(At,{sx:{py:10},children:A.jsx(Fd,{size:"large"})}),g==="waiting"&&A.jsxs(xa,{children:[A.jsx(Fd,{size:"small"}),A.jsx(qt,{children:"Waiting for device..."})]}),g==="connected"&&A.jsx(Cd,{severity:"success",children:"Device connected successfully!"}),g==="error"&&A.jsx(Cd,{severity:"error",children:"Connection failed. Please try again."})]})}),A.jsxs(Hn,{title:"Set Matrix Token",open:d,onCancel:()=>{D(!0),setTimeout(()=>D(!1),500)},closable:!1,maskClosable:!0,wrapClassName:R?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>f(!1),children:"Skip"},"skip"),A.jsx(jt,{type:"text",onClick:re,style:{color:"#4357AD"},children:"Set Token"},"set")],children:[A.jsx(qt,{color:"text.secondary",sx:{mb:2},children:"Please set your Matrix token to manage devices."}),A.jsx(wo,{size:"large",placeholder:"Matrix Token",value:C,onChange:ye=>{b(ye.target.value),x("")},status:$?"error":"",autoFocus:!0}),$&&A.jsx(qt,{color:"error",variant:"caption",sx:{mt:.5,display:"block"},children:$}),A.jsxs(qt,{color:"text.secondary",variant:"caption",sx:{mt:2,display:"block"},children:["Your token will be stored for this session and will be cleared when you log out. ",A.jsx("br",{}),"Don't have a token?"," ",A.jsx(I8,{to:"/tokens",style:{color:"#4357AD",textDecoration:"underline"},children:"Create one here"})]})]}),A.jsxs(Hn,{title:"Send Message",open:s,onCancel:()=>{oe.current?(oe.current=!1,de()):q(z)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:L?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:de,children:"Cancel"},"cancel"),A.jsx(jt,{type:"text",icon:A.jsx(X5,{style:{fontSize:16}}),onClick:be,style:{color:"#8ED462"},children:"Send"},"send")],children:[A.jsxs(At,{sx:{mt:5},children:[W&&A.jsx(Cd,{severity:"error",sx:{mb:2},children:W}),A.jsx(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:"Contact Number"}),A.jsx(U2e,{international:!0,defaultCountry:"CM",value:E,onChange:ye=>{P(ye),V("")},placeholder:"Enter phone number",autoComplete:"tel"})]}),A.jsxs(At,{sx:{mt:2},children:[A.jsx(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:"Message"}),A.jsx(wo.TextArea,{rows:4,placeholder:"Enter your message",value:O,onChange:ye=>{M(ye.target.value),V("")}})]}),A.jsxs(At,{sx:{mt:2},children:[A.jsxs(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:["Attachments ",A.jsx("span",{style:{fontSize:11,opacity:.6},children:"(optional)"})]}),A.jsxs(At,{sx:{display:"flex",flexWrap:"wrap",gap:1},children:[G.map(({file:ye,previewUrl:he},pe)=>A.jsxs(At,{sx:{position:"relative",width:80,height:80,borderRadius:1,border:"1px solid #333",overflow:"hidden",background:"#1e1e1e",flexShrink:0},children:[he?A.jsx("img",{src:he,alt:ye.name,style:{width:"100%",height:"100%",objectFit:"cover"}}):A.jsxs(At,{sx:{display:"flex",flexDirection:"column",alignItems:"center",justifyContent:"center",height:"100%",px:.5},children:[A.jsx(_he,{style:{fontSize:22,color:"#aaa"}}),A.jsx(qt,{sx:{fontSize:9,color:"#aaa",textAlign:"center",overflow:"hidden",textOverflow:"ellipsis",whiteSpace:"nowrap",maxWidth:"100%",mt:.5},children:ye.name})]}),he&&A.jsx(At,{sx:{position:"absolute",bottom:0,left:0,right:0,background:"rgba(0,0,0,0.55)",px:.5,py:.25},children:A.jsx(qt,{sx:{fontSize:9,color:"#fff",display:"block",overflow:"hidden",textOverflow:"ellipsis",whiteSpace:"nowrap"},children:ye.name})}),A.jsx(zi,{size:"small",sx:{position:"absolute",top:2,right:2,p:0,width:18,height:18,background:"rgba(0,0,0,0.65)","&:hover":{background:"rgba(0,0,0,0.9)"}},onClick:()=>le(pe),children:A.jsx(si,{style:{fontSize:12}})})]},pe)),A.jsx(At,{onClick:()=>{var ye;return(ye=te.current)==null?void 0:ye.click()},sx:{width:80,height:80,borderRadius:1,border:"1px dashed #555",background:"#1e1e1e",display:"flex",alignItems:"center",justifyContent:"center",cursor:"pointer",flexShrink:0,color:"#666","&:hover":{borderColor:"#888",color:"#aaa"},transition:"border-color 0.15s, color 0.15s"},children:A.jsx(Jc,{style:{fontSize:28}})})]}),A.jsx("input",{ref:te,type:"file",multiple:!0,style:{display:"none"},onChange:ye=>{var he;(he=ye.target.files)!=null&&he.length&&ue(ye.target.files),ye.target.value=""}})]})]})]})}function sxe(){const{modal:e}=Cu.useApp(),t=Wo("webhooks:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(!1),[d,f]=u.useState(!1),[p,m]=u.useState(""),[v,h]=u.useState(null),[g,y]=u.useState(""),[C,b]=u.useState(!0),[$,x]=u.useState(""),[S,w]=u.useState(""),[E,P]=u.useState(!1),[O,M]=u.useState(!1),[k,_]=u.useState(!1),[I,N]=u.useState(""),[R,D]=u.useState(""),T=u.useRef(!1),j=q=>{q(!0),setTimeout(()=>q(!1),500)};u.useEffect(()=>{L()},[]);const L=async()=>{try{const q=await Tn("/api/v1/admin/matrix-token-status");if(!q)return;if(!(await Cn(q)).has_matrix_token){f(!0),a(!1);return}const oe=await Tn("/api/v1/admin/webhooks");if(!oe)return;if(!oe.ok){if(oe.status===403){f(!0);return}const Q=await Cn(oe);Tt.error(Q.error||"Failed to load webhooks"),r([]);return}const te=await Cn(oe);if((te==null?void 0:te.error)==="Invalid server response"){f(!0);return}r(Array.isArray(te)?te:[])}catch(q){console.error("Error loading webhooks:",q),Tt.error("Failed to load webhooks")}finally{a(!1)}},z=async()=>{if(!$.trim()){w("Matrix token is required");return}if(!$.startsWith("mt_")){w("Token must start with mt_");return}try{const q=await Tn("/api/v1/admin/matrix-token",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({token:$})});if(!q)return;if(q.ok)f(!1),x(""),w(""),Tt.success("Matrix token set successfully"),L();else{const X=await Cn(q);w(X.error||"Failed to set token")}}catch(q){console.error("Error setting token:",q),w("Failed to set token")}},B=async()=>{if(!p.trim()){N("Please enter a webhook URL");return}try{const q=await Tn("/api/v1/admin/webhooks",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({url:p})});if(!q)return;if(q.ok)l(!1),m(""),N(""),L(),Tt.success("Webhook added successfully");else{if(q.status===403){l(!1),f(!0);return}const X=await Cn(q);N(X.error||"Failed to add webhook")}}catch(q){console.error("Error adding webhook:",q),N("Failed to add webhook")}},H=async()=>{if(!g.trim()){D("Please enter a webhook URL");return}try{const q=await Tn(`/api/v1/admin/webhooks/${v.id}`,{method:"PUT",headers:{"Content-Type":"application/json"},body:JSON.stringify({url:g,active:C})});if(!q)return;if(q.ok)c(!1),h(null),D(""),L(),Tt.success("Webhook updated successfully");else{if(q.status===403){c(!1),f(!0);return}const X=await Cn(q);D(X.error||"Failed to update webhook")}}catch(q){console.error("Error updating webhook:",q),D("Failed to update webhook")}},W=async q=>{e.confirm({title:"Delete Webhook",content:"Are you sure you want to delete this webhook?",okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const X=await Tn(`/api/v1/admin/webhooks/${q}`,{method:"DELETE"});if(!X)return;if(X.ok)L(),Tt.success("Webhook deleted successfully");else{if(X.status===403){f(!0);return}const oe=await Cn(X);Tt.error(oe.error||"Failed to delete webhook")}}catch(X){console.error("Error deleting webhook:",X),Tt.error("Failed to delete webhook")}}})},V=q=>{h(q),y(q.url),b(q.active),D(""),c(!0)},G=async()=>{const q=await Tn("/api/v1/admin/matrix-token-status");if(!q)return;if(!(await Cn(q)).has_matrix_token){f(!0);return}m(""),N(""),l(!0)},Z=[{title:"URL",dataIndex:"url",key:"url",width:"40%",ellipsis:!0},{title:"Status",dataIndex:"active",key:"active",width:"10%",render:q=>A.jsx(zl,{color:q?"success":"error",children:q?"Active":"Inactive"})},{title:"Created",dataIndex:"created_at",key:"created_at",width:"20%",render:q=>Cc(q)},{title:"Updated",dataIndex:"updated_at",key:"updated_at",width:"20%",render:q=>Cc(q)},{title:"Actions",key:"actions",width:"10%",align:"center",render:(q,X)=>A.jsxs(xa,{size:"small",children:[A.jsx(jt,{type:"text",icon:A.jsx(I_,{style:{fontSize:18}}),onClick:()=>t?V(X):Tt.info("You do not have permission to edit webhooks. Contact admin."),style:{opacity:t?1:.45}}),A.jsx(jt,{type:"text",icon:A.jsx(dy,{style:{fontSize:18}}),onClick:()=>t?W(X.id):Tt.info("You do not have permission to delete webhooks. Contact admin."),danger:t,style:{opacity:t?1:.45}})]})}];return A.jsxs(At,{children:[A.jsx(At,{children:A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Webhooks"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"Manage webhook URLs to receive message notifications."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?G():Tt.info("You do not have permission to add webhooks. Contact admin."),sx:{opacity:t?1:.45},children:"Add Webhook"})]})}),A.jsx(la,{columns:Z,dataSource:n,loading:o,rowKey:"id",pagination:{pageSize:10},locale:{emptyText:Wo("webhooks:read:*")?"No webhooks found":"You do not have access to view webhooks. Contact admin."}}),A.jsx(Hn,{title:"Add Webhook",open:i,onCancel:()=>{T.current?(T.current=!1,l(!1)):j(M)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{T.current=!0}}),wrapClassName:O?"modal-shake":"",footer:[A.jsx(jt,{variant:"text",onClick:()=>l(!1),children:"Cancel"},"cancel"),A.jsx(jt,{variant:"text",type:"primary",onClick:B,children:"Add Webhook"},"add")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx(wo,{placeholder:"https://example.com/webhook",value:p,onChange:q=>{m(q.target.value),N("")},onPressEnter:B,status:I?"error":""}),I&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:I}),A.jsx("div",{style:{marginTop:8,fontSize:12,color:"rgba(255, 255, 255, 0.6)"},children:"Enter a valid URL to receive webhook notifications"})]})}),A.jsx(Hn,{title:"Edit Webhook",open:s,onCancel:()=>{T.current?(T.current=!1,c(!1)):j(_)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{T.current=!0}}),wrapClassName:k?"modal-shake":"",footer:[A.jsx(jt,{onClick:()=>c(!1),children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:H,children:"Save Changes"},"save")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx(wo,{placeholder:"Webhook URL",value:g,onChange:q=>{y(q.target.value),D("")},onPressEnter:H,status:R?"error":""}),R&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:R}),A.jsxs("div",{style:{marginTop:16,display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs("div",{children:[A.jsx("div",{style:{fontSize:14},children:C?"Active":"Inactive"}),A.jsx("div",{style:{fontSize:12,color:"rgba(255,255,255,0.45)"},children:C?"Toggle off to deactivate webhook":"Toggle on to reactivate webhook"})]}),A.jsx(XS,{checked:C,onChange:b,checkedChildren:"Active",unCheckedChildren:"Inactive"})]})]})}),A.jsx(Hn,{title:"Set Matrix Token",open:d,onCancel:()=>{P(!0),setTimeout(()=>P(!1),500)},closable:!1,maskClosable:!0,wrapClassName:E?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>f(!1),children:"Skip"},"skip"),A.jsx(jt,{type:"text",onClick:z,style:{color:"#4357AD"},children:"Set Token"},"set")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx("div",{style:{marginBottom:8,color:"rgba(255, 255, 255, 0.6)"},children:"Please set your Matrix token to manage webhooks."}),A.jsx(wo,{placeholder:"Matrix Token",value:$,onChange:q=>{x(q.target.value),w("")},status:S?"error":"",onPressEnter:z}),S&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:S}),A.jsxs("div",{style:{marginTop:8,fontSize:12,color:"rgba(255, 255, 255, 0.6)"},children:["Your token will be stored for this session and will be cleared when you log out.",A.jsx("br",{}),"Don't have a token?"," ",A.jsx(I8,{to:"/tokens",style:{color:"#4357AD",textDecoration:"underline"},children:"Create one here"})]})]})})]})}function cxe(){const{modal:e}=Cu.useApp(),t=Wo("credentials:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(""),[d,f]=u.useState(""),[p,m]=u.useState(!1),[v,h]=u.useState(!1),[g,y]=u.useState(""),[C,b]=u.useState(!1),[$,x]=u.useState(!1),[S,w]=u.useState(null),[E,P]=u.useState(""),[O,M]=u.useState(!1),[k,_]=u.useState(!0),[I,N]=u.useState(!1),[R,D]=u.useState(!1),[T,j]=u.useState(""),[L,z]=u.useState(""),[B,H]=u.useState(!1),[W,V]=u.useState(!1),[G,Z]=u.useState(!1),[q,X]=u.useState(!1),oe=u.useRef(!1),te=ue=>{ue(!0),setTimeout(()=>ue(!1),500)};u.useEffect(()=>{Q()},[]);const Q=async()=>{a(!0);try{const ue=await Tn("/api/v1/admin/credentials");if(!ue)return;const le=await Cn(ue);r(Array.isArray(le)?le:[])}catch(ue){console.error("Error loading credentials:",ue),Tt.error("Failed to load credentials")}finally{a(!1)}},re=async()=>{if(!s.trim()){j("Client ID is required");return}j(""),m(!0);try{const ue=await Tn("/api/v1/admin/credentials",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({client_id:s.trim(),description:d.trim()})});if(!ue)return;if(ue.ok){const le=await Cn(ue);l(!1),c(""),f(""),j(""),y(le.client_secret),b(!1),h(!0),Q()}else{const le=await Cn(ue);j(le.error||"Failed to create credential")}}catch(ue){console.error("Error creating credential:",ue),j("Failed to create credential")}finally{m(!1)}},ae=ue=>{w(ue),P(ue.description||""),M(!1),_(ue.active),z(""),x(!0)},ee=()=>{M(!0),D(!1)},J=async()=>{if(S){z(""),N(!0);try{const ue={};E!==S.description&&(ue.description=E),O&&(ue.regenerate_secret=!0),k!==S.active&&(ue.active=k);const le=await Tn(`/api/v1/admin/credentials/${S.client_id}`,{method:"PUT",headers:{"Content-Type":"application/json"},body:JSON.stringify(ue)});if(!le)return;if(le.ok){const be=await Cn(le);x(!1),w(null),z(""),Q(),Tt.success("Credential updated successfully"),be.client_secret&&(y(be.client_secret),b(!1),h(!0))}else{const be=await Cn(le);z(be.error||"Failed to update credential")}}catch(ue){console.error("Error updating credential:",ue),z("Failed to update credential")}finally{N(!1)}}},ce=ue=>{e.confirm({title:"Delete Credential",content:`Are you sure you want to permanently delete "${ue}"? This cannot be undone.`,okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const le=await Tn(`/api/v1/admin/credentials/${ue}`,{method:"DELETE"});if(!le)return;if(le.ok)Q(),Tt.success("Credential deleted successfully");else{const be=await Cn(le);Tt.error(be.error||"Failed to delete credential")}}catch(le){console.error("Error deleting credential:",le),Tt.error("Failed to delete credential")}}})},de=async ue=>{try{await Fw(ue),Tt.success("Copied to clipboard")}catch{Tt.error("Failed to copy")}},xe=[{title:"Client ID",dataIndex:"client_id",key:"client_id",render:ue=>A.jsx("code",{style:{fontFamily:'"Google Sans Mono", monospace'},children:ue})},{title:"Role",dataIndex:"role",key:"role",render:ue=>A.jsx(zl,{color:ue==="super_admin"?"gold":"blue",children:ue==="super_admin"?"Super Admin":"User"})},{title:"Scopes",dataIndex:"scopes",key:"scopes",render:ue=>Array.isArray(ue)&&ue.length>0?A.jsx(xa,{size:[4,4],wrap:!0,children:ue.map(le=>A.jsx(zl,{style:{fontFamily:'"Google Sans Mono", monospace',fontSize:11},children:le},le))}):"-"},{title:"Description",dataIndex:"description",key:"description",render:ue=>ue||A.jsx(qt,{variant:"caption",color:"text.secondary",children:"-"})},{title:"Status",dataIndex:"active",key:"active",render:ue=>A.jsx(zl,{color:ue?"success":"error",children:ue?"Active":"Inactive"})},{title:"Created",dataIndex:"created_at",key:"created_at",render:ue=>Cc(ue)},{title:"Actions",key:"actions",align:"center",render:(ue,le)=>A.jsxs(xa,{children:[A.jsx(jt,{type:"text",icon:A.jsx(I_,{style:{fontSize:18}}),onClick:()=>t?ae(le):Tt.info("You do not have permission to edit credentials. Contact admin."),style:{opacity:t?1:.45}}),A.jsx(jt,{type:"text",danger:t,icon:A.jsx(dy,{style:{fontSize:18}}),onClick:()=>t?ce(le.client_id):Tt.info("You do not have permission to delete credentials. Contact admin."),style:{opacity:t?1:.45}})]})}];return A.jsxs(At,{children:[A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"API Credentials"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"Manage API credentials (client ID / client secret pairs) used to authenticate API requests."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?(c(""),f(""),j(""),l(!0)):Tt.info("You do not have permission to create credentials. Contact admin."),sx:{opacity:t?1:.45},children:"New Credential"})]}),A.jsx(la,{dataSource:n,columns:xe,rowKey:"client_id",loading:o,pagination:{pageSize:10},scroll:{x:!0},locale:{emptyText:Wo("credentials:read:*")?"No credentials found":"You do not have access to view credentials. Contact admin."}}),A.jsx(Hn,{title:"Create Credential",open:i,onCancel:()=>{oe.current?(oe.current=!1,l(!1)):te(H)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:B?"modal-shake":"",destroyOnHidden:!0,footer:[A.jsx(jt,{onClick:()=>l(!1),disabled:p,children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:re,loading:p,children:"Create"},"create")],children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[T&&A.jsx(ni,{type:"error",showIcon:!0,message:T}),A.jsxs(At,{children:[A.jsxs(qt,{variant:"body2",gutterBottom:!0,children:["Client ID ",A.jsx("span",{style:{color:"red"},children:"*"})]}),A.jsx(wo,{placeholder:"e.g. my-app",value:s,onChange:ue=>{c(ue.target.value),j("")},onPressEnter:re,autoFocus:!0})]}),A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",gutterBottom:!0,children:"Description"}),A.jsx(wo,{placeholder:"Optional description",value:d,onChange:ue=>f(ue.target.value)})]})]})}),A.jsx(Hn,{title:`Edit — ${S==null?void 0:S.client_id}`,open:$,onCancel:()=>{oe.current?(oe.current=!1,x(!1)):te(V)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:W?"modal-shake":"",destroyOnHidden:!0,footer:[A.jsx(jt,{onClick:()=>x(!1),disabled:I,children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:J,loading:I,children:"Save"},"save")],children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[L&&A.jsx(ni,{type:"error",showIcon:!0,message:L}),A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",gutterBottom:!0,children:"Description"}),A.jsx(wo,{placeholder:"Optional description",value:E,onChange:ue=>{P(ue.target.value),z("")}})]}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",children:"Regenerate client secret"}),A.jsx(qt,{variant:"caption",color:"text.secondary",children:"Issue a new secret and invalidate the current one"})]}),O?A.jsx(ni,{type:"warning",showIcon:!0,banner:!0,message:"Will regenerate on save",style:{padding:"2px 8px",fontSize:12}}):A.jsx(jt,{icon:A.jsx(Dhe,{style:{fontSize:16}}),onClick:()=>D(!0),children:"Regenerate"})]}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",children:k?"Active":"Inactive"}),A.jsx(qt,{variant:"caption",color:"text.secondary",children:k?"Toggle off to deactivate and revoke API access":"Toggle on to reactivate and restore API access"})]}),A.jsx(XS,{checked:k,onChange:_,checkedChildren:"Active",unCheckedChildren:"Inactive"})]})]})}),A.jsx(Hn,{title:"Regenerate Client Secret?",open:R,onCancel:()=>{oe.current?(oe.current=!1,D(!1)):te(Z)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:G?"modal-shake":"",zIndex:1100,width:420,footer:[A.jsx(jt,{onClick:()=>D(!1),children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",danger:!0,onClick:ee,children:"Yes, regenerate"},"regenerate")],children:A.jsx(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:1},children:A.jsx(ni,{type:"warning",showIcon:!0,message:"This will immediately invalidate the current client secret.",description:"Any integrations using the old secret will stop working. The new secret will be shown once after saving — make sure to copy it."})})}),A.jsx(Hn,{title:"Client Secret",open:v,onCancel:()=>{oe.current?(oe.current=!1,h(!1)):te(X)},closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),footer:[A.jsx(jt,{type:"primary",onClick:()=>h(!1),children:"I have saved!"},"close")],maskClosable:!0,wrapClassName:q?"modal-shake":"",destroyOnHidden:!0,children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[A.jsx(ni,{type:"warning",showIcon:!0,message:"Save this secret now — it will not be shown again."}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",background:"#1e1e1e",border:"1px solid #333",borderRadius:1,px:1.5,py:.75,gap:1},children:[A.jsx("code",{style:{flex:1,wordBreak:"break-all",fontFamily:'"Google Sans Mono", monospace',fontSize:13,color:"#e1e1e1",background:"transparent"},children:C?g:Yd(g)}),A.jsx(jt,{type:"text",size:"small",style:{color:"#aaa",flexShrink:0},icon:C?A.jsx(Ud,{style:{fontSize:16}}):A.jsx(Kd,{style:{fontSize:16}}),onClick:()=>b(ue=>!ue)}),A.jsx(jt,{type:"text",size:"small",style:{color:"#aaa",flexShrink:0},icon:A.jsx(Wd,{style:{fontSize:16}}),onClick:()=>de(g)})]})]})})]})}function uxe(){return A.jsxs($L,{children:[A.jsx(Ti,{path:"/login",element:A.jsx(Mye,{})}),A.jsxs(Ti,{path:"/",element:A.jsx(khe,{children:A.jsx(oye,{})}),children:[A.jsx(Ti,{index:!0,element:A.jsx(O8,{to:"/tokens",replace:!0})}),A.jsx(Ti,{path:"tokens",element:A.jsx(Tye,{})}),A.jsx(Ti,{path:"devices",element:A.jsx(lxe,{})}),A.jsx(Ti,{path:"webhooks",element:A.jsx(sxe,{})}),A.jsx(Ti,{path:"credentials",element:A.jsx(cxe,{})})]})]})}const dxe=tS({palette:{mode:"dark",primary:{main:"#8ED462"},secondary:{main:"#f50057"},background:{default:"#0a0a0a",paper:"#181818"}},typography:{fontFamily:'"Google Sans", -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif',fontWeightRegular:400,fontWeightMedium:500,fontWeightBold:700,code:{fontFamily:'"Google Sans Mono", Monaco, Consolas, "Courier New", monospace'}},components:{MuiCssBaseline:{styleOverrides:{body:{scrollbarColor:"#6b6b6b #2b2b2b","&::-webkit-scrollbar, & *::-webkit-scrollbar":{width:8},"&::-webkit-scrollbar-thumb, & *::-webkit-scrollbar-thumb":{borderRadius:8,backgroundColor:"#6b6b6b",minHeight:24},"&::-webkit-scrollbar-track, & *::-webkit-scrollbar-track":{backgroundColor:"#2b2b2b"}}}},MuiButton:{styleOverrides:{root:{textTransform:"none"}}}}}),fxe={token:{colorPrimary:"#8ED462",colorBgContainer:"#0f0f0f",colorBgElevated:"#1a1a1a",colorBorder:"rgba(255, 255, 255, 0.08)",colorText:"rgba(255, 255, 255, 0.87)",colorTextSecondary:"rgba(255, 255, 255, 0.6)",colorTextTertiary:"rgba(255, 255, 255, 0.38)",colorBgLayout:"#0a0a0a",fontFamily:'"Google Sans", "Roboto", "Helvetica", "Arial", sans-serif',fontSize:14,borderRadius:4},algorithm:"dark",components:{Table:{headerBg:"#0f0f0f",headerColor:"rgba(255, 255, 255, 0.87)",rowHoverBg:"rgba(255, 255, 255, 0.05)",borderColor:"rgba(255, 255, 255, 0.08)",bodySortBg:"#0f0f0f"},Modal:{contentBg:"#1f1f1f",headerBg:"#1f1f1f"},Card:{colorBgContainer:"#0f0f0f"}}};pb.createRoot(document.getElementById("root")).render(A.jsx(Y.StrictMode,{children:A.jsx(IL,{basename:"/admin",children:A.jsx(GV,{theme:dxe,children:A.jsxs(Ra,{theme:{...fxe,algorithm:Fue.darkAlgorithm},children:[A.jsx(QV,{}),A.jsx(Cu,{children:A.jsx(uxe,{})})]})})})}));
+ */var X2e={L:$d.QrCode.Ecc.LOW,M:$d.QrCode.Ecc.MEDIUM,Q:$d.QrCode.Ecc.QUARTILE,H:$d.QrCode.Ecc.HIGH},Q2e=128,Z2e="L",J2e="#FFFFFF",exe="#000000",txe=!1,ZD=4,nxe=.1;function rxe(e,t=0){const n=[];return e.forEach(function(r,o){let a=null;r.forEach(function(i,l){if(!i&&a!==null){n.push(`M${a+t} ${o+t}h${l-a}v1H${a+t}z`),a=null;return}if(l===r.length-1){if(!i)return;a===null?n.push(`M${l+t},${o+t} h1v1H${l+t}z`):n.push(`M${a+t},${o+t} h${l+1-a}v1H${a+t}z`);return}i&&a===null&&(a=l)})}),n.join("")}function oxe(e,t){return e.slice().map((n,r)=>r<t.y||r>=t.y+t.h?n:n.map((o,a)=>a<t.x||a>=t.x+t.w?o:!1))}function axe(e,t,n,r){if(r==null)return null;const o=n?ZD:0,a=e.length+o*2,i=Math.floor(t*nxe),l=a/t,s=(r.width||i)*l,c=(r.height||i)*l,d=r.x==null?e.length/2-s/2:r.x*l,f=r.y==null?e.length/2-c/2:r.y*l;let p=null;if(r.excavate){let m=Math.floor(d),v=Math.floor(f),h=Math.ceil(s+d-m),g=Math.ceil(c+f-v);p={x:m,y:v,w:h,h:g}}return{x:d,y:f,h:c,w:s,excavation:p}}(function(){try{new Path2D().addPath(new Path2D)}catch{return!1}return!0})();function ixe(e){const t=e,{value:n,size:r=Q2e,level:o=Z2e,bgColor:a=J2e,fgColor:i=exe,includeMargin:l=txe,imageSettings:s}=t,c=Y2e(t,["value","size","level","bgColor","fgColor","includeMargin","imageSettings"]);let d=$d.QrCode.encodeText(n,X2e[o]).getModules();const f=l?ZD:0,p=d.length+f*2,m=axe(d,r,l,s);let v=null;s!=null&&m!=null&&(m.excavation!=null&&(d=oxe(d,m.excavation)),v=Y.createElement("image",{xlinkHref:s.src,height:m.h,width:m.w,x:m.x+f,y:m.y+f,preserveAspectRatio:"none"}));const h=rxe(d,f);return Y.createElement("svg",q2e({height:r,width:r,viewBox:`0 0 ${p} ${p}`},c),Y.createElement("path",{fill:a,d:`M0,0 h${p}v${p}H0z`,shapeRendering:"crispEdges"}),Y.createElement("path",{fill:i,d:h,shapeRendering:"crispEdges"}),v)}function lxe(){const{modal:e}=Cu.useApp(),t=Wo("devices:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(!1),[d,f]=u.useState(!1),[p,m]=u.useState(!0),[v,h]=u.useState(null),[g,y]=u.useState("waiting"),[C,b]=u.useState(""),[$,x]=u.useState(""),[S,w]=u.useState(null),[E,P]=u.useState(""),[O,M]=u.useState(""),[k,_]=u.useState({}),[I,N]=u.useState(!1),[R,D]=u.useState(!1),[T,j]=u.useState(!1),[L,z]=u.useState(!1),[B,H]=u.useState(""),[W,V]=u.useState(""),[G,Z]=u.useState([]),q=ye=>{ye(!0),setTimeout(()=>ye(!1),500)},X=u.useRef(null),oe=u.useRef(!1),te=u.useRef(null);u.useEffect(()=>(Q(),()=>{X.current&&X.current.close()}),[]);const Q=async()=>{try{const ye=await Tn("/api/v1/admin/matrix-token-status");if(!ye)return;if(!(await Cn(ye)).has_matrix_token){f(!0),a(!1);return}const pe=await Tn("/api/v1/admin/devices");if(!pe)return;if(pe.status===403||!pe.ok){f(!0);return}const Oe=await Cn(pe);if((Oe==null?void 0:Oe.error)==="Invalid server response"){f(!0);return}r(Array.isArray(Oe)?Oe:[])}catch(ye){console.error("Error loading devices:",ye),Tt.error("Failed to load devices")}finally{a(!1)}},re=async()=>{if(!C.trim()){x("Matrix token is required");return}if(!C.startsWith("mt_")){x("Token must start with mt_");return}try{const ye=await Tn("/api/v1/admin/matrix-token",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({token:C})});if(!ye)return;if(ye.ok)f(!1),b(""),x(""),Tt.success("Matrix token set successfully"),Q();else{const he=await Cn(ye);x(he.error||"Failed to set token")}}catch(ye){console.error("Error setting token:",ye),x("Failed to set token")}},ae=async ye=>{N(!0);try{const he=await Tn("/api/v1/admin/devices",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({platform:ye})});if(!he)return;if(!he.ok){if(he.status===403){l(!1),f(!0);return}const Se=await Cn(he);H(Se.error||"Failed to add device");return}const Oe=(await Cn(he)).qr_code_url;if(!Oe||Oe.includes("ticket=")&&Oe.endsWith("ticket=")){Tt.error("Failed to generate QR code"),l(!1);return}m(!1),setTimeout(()=>{ee(Oe)},2e3)}catch(he){console.error("Error creating device:",he),H("Failed to add device")}finally{N(!1)}},ee=ye=>{try{X.current&&X.current.close();const he=new WebSocket(ye);X.current=he,he.paired=!1,he.hasError=!1,he.onopen=()=>{y("waiting")},he.onmessage=pe=>{let me;try{me=JSON.parse(pe.data)}catch{return}switch(me.type){case"qr":h(me.data);break;case"paired":he.paired=!0;break;case"expired":he.hasError=!0,y("error"),Tt.error(me.message||"Pairing timed out");break;case"error":he.hasError=!0,y("error"),Tt.error(me.message||"Connection error");break}},he.onerror=()=>{console.error("[Device Linking] WebSocket error"),he.hasError=!0,y("error"),Tt.error("Connection error")},he.onclose=()=>{he.paired&&!he.hasError&&(y("connected"),Tt.success("Device added successfully"),Q(),setTimeout(()=>{l(!1),J()},2e3))}}catch(he){console.error("[Device Linking] Error connecting WebSocket:",he),y("error")}},J=()=>{m(!0),h(null),y("waiting"),N(!1),H(""),X.current&&(X.current.close(),X.current=null)},ce=(ye,he)=>{e.confirm({title:"Delete Device",content:"Are you sure you want to delete this device?",okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const pe=await Tn("/api/v1/admin/devices",{method:"DELETE",headers:{"Content-Type":"application/json"},body:JSON.stringify({device_id:ye,platform:he})});if(!pe)return;if(pe.ok)Q(),Tt.success("Device deleted successfully");else{if(pe.status===403){f(!0);return}const Oe=await Cn(pe);Tt.error(Oe.error||"Failed to delete device")}}catch(pe){console.error("Error deleting device:",pe),Tt.error("Failed to delete device")}}})},de=()=>{Z(ye=>(ye.forEach(he=>{he.previewUrl&&URL.revokeObjectURL(he.previewUrl)}),[])),c(!1)},xe=ye=>{w(ye),P(""),M(""),Z(he=>(he.forEach(pe=>{pe.previewUrl&&URL.revokeObjectURL(pe.previewUrl)}),[])),V(""),c(!0)},ue=ye=>{const he=Array.from(ye).map(pe=>({file:pe,previewUrl:pe.type.startsWith("image/")?URL.createObjectURL(pe):null}));Z(pe=>[...pe,...he])},le=ye=>{Z(he=>{const pe=he[ye];return pe!=null&&pe.previewUrl&&URL.revokeObjectURL(pe.previewUrl),he.filter((Oe,Se)=>Se!==ye)})},be=async()=>{if(!E.trim()){V("Contact number is required");return}if(!O.trim()){V("Message is required");return}try{const ye=async pe=>{if(pe){const Oe=new FormData;return Oe.append("contact",E),Oe.append("platform",S.platform),Oe.append("text",O),Oe.append("file",pe),Tn(`/api/v1/admin/devices/${S.device_id}/message`,{method:"POST",body:Oe})}return Tn(`/api/v1/admin/devices/${S.device_id}/message`,{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({contact:E,platform:S.platform,text:O})})},he=G.length>0?G.map(pe=>pe.file):[null];for(const pe of he){const Oe=await ye(pe);if(!Oe)return;if(!Oe.ok){if(Oe.status===403){de(),f(!0);return}const Se=await Cn(Oe);V(Se.error||"Failed to send message");return}}de(),Tt.success("Message queued successfully")}catch(ye){console.error("Error sending message:",ye),V("Failed to send message")}},ge=async ye=>{try{await Fw(ye),Tt.success("Copied to clipboard")}catch{Tt.error("Failed to copy")}},me=ye=>{_(he=>({...he,[ye]:!he[ye]}))},we=async()=>{const ye=await Tn("/api/v1/admin/matrix-token-status");if(!ye)return;if(!(await Cn(ye)).has_matrix_token){f(!0);return}H(""),l(!0)},Ee=[{title:"Platform",dataIndex:"platform",key:"platform",width:120,render:ye=>A.jsx(zl,{color:"blue",children:ye.toUpperCase()})},{title:"Device ID",dataIndex:"device_id",key:"device_id",render:(ye,he,pe)=>A.jsxs(xa,{size:"small",children:[A.jsx(qt,{variant:"body2",component:"code",sx:{fontFamily:'"Google Sans Mono", monospace'},children:k[`device-${pe}`]?ye:Yd(ye)}),A.jsx(jm,{title:"Toggle visibility",children:A.jsx(zi,{size:"small",onClick:()=>me(`device-${pe}`),children:k[`device-${pe}`]?A.jsx(Ud,{fontSize:"small"}):A.jsx(Kd,{fontSize:"small"})})}),A.jsx(jm,{title:"Copy",children:A.jsx(zi,{size:"small",onClick:()=>ge(ye),children:A.jsx(Wd,{fontSize:"small"})})})]})},{title:"Actions",key:"actions",width:120,align:"center",render:(ye,he)=>A.jsxs(xa,{size:"small",children:[A.jsx(jm,{title:"Send message",children:A.jsx(zi,{sx:{color:"#e1e1e1",opacity:t?1:.45},size:"small",onClick:()=>t?xe(he):Tt.info("You do not have permission to send messages. Contact admin."),children:A.jsx(X5,{})})}),A.jsx(jm,{title:"Delete",children:A.jsx(zi,{color:t?"error":"default",sx:{opacity:t?1:.45},size:"small",onClick:()=>t?ce(he.device_id,he.platform):Tt.info("You do not have permission to delete devices. Contact admin."),children:A.jsx(dy,{})})})]})}];return A.jsxs(At,{children:[A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Devices"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"View and manage all devices connected to your Matrix identity."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?we():Tt.info("You do not have permission to add devices. Contact admin."),sx:{opacity:t?1:.45},children:"Add Device"})]}),A.jsx(la,{columns:Ee,dataSource:n,loading:o,rowKey:ye=>`${ye.platform}-${ye.device_id}`,pagination:!1,locale:{emptyText:Wo("devices:read:*")?"No devices found":"You do not have access to view devices. Contact admin."}}),A.jsx(Hn,{title:"Add New Device",open:i,onCancel:()=>{oe.current?(oe.current=!1,l(!1),J()):q(j)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:T?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>{l(!1),J()},children:"Cancel"},"cancel")],width:600,children:p?A.jsxs(At,{sx:{pt:2},children:[A.jsx(qt,{color:"text.secondary",gutterBottom:!0,children:"Select the platform for your new device"}),A.jsx(T5,{container:!0,spacing:2,sx:{mt:2},children:[{platform:"wa",name:"WhatsApp",image:"/admin/whatsapp.png"},{platform:"signal",name:"Signal",image:"/admin/signal.png"},{platform:"telegram",name:"Telegram",image:"/admin/telegram.png"}].map(ye=>A.jsx(T5,{item:!0,xs:12,sm:4,children:A.jsx(Gpe,{children:A.jsx(Jpe,{onClick:()=>ae(ye.platform),disabled:I,children:A.jsxs(ome,{sx:{textAlign:"center",py:4},children:[A.jsx("img",{src:ye.image,alt:ye.name,style:{width:80,height:80,objectFit:"contain"}}),A.jsx(qt,{variant:"h6",sx:{mt:2},children:ye.name})]})})})},ye.platform))}),I&&A.jsxs(At,{sx:{textAlign:"center",mt:3},children:[A.jsx(Fd,{size:"default"}),A.jsx(qt,{color:"text.secondary",sx:{mt:1},children:"Setting up device..."})]}),B&&A.jsx(At,{sx:{mt:2},children:A.jsx(Cd,{severity:"error",children:B})})]}):A.jsxs(At,{sx:{pt:2,textAlign:"center"},children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Scan QR Code"}),A.jsx(qt,{color:"text.secondary",sx:{mb:3},children:"Use your device to scan this QR code"}),v?A.jsx(At,{sx:{display:"flex",justifyContent:"center",mb:2},children:A.jsx(ixe,{value:v,size:300})}):A.jsx(At,{sx:{py:10},children:A.jsx(Fd,{size:"large"})}),g==="waiting"&&A.jsxs(xa,{children:[A.jsx(Fd,{size:"small"}),A.jsx(qt,{children:"Waiting for device..."})]}),g==="connected"&&A.jsx(Cd,{severity:"success",children:"Device connected successfully!"}),g==="error"&&A.jsx(Cd,{severity:"error",children:"Connection failed. Please try again."})]})}),A.jsxs(Hn,{title:"Set Matrix Token",open:d,onCancel:()=>{D(!0),setTimeout(()=>D(!1),500)},closable:!1,maskClosable:!0,wrapClassName:R?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>f(!1),children:"Skip"},"skip"),A.jsx(jt,{type:"text",onClick:re,style:{color:"#4357AD"},children:"Set Token"},"set")],children:[A.jsx(qt,{color:"text.secondary",sx:{mb:2},children:"Please set your Matrix token to manage devices."}),A.jsx(wo,{size:"large",placeholder:"Matrix Token",value:C,onChange:ye=>{b(ye.target.value),x("")},status:$?"error":"",autoFocus:!0}),$&&A.jsx(qt,{color:"error",variant:"caption",sx:{mt:.5,display:"block"},children:$}),A.jsxs(qt,{color:"text.secondary",variant:"caption",sx:{mt:2,display:"block"},children:["Your token will be stored for this session and will be cleared when you log out. ",A.jsx("br",{}),"Don't have a token?"," ",A.jsx(I8,{to:"/tokens",style:{color:"#4357AD",textDecoration:"underline"},children:"Create one here"})]})]}),A.jsxs(Hn,{title:"Send Message",open:s,onCancel:()=>{oe.current?(oe.current=!1,de()):q(z)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:L?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:de,children:"Cancel"},"cancel"),A.jsx(jt,{type:"text",icon:A.jsx(X5,{style:{fontSize:16}}),onClick:be,style:{color:"#8ED462"},children:"Send"},"send")],children:[A.jsxs(At,{sx:{mt:5},children:[W&&A.jsx(Cd,{severity:"error",sx:{mb:2},children:W}),A.jsx(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:"Contact Number"}),A.jsx(U2e,{international:!0,defaultCountry:"CM",value:E,onChange:ye=>{P(ye),V("")},placeholder:"Enter phone number",autoComplete:"tel"})]}),A.jsxs(At,{sx:{mt:2},children:[A.jsx(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:"Message"}),A.jsx(wo.TextArea,{rows:4,placeholder:"Enter your message",value:O,onChange:ye=>{M(ye.target.value),V("")}})]}),A.jsxs(At,{sx:{mt:2},children:[A.jsxs(qt,{variant:"body2",color:"text.secondary",sx:{mb:1},children:["Attachments ",A.jsx("span",{style:{fontSize:11,opacity:.6},children:"(optional)"})]}),A.jsxs(At,{sx:{display:"flex",flexWrap:"wrap",gap:1},children:[G.map(({file:ye,previewUrl:he},pe)=>A.jsxs(At,{sx:{position:"relative",width:80,height:80,borderRadius:1,border:"1px solid #333",overflow:"hidden",background:"#1e1e1e",flexShrink:0},children:[he?A.jsx("img",{src:he,alt:ye.name,style:{width:"100%",height:"100%",objectFit:"cover"}}):A.jsxs(At,{sx:{display:"flex",flexDirection:"column",alignItems:"center",justifyContent:"center",height:"100%",px:.5},children:[A.jsx(_he,{style:{fontSize:22,color:"#aaa"}}),A.jsx(qt,{sx:{fontSize:9,color:"#aaa",textAlign:"center",overflow:"hidden",textOverflow:"ellipsis",whiteSpace:"nowrap",maxWidth:"100%",mt:.5},children:ye.name})]}),he&&A.jsx(At,{sx:{position:"absolute",bottom:0,left:0,right:0,background:"rgba(0,0,0,0.55)",px:.5,py:.25},children:A.jsx(qt,{sx:{fontSize:9,color:"#fff",display:"block",overflow:"hidden",textOverflow:"ellipsis",whiteSpace:"nowrap"},children:ye.name})}),A.jsx(zi,{size:"small",sx:{position:"absolute",top:2,right:2,p:0,width:18,height:18,background:"rgba(0,0,0,0.65)","&:hover":{background:"rgba(0,0,0,0.9)"}},onClick:()=>le(pe),children:A.jsx(si,{style:{fontSize:12}})})]},pe)),A.jsx(At,{onClick:()=>{var ye;return(ye=te.current)==null?void 0:ye.click()},sx:{width:80,height:80,borderRadius:1,border:"1px dashed #555",background:"#1e1e1e",display:"flex",alignItems:"center",justifyContent:"center",cursor:"pointer",flexShrink:0,color:"#666","&:hover":{borderColor:"#888",color:"#aaa"},transition:"border-color 0.15s, color 0.15s"},children:A.jsx(Jc,{style:{fontSize:28}})})]}),A.jsx("input",{ref:te,type:"file",multiple:!0,style:{display:"none"},onChange:ye=>{var he;(he=ye.target.files)!=null&&he.length&&ue(ye.target.files),ye.target.value=""}})]})]})]})}function sxe(){const{modal:e}=Cu.useApp(),t=Wo("webhooks:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(!1),[d,f]=u.useState(!1),[p,m]=u.useState(""),[v,h]=u.useState(null),[g,y]=u.useState(""),[C,b]=u.useState(!0),[$,x]=u.useState(""),[S,w]=u.useState(""),[E,P]=u.useState(!1),[O,M]=u.useState(!1),[k,_]=u.useState(!1),[I,N]=u.useState(""),[R,D]=u.useState(""),T=u.useRef(!1),j=q=>{q(!0),setTimeout(()=>q(!1),500)};u.useEffect(()=>{L()},[]);const L=async()=>{try{const q=await Tn("/api/v1/admin/matrix-token-status");if(!q)return;if(!(await Cn(q)).has_matrix_token){f(!0),a(!1);return}const oe=await Tn("/api/v1/admin/webhooks");if(!oe)return;if(!oe.ok){if(oe.status===403){f(!0);return}const Q=await Cn(oe);Tt.error(Q.error||"Failed to load webhooks"),r([]);return}const te=await Cn(oe);if((te==null?void 0:te.error)==="Invalid server response"){f(!0);return}r(Array.isArray(te)?te:[])}catch(q){console.error("Error loading webhooks:",q),Tt.error("Failed to load webhooks")}finally{a(!1)}},z=async()=>{if(!$.trim()){w("Matrix token is required");return}if(!$.startsWith("mt_")){w("Token must start with mt_");return}try{const q=await Tn("/api/v1/admin/matrix-token",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({token:$})});if(!q)return;if(q.ok)f(!1),x(""),w(""),Tt.success("Matrix token set successfully"),L();else{const X=await Cn(q);w(X.error||"Failed to set token")}}catch(q){console.error("Error setting token:",q),w("Failed to set token")}},B=async()=>{if(!p.trim()){N("Please enter a webhook URL");return}try{const q=await Tn("/api/v1/admin/webhooks",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({url:p})});if(!q)return;if(q.ok)l(!1),m(""),N(""),L(),Tt.success("Webhook added successfully");else{if(q.status===403){l(!1),f(!0);return}const X=await Cn(q);N(X.error||"Failed to add webhook")}}catch(q){console.error("Error adding webhook:",q),N("Failed to add webhook")}},H=async()=>{if(!g.trim()){D("Please enter a webhook URL");return}try{const q=await Tn(`/api/v1/admin/webhooks/${v.id}`,{method:"PUT",headers:{"Content-Type":"application/json"},body:JSON.stringify({url:g,active:C})});if(!q)return;if(q.ok)c(!1),h(null),D(""),L(),Tt.success("Webhook updated successfully");else{if(q.status===403){c(!1),f(!0);return}const X=await Cn(q);D(X.error||"Failed to update webhook")}}catch(q){console.error("Error updating webhook:",q),D("Failed to update webhook")}},W=async q=>{e.confirm({title:"Delete Webhook",content:"Are you sure you want to delete this webhook?",okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const X=await Tn(`/api/v1/admin/webhooks/${q}`,{method:"DELETE"});if(!X)return;if(X.ok)L(),Tt.success("Webhook deleted successfully");else{if(X.status===403){f(!0);return}const oe=await Cn(X);Tt.error(oe.error||"Failed to delete webhook")}}catch(X){console.error("Error deleting webhook:",X),Tt.error("Failed to delete webhook")}}})},V=q=>{h(q),y(q.url),b(q.active),D(""),c(!0)},G=async()=>{const q=await Tn("/api/v1/admin/matrix-token-status");if(!q)return;if(!(await Cn(q)).has_matrix_token){f(!0);return}m(""),N(""),l(!0)},Z=[{title:"URL",dataIndex:"url",key:"url",width:"40%",ellipsis:!0},{title:"Status",dataIndex:"active",key:"active",width:"10%",render:q=>A.jsx(zl,{color:q?"success":"error",children:q?"Active":"Inactive"})},{title:"Created",dataIndex:"created_at",key:"created_at",width:"20%",render:q=>Cc(q)},{title:"Updated",dataIndex:"updated_at",key:"updated_at",width:"20%",render:q=>Cc(q)},{title:"Actions",key:"actions",width:"10%",align:"center",render:(q,X)=>A.jsxs(xa,{size:"small",children:[A.jsx(jt,{type:"text",icon:A.jsx(I_,{style:{fontSize:18}}),onClick:()=>t?V(X):Tt.info("You do not have permission to edit webhooks. Contact admin."),style:{opacity:t?1:.45}}),A.jsx(jt,{type:"text",icon:A.jsx(dy,{style:{fontSize:18}}),onClick:()=>t?W(X.id):Tt.info("You do not have permission to delete webhooks. Contact admin."),danger:t,style:{opacity:t?1:.45}})]})}];return A.jsxs(At,{children:[A.jsx(At,{children:A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"Webhooks"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"Manage webhook URLs to receive message notifications."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?G():Tt.info("You do not have permission to add webhooks. Contact admin."),sx:{opacity:t?1:.45},children:"Add Webhook"})]})}),A.jsx(la,{columns:Z,dataSource:n,loading:o,rowKey:"id",pagination:{pageSize:10},locale:{emptyText:Wo("webhooks:read:*")?"No webhooks found":"You do not have access to view webhooks. Contact admin."}}),A.jsx(Hn,{title:"Add Webhook",open:i,onCancel:()=>{T.current?(T.current=!1,l(!1)):j(M)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{T.current=!0}}),wrapClassName:O?"modal-shake":"",footer:[A.jsx(jt,{variant:"text",onClick:()=>l(!1),children:"Cancel"},"cancel"),A.jsx(jt,{variant:"text",type:"primary",onClick:B,children:"Add Webhook"},"add")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx(wo,{placeholder:"https://example.com/webhook",value:p,onChange:q=>{m(q.target.value),N("")},onPressEnter:B,status:I?"error":""}),I&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:I}),A.jsx("div",{style:{marginTop:8,fontSize:12,color:"rgba(255, 255, 255, 0.6)"},children:"Enter a valid URL to receive webhook notifications"})]})}),A.jsx(Hn,{title:"Edit Webhook",open:s,onCancel:()=>{T.current?(T.current=!1,c(!1)):j(_)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{T.current=!0}}),wrapClassName:k?"modal-shake":"",footer:[A.jsx(jt,{onClick:()=>c(!1),children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:H,children:"Save Changes"},"save")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx(wo,{placeholder:"Webhook URL",value:g,onChange:q=>{y(q.target.value),D("")},onPressEnter:H,status:R?"error":""}),R&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:R}),A.jsxs("div",{style:{marginTop:16,display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs("div",{children:[A.jsx("div",{style:{fontSize:14},children:C?"Active":"Inactive"}),A.jsx("div",{style:{fontSize:12,color:"rgba(255,255,255,0.45)"},children:C?"Toggle off to deactivate webhook":"Toggle on to reactivate webhook"})]}),A.jsx(XS,{checked:C,onChange:b,checkedChildren:"Active",unCheckedChildren:"Inactive"})]})]})}),A.jsx(Hn,{title:"Set Matrix Token",open:d,onCancel:()=>{P(!0),setTimeout(()=>P(!1),500)},closable:!1,maskClosable:!0,wrapClassName:E?"modal-shake":"",footer:[A.jsx(jt,{type:"text",onClick:()=>f(!1),children:"Skip"},"skip"),A.jsx(jt,{type:"text",onClick:z,style:{color:"#4357AD"},children:"Set Token"},"set")],children:A.jsxs("div",{style:{marginTop:16},children:[A.jsx("div",{style:{marginBottom:8,color:"rgba(255, 255, 255, 0.6)"},children:"Please set your Matrix token to manage webhooks."}),A.jsx(wo,{placeholder:"Matrix Token",value:$,onChange:q=>{x(q.target.value),w("")},status:S?"error":"",onPressEnter:z}),S&&A.jsx("div",{style:{marginTop:4,color:"#ff4d4f",fontSize:12},children:S}),A.jsxs("div",{style:{marginTop:8,fontSize:12,color:"rgba(255, 255, 255, 0.6)"},children:["Your token will be stored for this session and will be cleared when you log out.",A.jsx("br",{}),"Don't have a token?"," ",A.jsx(I8,{to:"/tokens",style:{color:"#4357AD",textDecoration:"underline"},children:"Create one here"})]})]})})]})}function cxe(){const{modal:e}=Cu.useApp(),t=Wo("credentials:write:*"),[n,r]=u.useState([]),[o,a]=u.useState(!0),[i,l]=u.useState(!1),[s,c]=u.useState(""),[d,f]=u.useState(""),[p,m]=u.useState(!1),[v,h]=u.useState(!1),[g,y]=u.useState(""),[C,b]=u.useState(!1),[$,x]=u.useState(!1),[S,w]=u.useState(null),[E,P]=u.useState(""),[O,M]=u.useState(!1),[k,_]=u.useState(!0),[I,N]=u.useState(!1),[R,D]=u.useState(!1),[T,j]=u.useState(""),[L,z]=u.useState(""),[B,H]=u.useState(!1),[W,V]=u.useState(!1),[G,Z]=u.useState(!1),[q,X]=u.useState(!1),oe=u.useRef(!1),te=ue=>{ue(!0),setTimeout(()=>ue(!1),500)};u.useEffect(()=>{Q()},[]);const Q=async()=>{a(!0);try{const ue=await Tn("/api/v1/admin/credentials");if(!ue)return;const le=await Cn(ue);r(Array.isArray(le)?le:[])}catch(ue){console.error("Error loading credentials:",ue),Tt.error("Failed to load credentials")}finally{a(!1)}},re=async()=>{if(!s.trim()){j("Client ID is required");return}j(""),m(!0);try{const ue=await Tn("/api/v1/admin/credentials",{method:"POST",headers:{"Content-Type":"application/json"},body:JSON.stringify({client_id:s.trim(),description:d.trim()})});if(!ue)return;if(ue.ok){const le=await Cn(ue);l(!1),c(""),f(""),j(""),y(le.client_secret),b(!1),h(!0),Q()}else{const le=await Cn(ue);j(le.error||"Failed to create credential")}}catch(ue){console.error("Error creating credential:",ue),j("Failed to create credential")}finally{m(!1)}},ae=ue=>{w(ue),P(ue.description||""),M(!1),_(ue.active),z(""),x(!0)},ee=()=>{M(!0),D(!1)},J=async()=>{if(S){z(""),N(!0);try{const ue={};E!==S.description&&(ue.description=E),O&&(ue.regenerate_secret=!0),k!==S.active&&(ue.active=k);const le=await Tn(`/api/v1/admin/credentials/${S.client_id}`,{method:"PUT",headers:{"Content-Type":"application/json"},body:JSON.stringify(ue)});if(!le)return;if(le.ok){const be=await Cn(le);x(!1),w(null),z(""),Q(),Tt.success("Credential updated successfully"),be.client_secret&&(y(be.client_secret),b(!1),h(!0))}else{const be=await Cn(le);z(be.error||"Failed to update credential")}}catch(ue){console.error("Error updating credential:",ue),z("Failed to update credential")}finally{N(!1)}}},ce=ue=>{e.confirm({title:"Delete Credential",content:`Are you sure you want to permanently delete "${ue}"? This cannot be undone.`,okText:"Delete",okType:"danger",cancelText:"Cancel",onOk:async()=>{try{const le=await Tn(`/api/v1/admin/credentials/${ue}`,{method:"DELETE"});if(!le)return;if(le.ok)Q(),Tt.success("Credential deleted successfully");else{const be=await Cn(le);Tt.error(be.error||"Failed to delete credential")}}catch(le){console.error("Error deleting credential:",le),Tt.error("Failed to delete credential")}}})},de=async ue=>{try{await Fw(ue),Tt.success("Copied to clipboard")}catch{Tt.error("Failed to copy")}},xe=[{title:"Client ID",dataIndex:"client_id",key:"client_id",render:ue=>A.jsx("code",{style:{fontFamily:'"Google Sans Mono", monospace'},children:ue})},{title:"Role",dataIndex:"role",key:"role",render:ue=>A.jsx(zl,{color:ue==="super_admin"?"gold":"blue",children:ue==="super_admin"?"Super Admin":"User"})},{title:"Scopes",dataIndex:"scopes",key:"scopes",render:ue=>Array.isArray(ue)&&ue.length>0?A.jsx(xa,{size:[4,4],wrap:!0,children:ue.map(le=>A.jsx(zl,{style:{fontFamily:'"Google Sans Mono", monospace',fontSize:11},children:le},le))}):"-"},{title:"Description",dataIndex:"description",key:"description",render:ue=>ue||A.jsx(qt,{variant:"caption",color:"text.secondary",children:"-"})},{title:"Status",dataIndex:"active",key:"active",render:ue=>A.jsx(zl,{color:ue?"success":"error",children:ue?"Active":"Inactive"})},{title:"Created",dataIndex:"created_at",key:"created_at",render:ue=>Cc(ue)},{title:"Actions",key:"actions",align:"center",render:(ue,le)=>A.jsxs(xa,{children:[A.jsx(jt,{type:"text",icon:A.jsx(I_,{style:{fontSize:18}}),onClick:()=>t?ae(le):Tt.info("You do not have permission to edit credentials. Contact admin."),style:{opacity:t?1:.45}}),A.jsx(jt,{type:"text",danger:t,icon:A.jsx(dy,{style:{fontSize:18}}),onClick:()=>t?ce(le.client_id):Tt.info("You do not have permission to delete credentials. Contact admin."),style:{opacity:t?1:.45}})]})}];return A.jsxs(At,{children:[A.jsxs(At,{sx:{mb:4,display:"flex",justifyContent:"space-between",alignItems:"flex-start"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"h6",gutterBottom:!0,children:"API Credentials"}),A.jsx(qt,{color:"text.secondary",paragraph:!0,children:"Manage API credentials (client ID / client secret pairs) used to authenticate API requests."})]}),A.jsx(Zc,{variant:"contained",startIcon:A.jsx(Jc,{}),onClick:()=>t?(c(""),f(""),j(""),l(!0)):Tt.info("You do not have permission to create credentials. Contact admin."),sx:{opacity:t?1:.45},children:"New Credential"})]}),A.jsx(la,{dataSource:n,columns:xe,rowKey:"client_id",loading:o,pagination:{pageSize:10},scroll:{x:!0},locale:{emptyText:Wo("credentials:read:*")?"No credentials found":"You do not have access to view credentials. Contact admin."}}),A.jsx(Hn,{title:"Create Credential",open:i,onCancel:()=>{oe.current?(oe.current=!1,l(!1)):te(H)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:B?"modal-shake":"",destroyOnHidden:!0,footer:[A.jsx(jt,{onClick:()=>l(!1),disabled:p,children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:re,loading:p,children:"Create"},"create")],children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[T&&A.jsx(ni,{type:"error",showIcon:!0,message:T}),A.jsxs(At,{children:[A.jsxs(qt,{variant:"body2",gutterBottom:!0,children:["Client ID ",A.jsx("span",{style:{color:"red"},children:"*"})]}),A.jsx(wo,{placeholder:"e.g. my-app",value:s,onChange:ue=>{c(ue.target.value),j("")},onPressEnter:re,autoFocus:!0})]}),A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",gutterBottom:!0,children:"Description"}),A.jsx(wo,{placeholder:"Optional description",value:d,onChange:ue=>f(ue.target.value)})]})]})}),A.jsx(Hn,{title:`Edit — ${S==null?void 0:S.client_id}`,open:$,onCancel:()=>{oe.current?(oe.current=!1,x(!1)):te(V)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:W?"modal-shake":"",destroyOnHidden:!0,footer:[A.jsx(jt,{onClick:()=>x(!1),disabled:I,children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",onClick:J,loading:I,children:"Save"},"save")],children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[L&&A.jsx(ni,{type:"error",showIcon:!0,message:L}),A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",gutterBottom:!0,children:"Description"}),A.jsx(wo,{placeholder:"Optional description",value:E,onChange:ue=>{P(ue.target.value),z("")}})]}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",children:"Regenerate client secret"}),A.jsx(qt,{variant:"caption",color:"text.secondary",children:"Issue a new secret and invalidate the current one"})]}),O?A.jsx(ni,{type:"warning",showIcon:!0,banner:!0,message:"Will regenerate on save",style:{padding:"2px 8px",fontSize:12}}):A.jsx(jt,{icon:A.jsx(Dhe,{style:{fontSize:16}}),onClick:()=>D(!0),children:"Regenerate"})]}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",justifyContent:"space-between"},children:[A.jsxs(At,{children:[A.jsx(qt,{variant:"body2",children:k?"Active":"Inactive"}),A.jsx(qt,{variant:"caption",color:"text.secondary",children:k?"Toggle off to deactivate and revoke API access":"Toggle on to reactivate and restore API access"})]}),A.jsx(XS,{checked:k,onChange:_,checkedChildren:"Active",unCheckedChildren:"Inactive"})]})]})}),A.jsx(Hn,{title:"Regenerate Client Secret?",open:R,onCancel:()=>{oe.current?(oe.current=!1,D(!1)):te(Z)},maskClosable:!0,closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),wrapClassName:G?"modal-shake":"",zIndex:1100,width:420,footer:[A.jsx(jt,{onClick:()=>D(!1),children:"Cancel"},"cancel"),A.jsx(jt,{type:"primary",danger:!0,onClick:ee,children:"Yes, regenerate"},"regenerate")],children:A.jsx(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:1},children:A.jsx(ni,{type:"warning",showIcon:!0,message:"This will immediately invalidate the current client secret.",description:"Any integrations using the old secret will stop working. The new secret will be shown once after saving — make sure to copy it."})})}),A.jsx(Hn,{title:"Client Secret",open:v,onCancel:()=>{oe.current?(oe.current=!1,h(!1)):te(X)},closeIcon:A.jsx(si,{onClick:()=>{oe.current=!0}}),footer:[A.jsx(jt,{type:"primary",onClick:()=>h(!1),children:"I have saved!"},"close")],maskClosable:!0,wrapClassName:q?"modal-shake":"",destroyOnHidden:!0,children:A.jsxs(At,{sx:{display:"flex",flexDirection:"column",gap:2,mt:2},children:[A.jsx(ni,{type:"warning",showIcon:!0,message:"Save this secret now — it will not be shown again."}),A.jsxs(At,{sx:{display:"flex",alignItems:"center",background:"#1e1e1e",border:"1px solid #333",borderRadius:1,px:1.5,py:.75,gap:1},children:[A.jsx("code",{style:{flex:1,wordBreak:"break-all",fontFamily:'"Google Sans Mono", monospace',fontSize:13,color:"#e1e1e1",background:"transparent"},children:C?g:Yd(g)}),A.jsx(jt,{type:"text",size:"small",style:{color:"#aaa",flexShrink:0},icon:C?A.jsx(Ud,{style:{fontSize:16}}):A.jsx(Kd,{style:{fontSize:16}}),onClick:()=>b(ue=>!ue)}),A.jsx(jt,{type:"text",size:"small",style:{color:"#aaa",flexShrink:0},icon:A.jsx(Wd,{style:{fontSize:16}}),onClick:()=>de(g)})]})]})})]})}function uxe(){return A.jsxs($L,{children:[A.jsx(Ti,{path:"/login",element:A.jsx(Mye,{})}),A.jsxs(Ti,{path:"/",element:A.jsx(khe,{children:A.jsx(oye,{})}),children:[A.jsx(Ti,{index:!0,element:A.jsx(O8,{to:"/tokens",replace:!0})}),A.jsx(Ti,{path:"tokens",element:A.jsx(Tye,{})}),A.jsx(Ti,{path:"devices",element:A.jsx(lxe,{})}),A.jsx(Ti,{path:"webhooks",element:A.jsx(sxe,{})}),A.jsx(Ti,{path:"credentials",element:A.jsx(cxe,{})})]})]})}const dxe=tS({palette:{mode:"dark",primary:{main:"#8ED462"},secondary:{main:"#f50057"},background:{default:"#0a0a0a",paper:"#181818"}},typography:{fontFamily:'"Google Sans", -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif',fontWeightRegular:400,fontWeightMedium:500,fontWeightBold:700,code:{fontFamily:'"Google Sans Mono", Monaco, Consolas, "Courier New", monospace'}},components:{MuiCssBaseline:{styleOverrides:{body:{scrollbarColor:"#6b6b6b #2b2b2b","&::-webkit-scrollbar, & *::-webkit-scrollbar":{width:8},"&::-webkit-scrollbar-thumb, & *::-webkit-scrollbar-thumb":{borderRadius:8,backgroundColor:"#6b6b6b",minHeight:24},"&::-webkit-scrollbar-track, & *::-webkit-scrollbar-track":{backgroundColor:"#2b2b2b"}}}},MuiButton:{styleOverrides:{root:{textTransform:"none"}}}}}),fxe={token:{colorPrimary:"#8ED462",colorBgContainer:"#0f0f0f",colorBgElevated:"#1a1a1a",colorBorder:"rgba(255, 255, 255, 0.08)",colorText:"rgba(255, 255, 255, 0.87)",colorTextSecondary:"rgba(255, 255, 255, 0.6)",colorTextTertiary:"rgba(255, 255, 255, 0.38)",colorBgLayout:"#0a0a0a",fontFamily:'"Google Sans", "Roboto", "Helvetica", "Arial", sans-serif',fontSize:14,borderRadius:4},algorithm:"dark",components:{Table:{headerBg:"#0f0f0f",headerColor:"rgba(255, 255, 255, 0.87)",rowHoverBg:"rgba(255, 255, 255, 0.05)",borderColor:"rgba(255, 255, 255, 0.08)",bodySortBg:"#0f0f0f"},Modal:{contentBg:"#1f1f1f",headerBg:"#1f1f1f"},Card:{colorBgContainer:"#0f0f0f"}}};pb.createRoot(document.getElementById("root")).render(A.jsx(Y.StrictMode,{children:A.jsx(IL,{basename:"/admin",children:A.jsx(GV,{theme:dxe,children:A.jsxs(Ra,{theme:{...fxe,algorithm:Fue.darkAlgorithm},children:[A.jsx(QV,{}),A.jsx(Cu,{children:A.jsx(uxe,{})})]})})})}));
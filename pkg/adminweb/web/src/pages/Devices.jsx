@@ -174,7 +174,7 @@ export default function Devices() {
 
       if (
         !qrCodeUrl ||
-        (qrCodeUrl.includes("token=") && qrCodeUrl.endsWith("token="))
+        (qrCodeUrl.includes("ticket=") && qrCodeUrl.endsWith("ticket="))
       ) {
         message.error("Failed to generate QR code");
         setAddDeviceDialogOpen(false);
@@ -202,7 +202,7 @@ export default function Devices() {
 
       const ws = new WebSocket(qrCodeUrl);
       wsRef.current = ws;
-      ws.receivedData = false;
+      ws.paired = false;
       ws.hasError = false;
 
       ws.onopen = () => {
@@ -210,13 +210,32 @@ export default function Devices() {
       };
 
       ws.onmessage = (event) => {
-        if (event.data.startsWith("Error:")) {
-          ws.hasError = true;
-          setConnectionStatus("error");
-          message.error(event.data);
-        } else {
-          ws.receivedData = true;
-          setQrCodeData(event.data);
+        let msg;
+        try {
+          msg = JSON.parse(event.data);
+        } catch {
+          return;
+        }
+
+        switch (msg.type) {
+          case "qr":
+            setQrCodeData(msg.data);
+            break;
+          case "paired":
+            ws.paired = true;
+            break;
+          case "expired":
+            ws.hasError = true;
+            setConnectionStatus("error");
+            message.error(msg.message || "Pairing timed out");
+            break;
+          case "error":
+            ws.hasError = true;
+            setConnectionStatus("error");
+            message.error(msg.message || "Connection error");
+            break;
+          default:
+            break;
         }
       };
 
@@ -228,7 +247,7 @@ export default function Devices() {
       };
 
       ws.onclose = () => {
-        if (ws.receivedData && !ws.hasError) {
+        if (ws.paired && !ws.hasError) {
           setConnectionStatus("connected");
           message.success("Device added successfully");
           loadDevices();
@@ -0,0 +1,132 @@
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"interface-api/pkg/throttler"
+)
+
+var (
+	ErrMissingExtension = errors.New("uploaded file must have a file extension")
+	ErrFileTooLarge     = errors.New("uploaded file exceeds the maximum size for this platform")
+	ErrUnsupportedType  = errors.New("uploaded file type is not supported for this platform")
+	ErrTypeMismatch     = errors.New("uploaded file content does not match its extension")
+)
+
+const sniffLength = 512
+
+type Validator struct {
+	configs map[string]PlatformConfig
+}
+
+func New() *Validator {
+	return NewWithConfigs(platformConfigs(throttler.DefaultPlatformConfigs))
+}
+
+func NewWithConfigs(configs map[string]PlatformConfig) *Validator {
+	return &Validator{
+		configs: configs,
+	}
+}
+
+func (v *Validator) configFor(platform string) PlatformConfig {
+	config, exists := v.configs[platform]
+	if !exists {
+		config = v.configs["default"]
+	}
+	return config
+}
+
+// MaxSize returns the maximum accepted file size in bytes for the platform
+func (v *Validator) MaxSize(platform string) int64 {
+	return v.configFor(platform).MaxSize
+}
+
+// MaxUploadSize returns the largest file size accepted by any platform
+func (v *Validator) MaxUploadSize() int64 {
+	var maxSize int64
+	for _, config := range v.configs {
+		if config.MaxSize > maxSize {
+			maxSize = config.MaxSize
+		}
+	}
+	return maxSize
+}
+
+// Validate checks an uploaded file against the platform rules and returns its
+// normalised extension. The sniffed content type must be one of the types
+// accepted for the extension.
+func (v *Validator) Validate(platform, filename string, content []byte) (string, error) {
+	extension := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	if extension == "" {
+		return "", ErrMissingExtension
+	}
+
+	config := v.configFor(platform)
+
+	if config.MaxSize > 0 && int64(len(content)) > config.MaxSize {
+		return "", fmt.Errorf("%w (%d bytes > %d bytes)", ErrFileTooLarge, len(content), config.MaxSize)
+	}
+
+	acceptedTypes, ok := config.AllowedTypes[extension]
+	if !ok {
+		return "", fmt.Errorf("%w: .%s", ErrUnsupportedType, extension)
+	}
+
+	sniffed := DetectContentType(content)
+	if !slices.Contains(acceptedTypes, sniffed) {
+		return "", fmt.Errorf("%w: .%s detected as %s", ErrTypeMismatch, extension, sniffed)
+	}
+
+	return extension, nil
+}
+
+// DetectContentType sniffs the media type of the content without parameters.
+// On top of the net/http signatures it recognises OLE2 compound files (legacy
+// Office documents) and MP3 streams that start with a frame instead of an ID3
+// tag.
+func DetectContentType(content []byte) string {
+	head := content
+	if len(head) > sniffLength {
+		head = head[:sniffLength]
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	if mediaType != "application/octet-stream" {
+		return mediaType
+	}
+
+	switch {
+	case bytes.HasPrefix(head, oleSignature):
+		return "application/x-ole-storage"
+	case isMPEGAudioFrame(head):
+		return "audio/mpeg"
+	}
+	return mediaType
+}
+
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// isMPEGAudioFrame reports whether head starts with a valid MPEG audio frame
+// header: an 11 bit frame sync followed by a known version, layer, bitrate
+// and sample rate.
+func isMPEGAudioFrame(head []byte) bool {
+	if len(head) < 4 || head[0] != 0xFF || head[1]&0xE0 != 0xE0 {
+		return false
+	}
+	version := (head[1] >> 3) & 0x03
+	layer := (head[1] >> 1) & 0x03
+	bitrate := head[2] >> 4
+	sampleRate := (head[2] >> 2) & 0x03
+	return version != 0x01 && layer != 0x00 && bitrate != 0x00 && bitrate != 0x0F && sampleRate != 0x03
+}
@@ -0,0 +1,151 @@
+package attachment
+
+import (
+	"errors"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+func TestValidator_Validate(t *testing.T) {
+	configs := map[string]PlatformConfig{
+		"default": {
+			MaxSize: 64,
+			AllowedTypes: map[string][]string{
+				"png": {"image/png"},
+				"txt": {"text/plain"},
+			},
+		},
+		"small": {
+			MaxSize: 8,
+			AllowedTypes: map[string][]string{
+				"png": {"image/png"},
+			},
+		},
+	}
+
+	validator := NewWithConfigs(configs)
+
+	tests := []struct {
+		name        string
+		platform    string
+		filename    string
+		content     []byte
+		wantExt     string
+		expectedErr error
+	}{
+		{
+			name:     "valid png",
+			platform: "wa",
+			filename: "photo.PNG",
+			content:  pngHeader,
+			wantExt:  "png",
+		},
+		{
+			name:     "valid text",
+			platform: "wa",
+			filename: "notes.txt",
+			content:  []byte("hello world"),
+			wantExt:  "txt",
+		},
+		{
+			name:        "missing extension",
+			platform:    "wa",
+			filename:    "photo",
+			content:     pngHeader,
+			expectedErr: ErrMissingExtension,
+		},
+		{
+			name:        "unsupported extension",
+			platform:    "wa",
+			filename:    "script.exe",
+			content:     []byte("MZ"),
+			expectedErr: ErrUnsupportedType,
+		},
+		{
+			name:        "content does not match extension",
+			platform:    "wa",
+			filename:    "photo.png",
+			content:     []byte("plain text pretending to be an image"),
+			expectedErr: ErrTypeMismatch,
+		},
+		{
+			name:        "platform size limit",
+			platform:    "small",
+			filename:    "photo.png",
+			content:     pngHeader,
+			expectedErr: ErrFileTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ext, err := validator.Validate(tt.platform, tt.filename, tt.content)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Validate() error = %v, expectedErr %v", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+			if ext != tt.wantExt {
+				t.Errorf("Validate() extension = %s, want %s", ext, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestValidator_MaxUploadSize(t *testing.T) {
+	validator := NewWithConfigs(map[string]PlatformConfig{
+		"default": {MaxSize: 10},
+		"wa":      {MaxSize: 30},
+		"signal":  {MaxSize: 20},
+	})
+
+	if got := validator.MaxUploadSize(); got != 30 {
+		t.Errorf("MaxUploadSize() = %d, want 30", got)
+	}
+
+	if got := validator.MaxSize("unknown"); got != 10 {
+		t.Errorf("MaxSize() for unknown platform = %d, want default 10", got)
+	}
+}
+
+func TestDetectContentType_LegacyFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"ole2 document", []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1\x00\x00"), "application/x-ole-storage"},
+		{"mp3 frame", []byte("\xFF\xFB\x90\x64\x00\x00"), "audio/mpeg"},
+		{"mp3 with id3 tag", []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), "audio/mpeg"},
+		{"invalid frame bitrate", []byte("\xFF\xFB\xF0\x64\x00\x00"), "application/octet-stream"},
+		{"renamed binary", []byte("MZ\x90\x00\x03\x00\x00\x00"), "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectContentType(tt.content); got != tt.want {
+				t.Errorf("DetectContentType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_RejectsRenamedBinaries(t *testing.T) {
+	validator := New()
+	binary := []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00")
+
+	for _, filename := range []string{"song.mp3", "report.doc", "sheet.xls", "slides.ppt"} {
+		if _, err := validator.Validate("wa", filename, binary); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Validate(%s) error = %v, want ErrTypeMismatch", filename, err)
+		}
+	}
+
+	if got := validator.MaxSize("telegram"); got != 50<<20 {
+		t.Errorf("MaxSize(telegram) = %d, want 50 MiB", got)
+	}
+}
@@ -0,0 +1,67 @@
+package attachment
+
+import "interface-api/pkg/throttler"
+
+type PlatformConfig struct {
+	MaxSize      int64               // Maximum file size in bytes
+	AllowedTypes map[string][]string // File extension -> accepted sniffed content types
+}
+
+var imageTypes = map[string][]string{
+	"jpg":  {"image/jpeg"},
+	"jpeg": {"image/jpeg"},
+	"png":  {"image/png"},
+	"gif":  {"image/gif"},
+	"webp": {"image/webp"},
+}
+
+var audioTypes = map[string][]string{
+	"mp3":  {"audio/mpeg"},
+	"ogg":  {"application/ogg", "audio/ogg"},
+	"opus": {"application/ogg", "audio/ogg"},
+	"wav":  {"audio/wave"},
+}
+
+var videoTypes = map[string][]string{
+	"mp4":  {"video/mp4"},
+	"webm": {"video/webm"},
+	"avi":  {"video/avi"},
+}
+
+var documentTypes = map[string][]string{
+	"pdf":  {"application/pdf"},
+	"txt":  {"text/plain"},
+	"csv":  {"text/plain"},
+	"zip":  {"application/zip"},
+	"docx": {"application/zip"},
+	"xlsx": {"application/zip"},
+	"pptx": {"application/zip"},
+	"doc":  {"application/x-ole-storage"},
+	"xls":  {"application/x-ole-storage"},
+	"ppt":  {"application/x-ole-storage"},
+}
+
+func mergeTypes(groups ...map[string][]string) map[string][]string {
+	merged := make(map[string][]string)
+	for _, group := range groups {
+		for ext, types := range group {
+			merged[ext] = types
+		}
+	}
+	return merged
+}
+
+var defaultAllowedTypes = mergeTypes(imageTypes, audioTypes, videoTypes, documentTypes)
+
+// platformConfigs takes each platform's size limit from the throttler's
+// platform configs, so per-platform limits live in one place
+func platformConfigs(throttlerConfigs map[string]throttler.PlatformConfig) map[string]PlatformConfig {
+	configs := make(map[string]PlatformConfig, len(throttlerConfigs))
+	for platform, config := range throttlerConfigs {
+		configs[platform] = PlatformConfig{
+			MaxSize:      config.MaxAttachmentSize,
+			AllowedTypes: defaultAllowedTypes,
+		}
+	}
+	return configs
+}
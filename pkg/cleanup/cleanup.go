@@ -11,6 +11,9 @@ import (
 	"interface-api/internal/database"
 	"interface-api/internal/database/models"
 	"interface-api/pkg/logger"
+	"interface-api/pkg/provisioning"
+	"interface-api/pkg/tokenrevoke"
+	"interface-api/pkg/webhookworker"
 )
 
 type CleanupWorker struct {
@@ -19,6 +22,8 @@ type CleanupWorker struct {
 	wg                  sync.WaitGroup
 	db                  database.Service
 	matrixTokenInterval time.Duration
+	expiryWarning       time.Duration
+	provisionStaleAfter time.Duration
 }
 
 func New() *CleanupWorker {
@@ -29,6 +34,20 @@ func New() *CleanupWorker {
 		}
 	}
 
+	expiryWarningDays := 7
+	if days := os.Getenv("TOKEN_EXPIRY_WARNING_DAYS"); days != "" {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			expiryWarningDays = n
+		}
+	}
+
+	provisionStaleMinutes := 15
+	if minutes := os.Getenv("TOKEN_PROVISION_STALE_MINUTES"); minutes != "" {
+		if n, err := strconv.Atoi(minutes); err == nil && n > 0 {
+			provisionStaleMinutes = n
+		}
+	}
+
 	db := database.New()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -37,6 +56,8 @@ func New() *CleanupWorker {
 		cancel:              cancel,
 		db:                  db,
 		matrixTokenInterval: time.Duration(matrixTokenIntervalMinutes) * time.Minute,
+		expiryWarning:       time.Duration(expiryWarningDays) * 24 * time.Hour,
+		provisionStaleAfter: time.Duration(provisionStaleMinutes) * time.Minute,
 	}
 }
 
@@ -65,23 +86,146 @@ func (cw *CleanupWorker) runMatrixTokenCleanup() {
 	ticker := time.NewTicker(cw.matrixTokenInterval)
 	defer ticker.Stop()
 
+	cw.notifyExpiringMatrixTokens()
 	cw.cleanupMatrixTokens()
+	cw.cleanupWSTickets()
+	cw.cleanupAdminSessions()
+	cw.cleanupSignatureNonces()
+	cw.cleanupLoginLockouts()
+	cw.reconcileTokenProvisions()
+	cw.retryTokenRevocations()
 
 	for {
 		select {
 		case <-cw.ctx.Done():
 			return
 		case <-ticker.C:
+			cw.notifyExpiringMatrixTokens()
 			cw.cleanupMatrixTokens()
+			cw.cleanupWSTickets()
+			cw.cleanupAdminSessions()
+			cw.cleanupSignatureNonces()
+			cw.cleanupLoginLockouts()
+			cw.reconcileTokenProvisions()
+			cw.retryTokenRevocations()
+		}
+	}
+}
+
+// notifyExpiringMatrixTokens emits a token.expiring event once for every token
+// entering the warning window.
+func (cw *CleanupWorker) notifyExpiringMatrixTokens() {
+	if cw.expiryWarning <= 0 {
+		return
+	}
+
+	identities, err := models.FindExpiringMatrixIdentities(cw.db.DB(), time.Now().UTC().Add(cw.expiryWarning))
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find expiring matrix tokens: %v", err))
+		return
+	}
+
+	for _, identity := range identities {
+		logger.Warn(fmt.Sprintf("Matrix token %d expires at %s", identity.ID, identity.ExpiresAt.Format(time.RFC3339)))
+		cw.publishTokenEvent(webhookworker.EventTokenExpiring, identity)
+
+		if err := models.MarkMatrixIdentityExpiryNotified(cw.db.DB(), identity.ID); err != nil {
+			logger.Error(fmt.Sprintf("Failed to record expiry notification: %v", err))
 		}
 	}
 }
 
+// cleanupMatrixTokens deletes expired tokens, emitting token.expired for each
+// before its webhooks are removed along with it, and then revokes the Matrix
+// and MAS resources no remaining token uses. Tokens retired by a rotation are
+// deleted without an event, since their webhooks belong to the new token.
 func (cw *CleanupWorker) cleanupMatrixTokens() {
-	result := cw.db.DB().Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now().UTC()).Delete(&models.MatrixIdentity{})
-	if result.Error != nil {
-		logger.Error(fmt.Sprintf("Failed to cleanup expired matrix tokens: %v", result.Error))
-	} else if result.RowsAffected > 0 {
-		logger.Info(fmt.Sprintf("Cleaned up %d expired matrix token(s)", result.RowsAffected))
+	identities, err := models.FindExpiredMatrixIdentities(cw.db.DB())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to cleanup expired matrix tokens: %v", err))
+		return
+	}
+
+	var deleted int
+	for _, identity := range identities {
+		if identity.RotatedAt == nil {
+			cw.publishTokenEvent(webhookworker.EventTokenExpired, identity)
+		}
+
+		if err := cw.db.DB().Delete(&models.MatrixIdentity{}, identity.ID).Error; err != nil {
+			logger.Error(fmt.Sprintf("Failed to delete expired matrix token: %v", err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("Matrix token %d expired and was deleted", identity.ID))
+		deleted++
+
+		if err := tokenrevoke.Revoke(cw.db.DB(), &identity); err != nil {
+			logger.Warn(fmt.Sprintf("Matrix token %d deleted but teardown was incomplete and will be retried: %v", identity.ID, err))
+		}
+	}
+
+	if deleted > 0 {
+		logger.Info(fmt.Sprintf("Cleaned up %d expired matrix token(s)", deleted))
+	}
+}
+
+func (cw *CleanupWorker) publishTokenEvent(eventType string, identity models.MatrixIdentity) {
+	event := webhookworker.NewEvent(eventType, webhookworker.TokenEventData{
+		TokenID:   identity.ID,
+		Name:      identity.Name,
+		Labels:    identity.Labels,
+		ExpiresAt: *identity.ExpiresAt,
+	})
+
+	if err := webhookworker.PublishEvent(cw.db.DB(), identity.MatrixUsername, event); err != nil {
+		logger.Error(fmt.Sprintf("Failed to publish %s event: %v", eventType, err))
+	}
+}
+
+// reconcileTokenProvisions releases the MAS and Matrix resources of token
+// provisions that failed or were interrupted.
+func (cw *CleanupWorker) reconcileTokenProvisions() {
+	provisioning.Reconcile(cw.db.DB(), time.Now().UTC().Add(-cw.provisionStaleAfter))
+}
+
+// retryTokenRevocations releases the Matrix and MAS resources of deleted
+// tokens whose teardown failed.
+func (cw *CleanupWorker) retryTokenRevocations() {
+	tokenrevoke.Retry(cw.db.DB())
+}
+
+func (cw *CleanupWorker) cleanupWSTickets() {
+	count, err := models.DeleteExpiredWSTickets(cw.db.DB())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to cleanup expired WebSocket tickets: %v", err))
+	} else if count > 0 {
+		logger.Info(fmt.Sprintf("Cleaned up %d expired WebSocket ticket(s)", count))
+	}
+}
+
+func (cw *CleanupWorker) cleanupAdminSessions() {
+	count, err := models.DeleteExpiredAdminSessions(cw.db.DB())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to cleanup expired admin sessions: %v", err))
+	} else if count > 0 {
+		logger.Info(fmt.Sprintf("Cleaned up %d expired admin session(s)", count))
+	}
+}
+
+func (cw *CleanupWorker) cleanupSignatureNonces() {
+	count, err := models.DeleteExpiredSignatureNonces(cw.db.DB())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to cleanup expired signature nonces: %v", err))
+	} else if count > 0 {
+		logger.Info(fmt.Sprintf("Cleaned up %d expired signature nonce(s)", count))
+	}
+}
+
+func (cw *CleanupWorker) cleanupLoginLockouts() {
+	count, err := models.DeleteExpiredLoginLockouts(cw.db.DB())
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to cleanup expired login lockouts: %v", err))
+	} else if count > 0 {
+		logger.Info(fmt.Sprintf("Cleaned up %d expired login lockout(s)", count))
 	}
 }
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Setenv("HASH_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	plaintext := []byte("signing secret")
+	ciphertext, err := Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Error("Encrypt() output contains the plaintext")
+	}
+
+	again, err := Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Equal(ciphertext, again) {
+		t.Error("Encrypt() should use a fresh nonce each time")
+	}
+
+	decrypted, err := Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", decrypted, plaintext)
+	}
+}
+
+func TestDecrypt_Tampered(t *testing.T) {
+	t.Setenv("HASH_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+
+	ciphertext, err := Encrypt([]byte("signing secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	if _, err := Decrypt(ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Decrypt(tampered) error = %v, want ErrInvalidCiphertext", err)
+	}
+
+	if _, err := Decrypt([]byte("short")); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Decrypt(short) error = %v, want ErrInvalidCiphertext", err)
+	}
+}
+
+func TestDecrypt_WrongKey(t *testing.T) {
+	t.Setenv("HASH_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	ciphertext, err := Encrypt([]byte("signing secret"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	t.Setenv("HASH_KEY", base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+	if _, err := Decrypt(ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Decrypt() with another key error = %v, want ErrInvalidCiphertext", err)
+	}
+}
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"fmt"
 	"os"
 	"strconv"
@@ -67,3 +69,38 @@ func HashPassword(password string) (string, error) {
 func VerifyPassword(password, hash string) (bool, error) {
 	return argon2id.ComparePasswordAndHash(password, hash)
 }
+
+// argon2idPrefix marks secrets hashed with HashSecret; any other stored value
+// is a legacy HMAC from Hash.
+const argon2idPrefix = "$argon2id$"
+
+// HashSecret hashes a client secret with Argon2id for storage
+func HashSecret(secret string) ([]byte, error) {
+	hash, err := HashPassword(secret)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(hash), nil
+}
+
+// VerifySecret checks a secret against a stored hash, which is either Argon2id
+// or a legacy HMAC. needsRehash reports a match against a legacy hash or an
+// Argon2id hash made with parameters other than the configured ones, which
+// the caller should replace with HashSecret.
+func VerifySecret(secret string, stored []byte) (match bool, needsRehash bool, err error) {
+	if !bytes.HasPrefix(stored, []byte(argon2idPrefix)) {
+		legacy, err := Hash(secret)
+		if err != nil {
+			return false, false, err
+		}
+		match := subtle.ConstantTimeCompare(legacy, stored) == 1
+		return match, match, nil
+	}
+
+	match, params, err := argon2id.CheckHash(secret, string(stored))
+	if err != nil || !match {
+		return false, false, err
+	}
+
+	return true, *params != *getArgon2Params(), nil
+}
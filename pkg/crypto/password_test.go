@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func setupSecretEnv(t *testing.T) {
+	t.Setenv("HASH_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+}
+
+func TestVerifySecret_Argon2id(t *testing.T) {
+	setupSecretEnv(t)
+
+	stored, err := HashSecret("s3cret")
+	if err != nil {
+		t.Fatalf("HashSecret() error = %v", err)
+	}
+
+	match, needsRehash, err := VerifySecret("s3cret", stored)
+	if err != nil || !match || needsRehash {
+		t.Errorf("VerifySecret() = %v, %v, %v; want true, false, nil", match, needsRehash, err)
+	}
+
+	match, needsRehash, err = VerifySecret("wrong", stored)
+	if err != nil || match || needsRehash {
+		t.Errorf("VerifySecret(wrong) = %v, %v, %v; want false, false, nil", match, needsRehash, err)
+	}
+}
+
+func TestVerifySecret_Legacy(t *testing.T) {
+	setupSecretEnv(t)
+
+	stored, err := Hash("s3cret")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	match, needsRehash, err := VerifySecret("s3cret", stored)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("VerifySecret() = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+
+	match, needsRehash, err = VerifySecret("wrong", stored)
+	if err != nil || match || needsRehash {
+		t.Errorf("VerifySecret(wrong) = %v, %v, %v; want false, false, nil", match, needsRehash, err)
+	}
+}
+
+func TestVerifySecret_OutdatedParams(t *testing.T) {
+	setupSecretEnv(t)
+
+	stored, err := HashSecret("s3cret")
+	if err != nil {
+		t.Fatalf("HashSecret() error = %v", err)
+	}
+
+	t.Setenv("ARGON2_ITERATIONS", "2")
+
+	match, needsRehash, err := VerifySecret("s3cret", stored)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("VerifySecret() = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+}
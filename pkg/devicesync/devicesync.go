@@ -0,0 +1,157 @@
+package devicesync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"interface-api/internal/database"
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/matrixclient"
+
+	"gorm.io/gorm"
+)
+
+type DeviceSyncWorker struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	db       database.Service
+	interval time.Duration
+}
+
+func New(db database.Service) *DeviceSyncWorker {
+	interval := 5 * time.Minute
+	if seconds := os.Getenv("DEVICE_SYNC_INTERVAL_SECONDS"); seconds != "" {
+		if n, err := strconv.Atoi(seconds); err == nil && n > 0 {
+			interval = time.Duration(n) * time.Second
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DeviceSyncWorker{
+		ctx:      ctx,
+		cancel:   cancel,
+		db:       db,
+		interval: interval,
+	}
+}
+
+func IsEnabled() bool {
+	return os.Getenv("DEVICE_SYNC_ENABLED") != "false"
+}
+
+func (w *DeviceSyncWorker) Start() {
+	logger.Info(fmt.Sprintf("Starting device sync worker - interval: %v", w.interval))
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+}
+
+func (w *DeviceSyncWorker) Stop() {
+	logger.Info("Stopping device sync worker")
+	w.cancel()
+	w.wg.Wait()
+	logger.Info("Device sync worker stopped")
+}
+
+func (w *DeviceSyncWorker) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("Device sync worker panic: %v\n%s", r, debug.Stack()))
+		}
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.syncAll()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.syncAll()
+		}
+	}
+}
+
+func (w *DeviceSyncWorker) syncAll() {
+	matrixClient, err := matrixclient.New()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Device sync: Matrix client initialization failed: %v", err))
+		return
+	}
+
+	var usernames []string
+	if err := w.db.DB().Model(&models.MatrixIdentity{}).Distinct().Pluck("matrix_username", &usernames).Error; err != nil {
+		logger.Error(fmt.Sprintf("Device sync: Failed to fetch matrix usernames: %v", err))
+		return
+	}
+
+	for _, username := range usernames {
+		select {
+		case <-w.ctx.Done():
+			return
+		default:
+		}
+
+		if err := SyncUser(w.db.DB(), matrixClient, username); err != nil {
+			logger.Error(fmt.Sprintf("Device sync: Failed to reconcile devices: %v", err))
+		}
+	}
+}
+
+// SyncUser reconciles the local device registry for a Matrix user against the
+// devices currently reported by the Matrix client.
+func SyncUser(db *gorm.DB, matrixClient *matrixclient.Client, matrixUsername string) error {
+	remoteDevices, err := matrixClient.ListDevices(&matrixclient.ListDevicesRequest{
+		Username: matrixUsername,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	seen := make(map[string]bool, len(remoteDevices))
+	for _, remote := range remoteDevices {
+		seen[remote.BridgeName+":"+remote.DeviceID] = true
+
+		_, linked, err := models.UpsertLinkedDevice(db, matrixUsername, remote.BridgeName, remote.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to record device: %w", err)
+		}
+		if linked {
+			logger.Info(fmt.Sprintf("Device sync: Recorded newly linked %s device", remote.BridgeName))
+		}
+	}
+
+	localDevices, err := models.FindDevicesByUsername(db, matrixUsername)
+	if err != nil {
+		return fmt.Errorf("failed to load local devices: %w", err)
+	}
+
+	for _, local := range localDevices {
+		if local.Status == models.DeviceStatusUnlinked || seen[local.Platform+":"+local.DeviceID] {
+			continue
+		}
+		if err := models.UpdateDeviceStatus(db, local.ID, models.DeviceStatusUnlinked); err != nil {
+			return fmt.Errorf("failed to update device status: %w", err)
+		}
+		logger.Warn(fmt.Sprintf("Device sync: %s device no longer reported by the Matrix client, marked as unlinked", local.Platform))
+
+		local.Status = models.DeviceStatusUnlinked
+		notifyDisconnected(db, local, reasonUnlinked)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package devicesync
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/webhookworker"
+
+	"gorm.io/gorm"
+)
+
+const (
+	reasonUnlinked     = "unlinked"
+	reasonSendFailures = "send_failures"
+)
+
+func getThreshold(key string, defaultValue int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func degradedThreshold() int {
+	return getThreshold("DEVICE_DEGRADED_FAILURE_THRESHOLD", 3)
+}
+
+func disconnectedThreshold() int {
+	return getThreshold("DEVICE_DISCONNECTED_FAILURE_THRESHOLD", 10)
+}
+
+// RecordSendSuccess clears the failure streak of a device after a delivery
+func RecordSendSuccess(db *gorm.DB, matrixUsername, platform, deviceID string) error {
+	previous, err := models.RecordDeviceSendSuccess(db, matrixUsername, platform, deviceID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if previous == models.DeviceStatusDegraded || previous == models.DeviceStatusDisconnected {
+		logger.Info(fmt.Sprintf("Device health: %s device recovered from %s", platform, previous))
+	}
+	return nil
+}
+
+// RecordSendFailure counts a failed delivery against a device and moves it to
+// degraded or disconnected once the configured thresholds are reached.
+func RecordSendFailure(db *gorm.DB, matrixUsername, platform, deviceID string, sendErr error) error {
+	device, err := models.RecordDeviceSendFailure(db, matrixUsername, platform, deviceID, sendErr.Error())
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status := device.Status
+	switch {
+	case device.ConsecutiveFailures >= disconnectedThreshold():
+		status = models.DeviceStatusDisconnected
+	case device.ConsecutiveFailures >= degradedThreshold():
+		status = models.DeviceStatusDegraded
+	}
+
+	if status == device.Status || device.Status == models.DeviceStatusUnlinked {
+		return nil
+	}
+
+	if err := models.UpdateDeviceStatus(db, device.ID, status); err != nil {
+		return err
+	}
+	logger.Warn(fmt.Sprintf("Device health: %s device marked %s after %d consecutive failures", platform, status, device.ConsecutiveFailures))
+
+	if status == models.DeviceStatusDisconnected {
+		device.Status = status
+		notifyDisconnected(db, *device, reasonSendFailures)
+	}
+	return nil
+}
+
+func notifyDisconnected(db *gorm.DB, device models.Device, reason string) {
+	event := webhookworker.NewEvent(webhookworker.EventDeviceDisconnected, webhookworker.DeviceEventData{
+		Platform:            device.Platform,
+		DeviceID:            device.DeviceID,
+		Label:               device.Label,
+		Status:              string(device.Status),
+		Reason:              reason,
+		ConsecutiveFailures: device.ConsecutiveFailures,
+	})
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error(fmt.Sprintf("Device event delivery panic: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		if err := webhookworker.PublishEvent(db, device.MatrixUsername, event); err != nil {
+			logger.Error(fmt.Sprintf("Failed to publish %s event: %v", event.Event, err))
+		}
+	}()
+}
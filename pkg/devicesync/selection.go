@@ -0,0 +1,51 @@
+package devicesync
+
+import (
+	"errors"
+	"os"
+
+	"interface-api/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrNoAvailableDevice = errors.New("no available device for platform")
+
+const (
+	StrategyRoundRobin = "round_robin"
+	StrategyLRU        = "lru"
+)
+
+// selectionStrategy reads DEVICE_SELECTION_STRATEGY. Round robin rotates
+// through devices in the order they were last picked, LRU prefers the device
+// that has gone longest without a successful send.
+func selectionStrategy() string {
+	if os.Getenv("DEVICE_SELECTION_STRATEGY") == StrategyLRU {
+		return StrategyLRU
+	}
+	return StrategyRoundRobin
+}
+
+// SelectDevice picks a healthy device of the user on the platform, skipping
+// the devices listed in exclude. Active devices are always preferred over
+// degraded ones.
+func SelectDevice(db *gorm.DB, matrixUsername, platform string, exclude []string) (*models.Device, error) {
+	orderColumn := "last_selected_at"
+	if selectionStrategy() == StrategyLRU {
+		orderColumn = "last_send_at"
+	}
+
+	devices, err := models.FindSelectableDevices(db, matrixUsername, platform, orderColumn, exclude)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, ErrNoAvailableDevice
+	}
+
+	device := devices[0]
+	if err := models.MarkDeviceSelected(db, device.ID); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
@@ -4,21 +4,27 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
 	"interface-api/pkg/config"
 )
 
+// ErrNotFound is returned when the requested MAS resource does not exist
+var ErrNotFound = errors.New("resource not found")
+
 type Client struct {
 	baseURL      string
 	adminBaseURL string
 	clientID     string
 	clientSecret string
 	httpClient   *http.Client
+	adminTokens  *tokenSource
 }
 
 func New() (*Client, error) {
@@ -50,7 +56,7 @@ func New() (*Client, error) {
 		return nil, fmt.Errorf("ADMIN_CLIENT_SECRET environment variable is not set")
 	}
 
-	return &Client{
+	c := &Client{
 		baseURL:      baseURL,
 		adminBaseURL: adminBaseURL,
 		clientID:     clientID,
@@ -58,15 +64,25 @@ func New() (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
-	}, nil
+	}
+	c.adminTokens = sharedTokenSource(baseURL, clientID, c.requestAdminToken)
+
+	return c, nil
 }
 
+// GetAdminToken returns a MAS admin token, reusing the cached one until it is
+// about to expire. The cache is shared by every client for the same MAS
+// instance and admin client.
 func (c *Client) GetAdminToken() (string, error) {
+	return c.adminTokens.Token()
+}
+
+func (c *Client) requestAdminToken() (*TokenResponse, error) {
 	data := "grant_type=client_credentials&scope=urn:mas:admin"
 
 	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/oauth2/token", c.baseURL), bytes.NewBufferString(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	auth := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%s:%s", c.clientID, c.clientSecret))
@@ -75,55 +91,86 @@ func (c *Client) GetAdminToken() (string, error) {
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %w", err)
+		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var tokenResp TokenResponse
 	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return tokenResp.AccessToken, nil
+	return &tokenResp, nil
+}
+
+// doAdmin sends a request to the MAS admin API with the cached admin token.
+// If MAS rejects the token, a new one is fetched and the request is retried
+// once.
+func (c *Client) doAdmin(method, path string, body []byte) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		adminToken, err := c.adminTokens.Token()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get admin token: %w", err)
+		}
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		httpReq, err := http.NewRequest(method, fmt.Sprintf("%s%s", c.adminBaseURL, path), reqBody)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to execute request: %w", err)
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			c.adminTokens.Invalidate(adminToken)
+			continue
+		}
+
+		return resp.StatusCode, respBody, nil
+	}
 }
 
-func (c *Client) CreateUser(adminToken, username string) (*CreateUserResponse, error) {
+func (c *Client) CreateUser(username string) (*CreateUserResponse, error) {
 	reqBody := map[string]string{"username": username}
 	body, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/admin/v1/users", c.adminBaseURL), bytes.NewBuffer(body))
+	statusCode, respBody, err := c.doAdmin("POST", "/api/admin/v1/users", body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", adminToken))
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status code %d: %s", statusCode, string(respBody))
 	}
 
 	var createUserResp CreateUserResponse
@@ -134,7 +181,7 @@ func (c *Client) CreateUser(adminToken, username string) (*CreateUserResponse, e
 	return &createUserResp, nil
 }
 
-func (c *Client) CreatePersonalSession(adminToken, userID, deviceID string, expiresAt *time.Time) (*CreatePersonalSessionResponse, error) {
+func (c *Client) CreatePersonalSession(userID, deviceID string, expiresAt *time.Time) (*CreatePersonalSessionResponse, error) {
 	scope := fmt.Sprintf("openid urn:matrix:org.matrix.msc2967.client:api:* urn:matrix:org.matrix.msc2967.client:device:%s", deviceID)
 
 	reqBody := map[string]any{
@@ -156,33 +203,70 @@ func (c *Client) CreatePersonalSession(adminToken, userID, deviceID string, expi
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", fmt.Sprintf("%s/api/admin/v1/personal-sessions", c.adminBaseURL), bytes.NewBuffer(body))
+	statusCode, respBody, err := c.doAdmin("POST", "/api/admin/v1/personal-sessions", body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", adminToken))
-	httpReq.Header.Set("Content-Type", "application/json")
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status code %d: %s", statusCode, string(respBody))
+	}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+	var sessionResp CreatePersonalSessionResponse
+	if err := json.Unmarshal(respBody, &sessionResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	return &sessionResp, nil
+}
+
+// GetUserByUsername looks up a MAS user by username. It returns ErrNotFound if
+// no such user exists.
+func (c *Client) GetUserByUsername(username string) (*CreateUserResponse, error) {
+	statusCode, respBody, err := c.doAdmin("GET", fmt.Sprintf("/api/admin/v1/users/by-username/%s", url.PathEscape(username)), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	if statusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", statusCode, string(respBody))
 	}
 
-	var sessionResp CreatePersonalSessionResponse
-	if err := json.Unmarshal(respBody, &sessionResp); err != nil {
+	var userResp CreateUserResponse
+	if err := json.Unmarshal(respBody, &userResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return &sessionResp, nil
+	return &userResp, nil
+}
+
+// RevokePersonalSession revokes a personal session and the access token it
+// issued. It returns ErrNotFound if the session does not exist.
+func (c *Client) RevokePersonalSession(sessionID string) error {
+	return c.postAdminAction(fmt.Sprintf("/api/admin/v1/personal-sessions/%s/revoke", url.PathEscape(sessionID)))
+}
+
+// DeactivateUser deactivates a MAS user, which also ends all of their
+// sessions. It returns ErrNotFound if the user does not exist.
+func (c *Client) DeactivateUser(userID string) error {
+	return c.postAdminAction(fmt.Sprintf("/api/admin/v1/users/%s/deactivate", url.PathEscape(userID)))
+}
+
+func (c *Client) postAdminAction(path string) error {
+	statusCode, respBody, err := c.doAdmin("POST", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if statusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code %d: %s", statusCode, string(respBody))
+	}
+
+	return nil
 }
@@ -0,0 +1,92 @@
+package masclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// adminTokenRefreshMargin is how long before expiry a cached admin token is
+// replaced, so that requests never go out with a token about to lapse.
+const adminTokenRefreshMargin = 30 * time.Second
+
+var (
+	tokenSourcesMu sync.Mutex
+	tokenSources   = map[string]*tokenSource{}
+)
+
+// sharedTokenSource returns the token source for a MAS instance and admin
+// client, creating it on first use. Clients are created per request, so the
+// cache lives at package level.
+func sharedTokenSource(baseURL, clientID string, fetch func() (*TokenResponse, error)) *tokenSource {
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+
+	key := baseURL + "\x00" + clientID
+	source, ok := tokenSources[key]
+	if !ok {
+		source = newTokenSource(fetch)
+		tokenSources[key] = source
+	}
+	return source
+}
+
+// tokenSource caches an admin access token. Concurrent callers that find the
+// cache empty or stale wait for a single fetch instead of each requesting a
+// token.
+type tokenSource struct {
+	fetch func() (*TokenResponse, error)
+	now   func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+func newTokenSource(fetch func() (*TokenResponse, error)) *tokenSource {
+	return &tokenSource{
+		fetch: fetch,
+		now:   time.Now,
+	}
+}
+
+func (s *tokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Before(s.refreshAt) {
+		return s.token, nil
+	}
+
+	resp, err := s.fetch()
+	if err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access token")
+	}
+
+	// Refresh ahead of expiry, or halfway through lifetimes too short for the
+	// margin. Tokens without a lifetime are not cached.
+	lifetime := time.Duration(resp.ExpiresIn) * time.Second
+	margin := adminTokenRefreshMargin
+	if lifetime < 2*margin {
+		margin = lifetime / 2
+	}
+
+	s.token = resp.AccessToken
+	s.refreshAt = s.now().Add(lifetime - margin)
+	return s.token, nil
+}
+
+// Invalidate drops the cached token if it is still the given one, so that the
+// next call to Token fetches a new one. Passing the rejected token keeps a
+// caller from discarding a fresh token another caller already fetched.
+func (s *tokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+	}
+}
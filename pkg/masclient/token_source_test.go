@@ -0,0 +1,145 @@
+package masclient
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func countingSource(expiresIn int) (*tokenSource, *atomic.Int32) {
+	var fetches atomic.Int32
+	source := newTokenSource(func() (*TokenResponse, error) {
+		n := fetches.Add(1)
+		return &TokenResponse{AccessToken: fmt.Sprintf("token-%d", n), ExpiresIn: expiresIn}, nil
+	})
+	return source, &fetches
+}
+
+func TestTokenSource_ReusesToken(t *testing.T) {
+	source, fetches := countingSource(300)
+
+	first, err := source.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	second, err := source.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	if first != second {
+		t.Errorf("Token() = %q, want cached %q", second, first)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	source, fetches := countingSource(300)
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	now = now.Add(300*time.Second - adminTokenRefreshMargin - time.Second)
+	if _, err := source.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches before refresh margin = %d, want 1", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token != "token-2" {
+		t.Errorf("Token() inside refresh margin = %q, want token-2", token)
+	}
+}
+
+func TestTokenSource_ShortLifetime(t *testing.T) {
+	source, fetches := countingSource(20)
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	source.Token()
+	now = now.Add(9 * time.Second)
+	source.Token()
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches before half lifetime = %d, want 1", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	source.Token()
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetches after half lifetime = %d, want 2", got)
+	}
+}
+
+func TestTokenSource_NoLifetimeNotCached(t *testing.T) {
+	source, fetches := countingSource(0)
+
+	source.Token()
+	source.Token()
+
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestTokenSource_Invalidate(t *testing.T) {
+	source, fetches := countingSource(300)
+
+	stale, _ := source.Token()
+	source.Invalidate(stale)
+	fresh, _ := source.Token()
+	if fresh == stale {
+		t.Errorf("Token() after Invalidate = %q, want a new token", fresh)
+	}
+
+	source.Invalidate(stale)
+	again, _ := source.Token()
+	if again != fresh {
+		t.Errorf("Invalidate with an old token dropped %q", fresh)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestTokenSource_FetchError(t *testing.T) {
+	source := newTokenSource(func() (*TokenResponse, error) {
+		return nil, fmt.Errorf("unavailable")
+	})
+
+	if _, err := source.Token(); err == nil {
+		t.Error("Token() should return the fetch error")
+	}
+}
+
+func TestTokenSource_ConcurrentFetchOnce(t *testing.T) {
+	source, fetches := countingSource(300)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := source.Token(); err != nil {
+				t.Errorf("Token() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
@@ -22,11 +22,14 @@ type StoreCredentialsResponse struct {
 type AddDeviceRequest struct {
 	Username     string `json:"username"`
 	PlatformName string `json:"platform_name"`
+	Method       string `json:"method,omitempty"`
+	PhoneNumber  string `json:"phone_number,omitempty"`
 }
 
 type AddDeviceResponse struct {
-	DeviceID string `json:"device_id"`
-	Platform string `json:"platform"`
+	DeviceID    string `json:"device_id"`
+	Platform    string `json:"platform"`
+	PairingCode string `json:"pairing_code,omitempty"`
 }
 
 type DeleteDeviceRequest struct {
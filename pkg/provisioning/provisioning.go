@@ -0,0 +1,180 @@
+package provisioning
+
+import (
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/masclient"
+	"interface-api/pkg/matrixclient"
+	"interface-api/pkg/tokenrevoke"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Provision creates a MAS user with a personal session and stores its
+// credentials in the Matrix client. Each step is recorded in a TokenProvision
+// before it runs, so that a failure, here or while the caller issues the
+// token, can be compensated by releasing whatever was created. The caller must
+// finish with Complete or Compensate.
+//
+// If a step fails, the resources created so far are released before the
+// error is returned; whatever cannot be released is left to Reconcile.
+func Provision(db *gorm.DB, expiresAt *time.Time) (*models.TokenProvision, error) {
+	u := uuid.New()
+	username := hex.EncodeToString(u[:])[:16]
+	u = uuid.New()
+	deviceID := hex.EncodeToString(u[:])[:16]
+
+	provision, err := models.CreateTokenProvision(db, username, deviceID)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to record token provision: %v", err))
+		return nil, err
+	}
+
+	if err := runSteps(db, provision, expiresAt); err != nil {
+		Compensate(db, provision, err)
+		return nil, err
+	}
+
+	return provision, nil
+}
+
+func runSteps(db *gorm.DB, provision *models.TokenProvision, expiresAt *time.Time) error {
+	masClient, err := masclient.New()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to initialize MAS client: %v", err))
+		return err
+	}
+
+	matrixClient, err := matrixclient.New()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to initialize Matrix client: %v", err))
+		return err
+	}
+
+	userResp, err := masClient.CreateUser(provision.MatrixUsername)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create MAS user:\n%v\n\n%s", err, debug.Stack()))
+		return err
+	}
+	logger.Info("Created MAS user")
+
+	provision.MASUserID = userResp.Data.ID
+	provision.Step = models.ProvisionStepMASSession
+	if err := models.SaveTokenProvision(db, provision); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record token provision: %v", err))
+		return err
+	}
+
+	sessionResp, err := masClient.CreatePersonalSession(
+		userResp.Data.ID, provision.MatrixDeviceID, expiresAt,
+	)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to create MAS personal session:\n%v\n\n%s", err, debug.Stack()))
+		return err
+	}
+	logger.Info("Created MAS personal session")
+
+	provision.MASSessionID = sessionResp.Data.ID
+	provision.Step = models.ProvisionStepMatrixCredentials
+	if err := models.SaveTokenProvision(db, provision); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record token provision: %v", err))
+		return err
+	}
+
+	_, err = matrixClient.StoreCredentials(&matrixclient.StoreCredentialsRequest{
+		Username:    provision.MatrixUsername,
+		AccessToken: sessionResp.Data.Attributes.AccessToken,
+		DeviceID:    provision.MatrixDeviceID,
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to store Matrix credentials:\n%v\n\n%s", err, debug.Stack()))
+		return err
+	}
+	logger.Info("Stored Matrix credentials")
+
+	provision.Step = models.ProvisionStepIdentity
+	if err := models.SaveTokenProvision(db, provision); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record token provision: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Complete drops the provision record once its token has been issued
+func Complete(db *gorm.DB, provision *models.TokenProvision) {
+	if err := models.DeleteTokenProvision(db, provision.ID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to clear token provision %d: %v", provision.ID, err))
+	}
+}
+
+// Compensate releases the resources created by a provision that did not
+// complete, newest first. The record is deleted once everything is released
+// and otherwise kept as failed for Reconcile to retry.
+func Compensate(db *gorm.DB, provision *models.TokenProvision, cause error) error {
+	logger.Warn(fmt.Sprintf("Rolling back token provision %d at step %s", provision.ID, provision.Step))
+
+	err := tokenrevoke.Release(tokenrevoke.Resources{
+		MatrixUsername:    provision.MatrixUsername,
+		MASUserID:         provision.MASUserID,
+		MASSessionID:      provision.MASSessionID,
+		DeleteCredentials: provision.Step == models.ProvisionStepIdentity || provision.Step == models.ProvisionStepMatrixCredentials,
+		DeactivateUser:    true,
+	})
+	if err == nil {
+		logger.Info(fmt.Sprintf("Rolled back token provision %d", provision.ID))
+		if err := models.DeleteTokenProvision(db, provision.ID); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to clear token provision %d: %v", provision.ID, err))
+		}
+		return nil
+	}
+
+	logger.Error(fmt.Sprintf("Failed to roll back token provision %d: %v", provision.ID, err))
+
+	provision.Status = models.ProvisionStatusFailed
+	provision.Attempts++
+	provision.LastError = err.Error()
+	if cause != nil {
+		provision.LastError = fmt.Sprintf("%v; rollback: %v", cause, err)
+	}
+	if saveErr := models.SaveTokenProvision(db, provision); saveErr != nil {
+		logger.Error(fmt.Sprintf("Failed to record token provision failure: %v", saveErr))
+	}
+	return err
+}
+
+// Reconcile settles failed provisions and pending ones that have not
+// progressed since staleBefore, which are left behind when the server stops
+// mid-provision. Provisions whose token was issued are completed; the rest
+// are compensated.
+func Reconcile(db *gorm.DB, staleBefore time.Time) {
+	provisions, err := models.FindUnreconciledTokenProvisions(db, staleBefore)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find unreconciled token provisions: %v", err))
+		return
+	}
+
+	for i := range provisions {
+		provision := &provisions[i]
+
+		issued, err := models.MatrixIdentityExistsFor(db, provision)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to check token provision %d: %v", provision.ID, err))
+			continue
+		}
+
+		if issued {
+			logger.Info(fmt.Sprintf("Token provision %d completed before interruption", provision.ID))
+			Complete(db, provision)
+			continue
+		}
+
+		Compensate(db, provision, nil)
+	}
+}
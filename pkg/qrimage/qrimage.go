@@ -0,0 +1,70 @@
+package qrimage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	DefaultSize = 256
+	MinSize     = 64
+	MaxSize     = 1024
+)
+
+var ErrInvalidSize = fmt.Errorf("size must be between %d and %d pixels", MinSize, MaxSize)
+
+var errEmptyContent = errors.New("qr code content is empty")
+
+func validate(content string, size int) error {
+	if content == "" {
+		return errEmptyContent
+	}
+	if size < MinSize || size > MaxSize {
+		return ErrInvalidSize
+	}
+	return nil
+}
+
+// PNG renders the content as a square PNG QR code of the given width in pixels
+func PNG(content string, size int) ([]byte, error) {
+	if err := validate(content, size); err != nil {
+		return nil, err
+	}
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+// SVG renders the content as a square SVG QR code of the given width in pixels.
+// Dark modules are drawn as a single path so the image scales without blurring.
+func SVG(content string, size int) ([]byte, error) {
+	if err := validate(content, size); err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := qr.Bitmap()
+	modules := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	svg.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>`)
+	fmt.Fprintf(&svg, `<path fill="#000000" d="%s"/>`, path.String())
+	svg.WriteString(`</svg>`)
+
+	return []byte(svg.String()), nil
+}
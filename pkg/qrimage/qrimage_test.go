@@ -0,0 +1,59 @@
+package qrimage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	image, err := PNG("2@abc123", DefaultSize)
+	if err != nil {
+		t.Fatalf("PNG() unexpected error = %v", err)
+	}
+	if !bytes.HasPrefix(image, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("PNG() did not return a PNG image")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	image, err := SVG("2@abc123", DefaultSize)
+	if err != nil {
+		t.Fatalf("SVG() unexpected error = %v", err)
+	}
+	svg := string(image)
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("SVG() did not return an SVG document")
+	}
+	if !strings.Contains(svg, `width="256"`) {
+		t.Errorf("SVG() width not set to requested size")
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		size    int
+	}{
+		{name: "empty content", content: "", size: DefaultSize},
+		{name: "too small", content: "data", size: MinSize - 1},
+		{name: "too large", content: "data", size: MaxSize + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PNG(tt.content, tt.size); err == nil {
+				t.Errorf("PNG() expected error")
+			}
+			if _, err := SVG(tt.content, tt.size); err == nil {
+				t.Errorf("SVG() expected error")
+			}
+		})
+	}
+
+	if _, err := PNG("data", MaxSize+1); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("PNG() error = %v, want ErrInvalidSize", err)
+	}
+}
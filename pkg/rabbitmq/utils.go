@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/streadway/amqp"
@@ -95,11 +96,21 @@ func (c *Client) bindQueue(queueName, exchangeName, routingKey string) error {
 func (c *Client) queueExists(queueName string) (bool, error) {
 	_, err := c.channel.QueueInspect(queueName)
 	if err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
 		return false, err
 	}
 	return true, nil
 }
 
+// IsNotFound reports whether the broker rejected an operation with 404
+// NOT_FOUND, e.g. a passive declare or bind of a queue that does not exist
+func IsNotFound(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound
+}
+
 func (c *Client) getQueueMessageCount(queueName string) (int, error) {
 	queue, err := c.channel.QueueInspect(queueName)
 	if err != nil {
@@ -2,18 +2,43 @@ package throttler
 
 import "time"
 
+const megabyte = 1 << 20
+
 type PlatformConfig struct {
-	Rate      int           // Messages per interval
-	Interval  time.Duration // Time window
-	JitterMin float64       // Minimum jitter multiplier (e.g., 0.8 = 80% of interval)
-	JitterMax float64       // Maximum jitter multiplier (e.g., 1.2 = 120% of interval)
+	Rate              int           // Messages per interval
+	Interval          time.Duration // Time window
+	JitterMin         float64       // Minimum jitter multiplier (e.g., 0.8 = 80% of interval)
+	JitterMax         float64       // Maximum jitter multiplier (e.g., 1.2 = 120% of interval)
+	MaxAttachmentSize int64         // Largest attachment in bytes the platform accepts
 }
 
 var DefaultPlatformConfigs = map[string]PlatformConfig{
 	"default": {
-		Rate:      1,
-		Interval:  8 * time.Second,
-		JitterMin: 0.75,
-		JitterMax: 1.25,
+		Rate:              1,
+		Interval:          8 * time.Second,
+		JitterMin:         0.75,
+		JitterMax:         1.25,
+		MaxAttachmentSize: 32 * megabyte,
+	},
+	"wa": {
+		Rate:              1,
+		Interval:          8 * time.Second,
+		JitterMin:         0.75,
+		JitterMax:         1.25,
+		MaxAttachmentSize: 100 * megabyte,
+	},
+	"signal": {
+		Rate:              1,
+		Interval:          8 * time.Second,
+		JitterMin:         0.75,
+		JitterMax:         1.25,
+		MaxAttachmentSize: 100 * megabyte,
+	},
+	"telegram": {
+		Rate:              1,
+		Interval:          8 * time.Second,
+		JitterMin:         0.75,
+		JitterMax:         1.25,
+		MaxAttachmentSize: 50 * megabyte,
 	},
 }
@@ -6,11 +6,36 @@ import (
 	"time"
 )
 
-type tokenBucket struct {
+// bucketState is the part of a token bucket that changes as it is used, kept
+// separate so buckets held outside memory can share the refill logic
+type bucketState struct {
 	tokens     float64
+	lastRefill time.Time
+}
+
+// take adds the tokens refilled since the last refill at refillRate tokens per
+// second, up to maxTokens, and then takes one token if there is one
+func (s *bucketState) take(now time.Time, maxTokens, refillRate float64) bool {
+	elapsed := now.Sub(s.lastRefill).Seconds()
+
+	s.tokens += elapsed * refillRate
+	if s.tokens > maxTokens {
+		s.tokens = maxTokens
+	}
+	s.lastRefill = now
+
+	if s.tokens >= 1.0 {
+		s.tokens -= 1.0
+		return true
+	}
+
+	return false
+}
+
+type tokenBucket struct {
+	bucketState
 	maxTokens  float64
 	refillRate float64
-	lastRefill time.Time
 	jitterMin  float64
 	jitterMax  float64
 	mu         sync.Mutex
@@ -25,10 +50,12 @@ func newTokenBucket(rate int, interval time.Duration, jitterMin, jitterMax float
 	}
 	refillRate := float64(rate) / interval.Seconds()
 	return &tokenBucket{
-		tokens:     0,
+		bucketState: bucketState{
+			tokens:     0,
+			lastRefill: time.Now(),
+		},
 		maxTokens:  float64(rate),
 		refillRate: refillRate,
-		lastRefill: time.Now(),
 		jitterMin:  jitterMin,
 		jitterMax:  jitterMax,
 	}
@@ -38,24 +65,10 @@ func (tb *tokenBucket) Allow() bool {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
-	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill).Seconds()
-
 	jitter := tb.jitterMin + rand.Float64()*(tb.jitterMax-tb.jitterMin)
 	adjustedRefillRate := tb.refillRate / jitter
 
-	tb.tokens += elapsed * adjustedRefillRate
-	if tb.tokens > tb.maxTokens {
-		tb.tokens = tb.maxTokens
-	}
-	tb.lastRefill = now
-
-	if tb.tokens >= 1.0 {
-		tb.tokens -= 1.0
-		return true
-	}
-
-	return false
+	return tb.take(time.Now(), tb.maxTokens, adjustedRefillRate)
 }
 
 func (tb *tokenBucket) WaitTime() time.Duration {
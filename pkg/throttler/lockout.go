@@ -0,0 +1,221 @@
+package throttler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type LockoutConfig struct {
+	Attempts    int           // Failed attempts allowed before a lockout
+	Interval    time.Duration // Time for the full allowance of attempts to refill
+	BaseLockout time.Duration // First lockout, doubled for each consecutive one
+	MaxLockout  time.Duration // Upper bound for a single lockout
+}
+
+// Lockout describes the failed attempts recorded against a key
+type Lockout struct {
+	Key         string
+	Failures    int
+	Lockouts    int
+	Tokens      float64   // Attempts left in the key's bucket at LastRefill
+	LastRefill  time.Time // When Tokens was last brought up to date
+	LockedUntil time.Time
+	LastFailure time.Time
+	ExpiresAt   time.Time // When the bucket has refilled and the entry can be forgotten
+}
+
+// Locked reports whether the key is locked out at the given time
+func (l Lockout) Locked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// LockoutStore keeps the lockout state of each key, so that instances sharing
+// a store also share their lockouts. Implementations must be safe for
+// concurrent use, and Update must apply its change atomically. Expired
+// entries are removed by the store or its owner.
+type LockoutStore interface {
+	// Get returns the entry for the key, or nil when there is none
+	Get(key string) (*Lockout, error)
+	// Update applies fn to the key's entry, or to an empty entry with only
+	// Key set, and saves the result
+	Update(key string, fn func(*Lockout)) error
+	Delete(key string) (bool, error)
+	DeleteAll() (int, error)
+	// List returns the entries that have not expired, sorted by key
+	List() ([]Lockout, error)
+}
+
+// LockoutTracker counts failed attempts per key, such as a username or an IP
+// address. Each failure takes a token from the key's bucket, and once the
+// bucket is empty the key is locked out. A key that keeps failing after its
+// lockout ends is locked out again for twice as long, up to MaxLockout.
+type LockoutTracker struct {
+	config LockoutConfig
+	store  LockoutStore
+}
+
+func NewLockoutTracker(config LockoutConfig, store LockoutStore) *LockoutTracker {
+	return &LockoutTracker{
+		config: config,
+		store:  store,
+	}
+}
+
+// Check returns how long the key remains locked out, or zero when it is not
+func (t *LockoutTracker) Check(key string) (time.Duration, error) {
+	entry, err := t.store.Get(key)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	if wait := time.Until(entry.LockedUntil); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failed attempt and returns the lockout it started, or zero
+// when the key still has attempts left.
+func (t *LockoutTracker) Fail(key string) (time.Duration, error) {
+	var lockout time.Duration
+	err := t.store.Update(key, func(entry *Lockout) {
+		lockout = t.fail(entry, time.Now())
+	})
+	return lockout, err
+}
+
+func (t *LockoutTracker) fail(entry *Lockout, now time.Time) time.Duration {
+	maxTokens := float64(t.config.Attempts)
+
+	// A new or forgotten key starts with a full bucket, unlike the message
+	// limiters, so the first attempts are not held back
+	if !now.Before(entry.ExpiresAt) {
+		*entry = Lockout{Key: entry.Key, Tokens: maxTokens, LastRefill: now}
+	}
+
+	bucket := bucketState{tokens: entry.Tokens, lastRefill: entry.LastRefill}
+	allowed := bucket.take(now, maxTokens, maxTokens/t.config.Interval.Seconds())
+	entry.Tokens, entry.LastRefill = bucket.tokens, bucket.lastRefill
+
+	entry.Failures++
+	entry.LastFailure = now
+	entry.ExpiresAt = now.Add(max(t.config.Interval, t.config.MaxLockout))
+
+	// The failure that takes the last token starts the lockout, so the
+	// configured number of attempts includes it
+	if allowed && bucket.tokens >= 1.0 {
+		return 0
+	}
+
+	entry.Lockouts++
+	lockout := t.config.BaseLockout
+	for i := 1; i < entry.Lockouts && lockout < t.config.MaxLockout; i++ {
+		lockout *= 2
+	}
+	lockout = min(lockout, t.config.MaxLockout)
+	entry.LockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// Clear forgets the failures recorded against the key, e.g. after a
+// successful attempt, and reports whether there were any
+func (t *LockoutTracker) Clear(key string) (bool, error) {
+	return t.store.Delete(key)
+}
+
+// ClearAll forgets every key and returns how many were tracked
+func (t *LockoutTracker) ClearAll() (int, error) {
+	return t.store.DeleteAll()
+}
+
+// List returns the keys with recent failures, sorted by key
+func (t *LockoutTracker) List() ([]Lockout, error) {
+	return t.store.List()
+}
+
+// MemoryLockoutStore keeps lockouts in process memory. They are lost on
+// restart and are not shared between replicas.
+type MemoryLockoutStore struct {
+	mu      sync.Mutex
+	entries map[string]Lockout
+}
+
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	s := &MemoryLockoutStore{entries: make(map[string]Lockout)}
+	go s.cleanupExpired()
+	return s
+}
+
+func (s *MemoryLockoutStore) cleanupExpired() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if !now.Before(entry.ExpiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *MemoryLockoutStore) Get(key string) (*Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !time.Now().Before(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *MemoryLockoutStore) Update(key string, fn func(*Lockout)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = Lockout{Key: key}
+	}
+	fn(&entry)
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryLockoutStore) Delete(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	delete(s.entries, key)
+	return ok && time.Now().Before(entry.ExpiresAt), nil
+}
+
+func (s *MemoryLockoutStore) DeleteAll() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	count := 0
+	for _, entry := range s.entries {
+		if now.Before(entry.ExpiresAt) {
+			count++
+		}
+	}
+	s.entries = make(map[string]Lockout)
+	return count, nil
+}
+
+func (s *MemoryLockoutStore) List() ([]Lockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	lockouts := make([]Lockout, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if now.Before(entry.ExpiresAt) {
+			lockouts = append(lockouts, entry)
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Key < lockouts[j].Key })
+	return lockouts, nil
+}
@@ -0,0 +1,145 @@
+package throttler
+
+import (
+	"testing"
+	"time"
+)
+
+func mustFail(t *testing.T, tracker *LockoutTracker, key string) time.Duration {
+	t.Helper()
+	lockout, err := tracker.Fail(key)
+	if err != nil {
+		t.Fatalf("Fail(%q) error: %v", key, err)
+	}
+	return lockout
+}
+
+func mustCheck(t *testing.T, tracker *LockoutTracker, key string) time.Duration {
+	t.Helper()
+	wait, err := tracker.Check(key)
+	if err != nil {
+		t.Fatalf("Check(%q) error: %v", key, err)
+	}
+	return wait
+}
+
+func TestLockoutTracker_LocksAfterAttempts(t *testing.T) {
+	tracker := NewLockoutTracker(LockoutConfig{
+		Attempts:    3,
+		Interval:    time.Hour,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	}, NewMemoryLockoutStore())
+
+	for i := 1; i < 3; i++ {
+		if lockout := mustFail(t, tracker, "admin"); lockout != 0 {
+			t.Fatalf("Failure %d started a lockout of %v", i, lockout)
+		}
+	}
+	if mustCheck(t, tracker, "admin") != 0 {
+		t.Error("Key should not be locked before its attempts are used up")
+	}
+
+	if lockout := mustFail(t, tracker, "admin"); lockout != time.Minute {
+		t.Errorf("Third failure lockout = %v, want 1m", lockout)
+	}
+	if wait := mustCheck(t, tracker, "admin"); wait <= 0 || wait > time.Minute {
+		t.Errorf("Check() = %v, want up to 1m", wait)
+	}
+	if mustCheck(t, tracker, "other") != 0 {
+		t.Error("Other keys should not be locked")
+	}
+}
+
+func TestLockoutTracker_ExponentialLockout(t *testing.T) {
+	tracker := NewLockoutTracker(LockoutConfig{
+		Attempts:    1,
+		Interval:    time.Hour,
+		BaseLockout: time.Minute,
+		MaxLockout:  5 * time.Minute,
+	}, NewMemoryLockoutStore())
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, expected := range want {
+		if lockout := mustFail(t, tracker, "10.0.0.1"); lockout != expected {
+			t.Errorf("Lockout %d = %v, want %v", i+1, lockout, expected)
+		}
+	}
+
+	lockouts, err := tracker.List()
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Failures != 5 || lockouts[0].Lockouts != 5 {
+		t.Fatalf("List() = %+v", lockouts)
+	}
+	if !lockouts[0].Locked(time.Now()) {
+		t.Error("Listed key should be locked")
+	}
+}
+
+func TestLockoutTracker_Refill(t *testing.T) {
+	tracker := NewLockoutTracker(LockoutConfig{
+		Attempts:    2,
+		Interval:    200 * time.Millisecond,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Minute,
+	}, NewMemoryLockoutStore())
+
+	mustFail(t, tracker, "admin")
+	time.Sleep(150 * time.Millisecond)
+
+	if lockout := mustFail(t, tracker, "admin"); lockout != 0 {
+		t.Errorf("Failure after refill started a lockout of %v", lockout)
+	}
+}
+
+func TestLockoutTracker_SharedStore(t *testing.T) {
+	config := LockoutConfig{
+		Attempts:    2,
+		Interval:    time.Hour,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	}
+	store := NewMemoryLockoutStore()
+	first := NewLockoutTracker(config, store)
+	second := NewLockoutTracker(config, store)
+
+	if lockout := mustFail(t, first, "admin"); lockout != 0 {
+		t.Fatalf("First failure started a lockout of %v", lockout)
+	}
+	if lockout := mustFail(t, second, "admin"); lockout != time.Minute {
+		t.Errorf("Failure on the second tracker lockout = %v, want 1m", lockout)
+	}
+	if mustCheck(t, first, "admin") == 0 {
+		t.Error("Lockout should be seen by every tracker sharing the store")
+	}
+}
+
+func TestLockoutTracker_Clear(t *testing.T) {
+	tracker := NewLockoutTracker(LockoutConfig{
+		Attempts:    1,
+		Interval:    time.Hour,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+	}, NewMemoryLockoutStore())
+
+	mustFail(t, tracker, "admin")
+	mustFail(t, tracker, "10.0.0.1")
+
+	if cleared, _ := tracker.Clear("admin"); !cleared {
+		t.Error("Clear() should report a tracked key")
+	}
+	if cleared, _ := tracker.Clear("admin"); cleared {
+		t.Error("Clear() should not report an untracked key")
+	}
+	if mustCheck(t, tracker, "admin") != 0 {
+		t.Error("Cleared key should not be locked")
+	}
+	if count, _ := tracker.ClearAll(); count != 1 {
+		t.Errorf("ClearAll() = %d, want 1", count)
+	}
+	if lockouts, _ := tracker.List(); len(lockouts) != 0 {
+		t.Error("List() should be empty after ClearAll()")
+	}
+}
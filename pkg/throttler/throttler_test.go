@@ -157,3 +157,21 @@ func TestThrottler_DefaultPlatform(t *testing.T) {
 		t.Error("Request after refill should use default config")
 	}
 }
+
+func TestBucketState_Take(t *testing.T) {
+	start := time.Now()
+	bucket := bucketState{tokens: 2, lastRefill: start}
+
+	if !bucket.take(start, 2, 1) || !bucket.take(start, 2, 1) {
+		t.Fatal("A full bucket should allow its tokens to be taken")
+	}
+	if bucket.take(start, 2, 1) {
+		t.Error("An empty bucket should not allow a take")
+	}
+	if !bucket.take(start.Add(1500*time.Millisecond), 2, 1) {
+		t.Error("A token should be refilled after a second")
+	}
+	if bucket.take(start.Add(time.Hour), 2, 1); bucket.tokens != 1 {
+		t.Errorf("Refilled tokens = %v, want the bucket capped at 2 before the take", bucket.tokens)
+	}
+}
@@ -0,0 +1,285 @@
+package tokenrevoke
+
+import (
+	"errors"
+	"fmt"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+	"interface-api/pkg/masclient"
+	"interface-api/pkg/matrixclient"
+
+	"gorm.io/gorm"
+)
+
+// Resources are the external resources provisioned for a Matrix user
+type Resources struct {
+	MatrixUsername string
+	MASUserID      string
+	MASSessionID   string
+	// DeleteCredentials removes the credentials stored in the Matrix client
+	DeleteCredentials bool
+	// DeactivateUser deactivates the MAS user, looking it up by username when
+	// MASUserID is unknown
+	DeactivateUser bool
+}
+
+// Revoke releases the Matrix and MAS resources behind a deleted token. The
+// stored Matrix credentials and the MAS personal session are removed once no
+// other token uses the same user and device, and the MAS user is deactivated
+// once no other token uses the same user. Tokens created with use_host share
+// the admin identity's user and device, so they never tear anything down while
+// that identity exists. Resources that cannot be released are queued for
+// Retry.
+func Revoke(db *gorm.DB, identity *models.MatrixIdentity) error {
+	deviceUsers, err := models.CountMatrixIdentitiesSharing(db, identity, true)
+	if err != nil {
+		return fmt.Errorf("failed to check for other tokens: %w", err)
+	}
+	if deviceUsers > 0 {
+		logger.Debug(fmt.Sprintf("Token %d deleted from database only - credentials still in use by %d other token(s)", identity.ID, deviceUsers))
+		return nil
+	}
+
+	userTokens, err := models.CountMatrixIdentitiesSharing(db, identity, false)
+	if err != nil {
+		return fmt.Errorf("failed to check for other tokens: %w", err)
+	}
+
+	return ReleaseOrQueue(db, Resources{
+		MatrixUsername:    identity.MatrixUsername,
+		MASUserID:         identity.MASUserID,
+		MASSessionID:      identity.MASSessionID,
+		DeleteCredentials: true,
+		DeactivateUser:    userTokens == 0,
+	})
+}
+
+// ReleaseOrQueue releases the resources of deleted tokens and queues whatever
+// could not be released for Retry, so nothing is leaked when MAS or the
+// Matrix client is unavailable. The release failures are returned.
+func ReleaseOrQueue(db *gorm.DB, r Resources) error {
+	remaining, err := release(r)
+	if err == nil {
+		return nil
+	}
+
+	revocation := &models.TokenRevocation{
+		MatrixUsername:    remaining.MatrixUsername,
+		MASUserID:         remaining.MASUserID,
+		MASSessionID:      remaining.MASSessionID,
+		DeleteCredentials: remaining.DeleteCredentials,
+		DeactivateUser:    remaining.DeactivateUser,
+		LastError:         err.Error(),
+		Attempts:          1,
+	}
+	if saveErr := models.SaveTokenRevocation(db, revocation); saveErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to queue revocation for retry: %w", saveErr))
+	}
+	logger.Info(fmt.Sprintf("Queued revocation %d of Matrix user %s for retry", revocation.ID, r.MatrixUsername))
+	return err
+}
+
+// Retry releases the resources queued by ReleaseOrQueue. Revocations are
+// dropped once released, or once a token uses the Matrix user again, and
+// otherwise kept with what is still left to release.
+func Retry(db *gorm.DB) {
+	revocations, err := models.FindTokenRevocations(db)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to find queued token revocations: %v", err))
+		return
+	}
+
+	for i := range revocations {
+		revocation := &revocations[i]
+
+		inUse, err := models.MatrixUserInUse(db, revocation.MatrixUsername)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to check token revocation %d: %v", revocation.ID, err))
+			continue
+		}
+		if inUse {
+			logger.Info(fmt.Sprintf("Dropping token revocation %d: Matrix user %s is in use again", revocation.ID, revocation.MatrixUsername))
+			if err := models.DeleteTokenRevocation(db, revocation.ID); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to clear token revocation %d: %v", revocation.ID, err))
+			}
+			continue
+		}
+
+		remaining, err := release(Resources{
+			MatrixUsername:    revocation.MatrixUsername,
+			MASUserID:         revocation.MASUserID,
+			MASSessionID:      revocation.MASSessionID,
+			DeleteCredentials: revocation.DeleteCredentials,
+			DeactivateUser:    revocation.DeactivateUser,
+		})
+		if err == nil {
+			logger.Info(fmt.Sprintf("Released queued token revocation %d", revocation.ID))
+			if err := models.DeleteTokenRevocation(db, revocation.ID); err != nil {
+				logger.Warn(fmt.Sprintf("Failed to clear token revocation %d: %v", revocation.ID, err))
+			}
+			continue
+		}
+
+		logger.Warn(fmt.Sprintf("Retry of token revocation %d failed: %v", revocation.ID, err))
+		revocation.MASSessionID = remaining.MASSessionID
+		revocation.DeleteCredentials = remaining.DeleteCredentials
+		revocation.DeactivateUser = remaining.DeactivateUser
+		revocation.LastError = err.Error()
+		revocation.Attempts++
+		if err := models.SaveTokenRevocation(db, revocation); err != nil {
+			logger.Error(fmt.Sprintf("Failed to record token revocation failure: %v", err))
+		}
+	}
+}
+
+// Release removes the given resources. It is best effort: every step is
+// attempted, resources that are already gone count as released, and the
+// failures are joined into the returned error.
+func Release(r Resources) error {
+	_, err := release(r)
+	return err
+}
+
+// release removes the given resources and returns the ones it could not
+func release(r Resources) (Resources, error) {
+	var errs []error
+	remaining := Resources{
+		MatrixUsername: r.MatrixUsername,
+		MASUserID:      r.MASUserID,
+	}
+
+	if r.DeleteCredentials {
+		matrixClient, err := matrixclient.New()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to initialize Matrix client: %w", err))
+			remaining.DeleteCredentials = true
+		} else if _, err := matrixClient.DeleteToken(&matrixclient.DeleteTokenRequest{
+			Username: r.MatrixUsername,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete credentials from Matrix client: %w", err))
+			remaining.DeleteCredentials = true
+		} else {
+			logger.Info("Deleted credentials from Matrix client")
+		}
+	}
+
+	if err := releaseMAS(r, &remaining); err != nil {
+		errs = append(errs, err)
+	}
+
+	return remaining, errors.Join(errs...)
+}
+
+// releaseMAS revokes the MAS session and deactivates the MAS user, recording
+// the steps that fail in remaining
+func releaseMAS(r Resources, remaining *Resources) error {
+	if r.MASSessionID == "" && !r.DeactivateUser {
+		return nil
+	}
+
+	masClient, err := masclient.New()
+	if err != nil {
+		remaining.MASSessionID = r.MASSessionID
+		remaining.DeactivateUser = r.DeactivateUser
+		return fmt.Errorf("failed to initialize MAS client: %w", err)
+	}
+
+	var errs []error
+
+	if r.MASSessionID != "" {
+		err := masClient.RevokePersonalSession(r.MASSessionID)
+		switch {
+		case err == nil:
+			logger.Info("Revoked MAS personal session")
+		case errors.Is(err, masclient.ErrNotFound):
+			logger.Debug("MAS personal session already gone")
+		default:
+			errs = append(errs, fmt.Errorf("failed to revoke MAS personal session: %w", err))
+			remaining.MASSessionID = r.MASSessionID
+		}
+	}
+
+	if r.DeactivateUser {
+		userID := r.MASUserID
+		if userID == "" {
+			// Tokens created before MAS ids were recorded, and provisions that
+			// failed while creating the user, are looked up by username.
+			user, err := masClient.GetUserByUsername(r.MatrixUsername)
+			if err != nil && !errors.Is(err, masclient.ErrNotFound) {
+				errs = append(errs, fmt.Errorf("failed to find MAS user: %w", err))
+				remaining.DeactivateUser = true
+			} else if err == nil {
+				userID = user.Data.ID
+			}
+		}
+
+		if userID != "" {
+			err := masClient.DeactivateUser(userID)
+			switch {
+			case err == nil:
+				logger.Info("Deactivated MAS user")
+			case errors.Is(err, masclient.ErrNotFound):
+				logger.Debug("MAS user already gone")
+			default:
+				errs = append(errs, fmt.Errorf("failed to deactivate MAS user: %w", err))
+				remaining.MASUserID = userID
+				remaining.DeactivateUser = true
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Plan works out what revoking the given tokens together releases, without
+// changing anything. Like Revoke, a device is only released once no token
+// outside the set uses it, and a user is only deactivated once none uses it.
+// Each device and user appears in at most one of the returned resources.
+func Plan(db *gorm.DB, identities []models.MatrixIdentity) ([]Resources, error) {
+	ids := make([]uint, 0, len(identities))
+	for _, identity := range identities {
+		ids = append(ids, identity.ID)
+	}
+
+	type device struct{ username, deviceID string }
+	planned := make(map[device]bool)
+	deactivated := make(map[string]bool)
+
+	var resources []Resources
+	for i := range identities {
+		identity := &identities[i]
+		key := device{identity.MatrixUsername, identity.MatrixDeviceID}
+		if planned[key] {
+			continue
+		}
+
+		deviceUsers, err := models.CountMatrixIdentitiesSharing(db, identity, true, ids...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for other tokens: %w", err)
+		}
+		if deviceUsers > 0 {
+			continue
+		}
+
+		userTokens, err := models.CountMatrixIdentitiesSharing(db, identity, false, ids...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for other tokens: %w", err)
+		}
+
+		deactivate := userTokens == 0 && !deactivated[identity.MatrixUsername]
+		if deactivate {
+			deactivated[identity.MatrixUsername] = true
+		}
+		planned[key] = true
+
+		resources = append(resources, Resources{
+			MatrixUsername:    identity.MatrixUsername,
+			MASUserID:         identity.MASUserID,
+			MASSessionID:      identity.MASSessionID,
+			DeleteCredentials: true,
+			DeactivateUser:    deactivate,
+		})
+	}
+	return resources, nil
+}
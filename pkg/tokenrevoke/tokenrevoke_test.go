@@ -0,0 +1,92 @@
+package tokenrevoke
+
+import (
+	"testing"
+
+	"interface-api/internal/database/models"
+	"interface-api/migrations"
+	"interface-api/pkg/migrator"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("HASH_KEY", "dGVzdC1oYXNoLWtleS10ZXN0LWhhc2gta2V5LTEyMzQ=")
+	// Leave MAS and the Matrix client unconfigured so every release fails
+	t.Setenv("MATRIX_CLIENT_URL", "")
+	t.Setenv("MAS_URL", "")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := migrator.NewManager(db, migrations.GetAllMigrations()).Up(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func findRevocations(t *testing.T, db *gorm.DB) []models.TokenRevocation {
+	t.Helper()
+	revocations, err := models.FindTokenRevocations(db)
+	if err != nil {
+		t.Fatalf("FindTokenRevocations() error: %v", err)
+	}
+	return revocations
+}
+
+func TestReleaseOrQueue_QueuesFailures(t *testing.T) {
+	db := newTestDB(t)
+
+	err := ReleaseOrQueue(db, Resources{
+		MatrixUsername:    "alice",
+		MASUserID:         "01USER",
+		MASSessionID:      "01SESSION",
+		DeleteCredentials: true,
+		DeactivateUser:    true,
+	})
+	if err == nil {
+		t.Fatal("ReleaseOrQueue() should report the failed release")
+	}
+
+	revocations := findRevocations(t, db)
+	if len(revocations) != 1 {
+		t.Fatalf("Queued %d revocation(s), want 1", len(revocations))
+	}
+	queued := revocations[0]
+	if queued.MatrixUsername != "alice" || queued.MASUserID != "01USER" || queued.MASSessionID != "01SESSION" ||
+		!queued.DeleteCredentials || !queued.DeactivateUser || queued.Attempts != 1 || queued.LastError == "" {
+		t.Errorf("Queued revocation = %+v", queued)
+	}
+
+	Retry(db)
+	if revocations := findRevocations(t, db); len(revocations) != 1 || revocations[0].Attempts != 2 {
+		t.Errorf("After a failed retry revocations = %+v, want one with 2 attempts", revocations)
+	}
+}
+
+func TestRetry_DropsUsersInUseAgain(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := ReleaseOrQueue(db, Resources{MatrixUsername: "alice", DeleteCredentials: true}); err == nil {
+		t.Fatal("ReleaseOrQueue() should report the failed release")
+	}
+	if _, _, err := models.CreateMatrixIdentity(db, "alice", "DEVICE", false, nil, nil, models.TokenMetadata{}); err != nil {
+		t.Fatalf("CreateMatrixIdentity() error: %v", err)
+	}
+
+	Retry(db)
+	if revocations := findRevocations(t, db); len(revocations) != 0 {
+		t.Errorf("Revocation of a user in use should be dropped, got %+v", revocations)
+	}
+}
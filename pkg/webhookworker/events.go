@@ -0,0 +1,84 @@
+package webhookworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"interface-api/internal/database/models"
+	"interface-api/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventDeviceDisconnected = "device.disconnected"
+	EventTokenExpiring      = "token.expiring"
+	EventTokenExpired       = "token.expired"
+)
+
+// Event is a lifecycle notification delivered to the webhooks of a Matrix user,
+// alongside the incoming messages relayed by the webhook worker.
+type Event struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+type DeviceEventData struct {
+	Platform            string `json:"platform"`
+	DeviceID            string `json:"device_id"`
+	Label               string `json:"label,omitempty"`
+	Status              string `json:"status"`
+	Reason              string `json:"reason"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+type TokenEventData struct {
+	TokenID   uint              `json:"token_id"`
+	Name      string            `json:"name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+func NewEvent(eventType string, data any) Event {
+	return Event{
+		Event:     eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// PublishEvent posts the event once to every distinct URL among the active
+// webhooks registered for the Matrix user and returns once all deliveries have
+// been attempted.
+func PublishEvent(db *gorm.DB, matrixUsername string, event Event) error {
+	urls, err := models.FindActiveWebhookURLsByUsername(db, matrixUsername)
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+
+	if len(urls) == 0 {
+		logger.Debug(fmt.Sprintf("No active webhooks for %s event, skipping", event.Event))
+		return nil
+	}
+
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			postToWebhook(url, jsonData)
+		}(url)
+	}
+	wg.Wait()
+
+	logger.Info(fmt.Sprintf("Delivered %s event to %d webhook(s)", event.Event, len(urls)))
+	return nil
+}
@@ -301,7 +301,7 @@ func (w *WebhookWorker) runUserConsumerLoop(matrixIdentityID uint, queueName str
 			wg.Add(1)
 			go func(url string) {
 				defer wg.Done()
-				w.postToWebhook(url, jsonData)
+				postToWebhook(url, jsonData)
 			}(webhook.URL)
 		}
 		wg.Wait()
@@ -336,7 +336,7 @@ func (w *WebhookWorker) runUserConsumerLoop(matrixIdentityID uint, queueName str
 	}
 }
 
-func (w *WebhookWorker) postToWebhook(url string, data []byte) {
+func postToWebhook(url string, data []byte) {
 	client := &http.Client{
 		Timeout: 10 * time.Second,
 	}
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
+	"interface-api/internal/database"
 	"interface-api/pkg/config"
+	"interface-api/pkg/devicesync"
 	"interface-api/pkg/logger"
 	"interface-api/pkg/matrixclient"
 	"interface-api/pkg/rabbitmq"
@@ -20,13 +23,15 @@ import (
 )
 
 type QueuedMessage struct {
-	DeviceID      string `json:"device_id"`
-	Contact       string `json:"contact"`
-	PlatformName  string `json:"platform_name"`
-	Text          string `json:"text"`
-	Username      string `json:"username"`
-	FileContent   string `json:"file_content,omitempty"`
-	FileExtension string `json:"file_extension,omitempty"`
+	DeviceID         string   `json:"device_id"`
+	Contact          string   `json:"contact"`
+	PlatformName     string   `json:"platform_name"`
+	Text             string   `json:"text"`
+	Username         string   `json:"username"`
+	FileContent      string   `json:"file_content,omitempty"`
+	FileExtension    string   `json:"file_extension,omitempty"`
+	AutoSelect       bool     `json:"auto_select,omitempty"`
+	AttemptedDevices []string `json:"attempted_devices,omitempty"`
 }
 
 type Worker struct {
@@ -39,6 +44,7 @@ type Worker struct {
 	queueName       string
 	delayQueueName  string
 	sharedThrottler *throttler.Throttler
+	db              database.Service
 }
 
 func New() *Worker {
@@ -84,6 +90,7 @@ func New() *Worker {
 		queueName:       queueName,
 		delayQueueName:  delayQueueName,
 		sharedThrottler: throttler.New(),
+		db:              database.New(),
 	}
 }
 
@@ -237,11 +244,23 @@ func (w *Worker) runWorkerLoop(workerID int, matrixClient *matrixclient.Client)
 		_, err := matrixClient.SendMessage(msg.DeviceID, req)
 		if err != nil {
 			logger.Error(fmt.Sprintf("Worker %d: Message delivery failed: %v", workerID, err))
+			if err := devicesync.RecordSendFailure(w.db.DB(), msg.Username, msg.PlatformName, msg.DeviceID, err); err != nil {
+				logger.Warn(fmt.Sprintf("Worker %d: Failed to record device send failure: %v", workerID, err))
+			}
+
+			if msg.AutoSelect && w.failover(workerID, producer, &msg) {
+				delivery.Ack(false)
+				return nil
+			}
+
 			delivery.Nack(false, false)
 			return err
 		}
 
 		logger.Info(fmt.Sprintf("Worker %d: Message delivered successfully", workerID))
+		if err := devicesync.RecordSendSuccess(w.db.DB(), msg.Username, msg.PlatformName, msg.DeviceID); err != nil {
+			logger.Warn(fmt.Sprintf("Worker %d: Failed to record device send success: %v", workerID, err))
+		}
 		delivery.Ack(false)
 		return nil
 	}
@@ -269,3 +288,33 @@ func (w *Worker) runWorkerLoop(workerID int, matrixClient *matrixclient.Client)
 		return fmt.Errorf("connection closed")
 	}
 }
+
+// failover requeues an automatically routed message on another healthy device
+// of the same platform. It returns false when every device has been tried.
+func (w *Worker) failover(workerID int, producer *rabbitmq.Producer, msg *QueuedMessage) bool {
+	if !slices.Contains(msg.AttemptedDevices, msg.DeviceID) {
+		msg.AttemptedDevices = append(msg.AttemptedDevices, msg.DeviceID)
+	}
+
+	device, err := devicesync.SelectDevice(w.db.DB(), msg.Username, msg.PlatformName, msg.AttemptedDevices)
+	if err == devicesync.ErrNoAvailableDevice {
+		logger.Warn(fmt.Sprintf("Worker %d: No remaining %s device to fail over to", workerID, msg.PlatformName))
+		return false
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Worker %d: Failover device selection failed: %v", workerID, err))
+		return false
+	}
+
+	msg.DeviceID = device.DeviceID
+	msg.AttemptedDevices = append(msg.AttemptedDevices, device.DeviceID)
+
+	routingKey := fmt.Sprintf("message.%s.%s", msg.PlatformName, msg.Username)
+	if err := producer.Publish(w.exchangeName, routingKey, msg, rabbitmq.DefaultPublishOptions()); err != nil {
+		logger.Error(fmt.Sprintf("Worker %d: Failover publish failed: %v\n%s", workerID, err, debug.Stack()))
+		return false
+	}
+
+	logger.Info(fmt.Sprintf("Worker %d: Message failed over to another %s device", workerID, msg.PlatformName))
+	return true
+}